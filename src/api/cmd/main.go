@@ -3,10 +3,19 @@ package main
 import (
 	"log"
 
+	"api/configs"
+	routes_alerts "api/internal/alerts"
+	routes_anomalies "api/internal/anomalies"
+	routes_apikeys "api/internal/apikeys"
 	routes_authentication "api/internal/auth"
+	routes_categories "api/internal/categories"
+	routes_retention "api/internal/retention"
 	routes_sensors "api/internal/sensors"
 	routes_sensors_data "api/internal/sensors_data"
+	"api/internal/sensors_data/mqtt"
+	sensor_data_service "api/internal/sensors_data/usecase"
 	routes_users "api/internal/users"
+	routes_webhooks "api/internal/webhooks"
 	"api/version"
 
 	_ "api/docs"
@@ -27,6 +36,12 @@ import (
 // @Tag Auth
 // @Tag Sensor
 // @Tag SensorData
+// @Tag Alerts
+// @Tag Webhooks
+// @Tag Retention
+// @Tag Categories
+// @Tag Anomalies
+// @Tag APIKeys
 // @host localhost:8080
 func main() {
 
@@ -42,9 +57,18 @@ func main() {
 
 	// All routes
 	routes_sensors.RegisterSensorRoutes(router)
-	routes_sensors_data.RegisterSensordataRoutes(router)
+	alertService := routes_alerts.RegisterAlertRoutes(router)
+	sensorDataService := routes_sensors_data.RegisterSensordataRoutes(router, alertService)
 	routes_users.RegisterUsersRoutes(router)
 	routes_authentication.RegisterAuthRoutes(router)
+	routes_webhooks.RegisterWebhookRoutes(router)
+	routes_retention.RegisterRetentionRoutes(router)
+	routes_categories.RegisterCategoryRoutes(router)
+	routes_anomalies.RegisterAnomalyRoutes(router)
+	routes_apikeys.RegisterAPIKeyRoutes(router)
+
+	// Background ingestion of the readings published over MQTT
+	startMQTTBridge(sensorDataService)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -56,3 +80,23 @@ func main() {
 		log.Fatalf("Server failed: %v", err)
 	}
 }
+
+// Starts the MQTT ingestion bridge when it's enabled in the configuration
+func startMQTTBridge(sensorDataService sensor_data_service.SensorDataService) {
+	config, err := configs.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	if !config.MQTT.Enabled {
+		return
+	}
+
+	client := mqtt.NewPahoClient(config.MQTT.Broker, config.MQTT.ClientID, config.MQTT.Username, config.MQTT.Password)
+	bridge := mqtt.NewBridge(client, sensorDataService, config.MQTT.Topics, config.MQTT.QoS)
+	if err := bridge.Start(); err != nil {
+		log.Fatalf("Failed to start MQTT bridge: %v", err)
+	}
+
+	log.Printf("MQTT bridge subscribed to %v on %s", config.MQTT.Topics, config.MQTT.Broker)
+}
@@ -38,6 +38,24 @@ type Config struct {
 	} `json:"email"`
 	// JWTSecret holds the JWT secret key
 	JWTSecret string `json:"jwt_secret"`
+
+	// MQTT holds the configuration for ingesting sensor data from an MQTT broker
+	MQTT struct {
+		// Enabled turns the MQTT ingestion bridge on
+		Enabled bool `json:"enabled"`
+		// Broker is the address of the MQTT broker (e.g. tcp://localhost:1883)
+		Broker string `json:"broker"`
+		// ClientID identifies the api on the broker
+		ClientID string `json:"client_id"`
+		// Username for authenticating with the broker
+		Username string `json:"username"`
+		// Password for authenticating with the broker
+		Password string `json:"password"`
+		// Topics to subscribe, where a single-level wildcard (+) stands for the sensor uuid (e.g. uno/+/data)
+		Topics []string `json:"topics"`
+		// QoS is the quality of service of the subscriptions (0, 1 or 2)
+		QoS byte `json:"qos"`
+	} `json:"mqtt"`
 }
 
 // ConfigFilePath is the relative path to the configuration JSON file.
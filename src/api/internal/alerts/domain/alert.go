@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+const (
+	// Rule types
+	// Fires when the value stays beyond a threshold for a duration
+	ALERT_RULE_THRESHOLD string = "threshold"
+	// Fires when the sensor sends no data for a duration
+	ALERT_RULE_NO_DATA string = "no_data"
+)
+
+const (
+	// Comparison operators of threshold rules
+	ALERT_OPERATOR_GREATER          string = ">"
+	ALERT_OPERATOR_GREATER_OR_EQUAL string = ">="
+	ALERT_OPERATOR_LESS             string = "<"
+	ALERT_OPERATOR_LESS_OR_EQUAL    string = "<="
+)
+
+const (
+	// Alert states
+	ALERT_STATE_FIRING       string = "firing"
+	ALERT_STATE_ACKNOWLEDGED string = "acknowledged"
+	ALERT_STATE_RESOLVED     string = "resolved"
+)
+
+var (
+	// Returned when the rule doesn't exist or belongs to another user
+	ErrAlertRuleNotFound = errors.New("alert rule not found")
+	// Returned when the alert doesn't exist, belongs to another user or isn't firing
+	ErrAlertNotFound = errors.New("alert not found or not firing")
+	// Returned when the sensor of a rule doesn't exist
+	ErrSensorNotFound = errors.New("sensor not found")
+	// Returned when the user tries to add a rule to a sensor they don't own
+	ErrNotSensorOwner = errors.New("user is not the owner of the sensor")
+	// Returned when the rule already has an alert that isn't resolved
+	ErrAlertAlreadyFiring = errors.New("alert rule is already firing")
+)
+
+// AlertRule is a condition on a sensor that fires an alert to its owner
+type AlertRule struct {
+	// Unique identifier for the rule
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the sensor the rule applies to
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// UUID of the user who owns the rule (the sensor's owner)
+	OwnerUuid uuid.UUID `json:"ownerUuid"`
+	// Name shown in the notifications
+	Name string `json:"name"`
+	// Type of rule: threshold or no_data
+	Type string `json:"type"`
+	// Comparison operator of threshold rules: >, >=, < or <=
+	Operator string `json:"operator"`
+	// Value compared against the readings in threshold rules
+	Threshold float64 `json:"threshold"`
+	// Minutes the condition must hold before firing
+	DurationMinutes int `json:"durationMinutes"`
+	// Whether the rule is evaluated
+	Enabled bool `json:"enabled"`
+	// Since when the threshold condition holds (read only, nil when it doesn't)
+	ConditionSince *time.Time `json:"conditionSince"`
+	// Timestamp of the last reading evaluated, older readings are ignored (read only)
+	EvaluatedUntil *time.Time `json:"evaluatedUntil"`
+}
+
+// Duration the condition must hold before firing
+func (r *AlertRule) Duration() time.Duration {
+	return time.Duration(r.DurationMinutes) * time.Minute
+}
+
+// Matches reports whether the value breaches a threshold rule
+func (r *AlertRule) Matches(value float64) bool {
+	switch r.Operator {
+	case ALERT_OPERATOR_GREATER:
+		return value > r.Threshold
+	case ALERT_OPERATOR_GREATER_OR_EQUAL:
+		return value >= r.Threshold
+	case ALERT_OPERATOR_LESS:
+		return value < r.Threshold
+	case ALERT_OPERATOR_LESS_OR_EQUAL:
+		return value <= r.Threshold
+	}
+	return false
+}
+
+// Alert is an occurrence of a rule firing, tracked until it's resolved
+type Alert struct {
+	// Unique identifier for the alert
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the rule that fired
+	RuleUuid uuid.UUID `json:"ruleUuid"`
+	// UUID of the sensor the rule applies to
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// State of the alert: firing, acknowledged or resolved
+	State string `json:"state"`
+	// Value that fired the alert (nil for no_data rules)
+	Value *float64 `json:"value"`
+	// Description of what fired the alert
+	Message string `json:"message"`
+	// When the alert fired
+	FiredAt time.Time `json:"firedAt"`
+	// When the owner acknowledged the alert
+	AcknowledgedAt *time.Time `json:"acknowledgedAt"`
+	// When the condition stopped holding
+	ResolvedAt *time.Time `json:"resolvedAt"`
+}
+
+// Contact details of a sensor's owner, used to notify alerts
+type SensorContact struct {
+	// Name of the sensor
+	SensorName string
+	// Name of the owner
+	OwnerName string
+	// Email of the owner
+	OwnerEmail string
+}
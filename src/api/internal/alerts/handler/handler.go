@@ -0,0 +1,252 @@
+package handler
+
+import (
+	"api/internal/alerts/domain"
+	alert_service "api/internal/alerts/usecase"
+	user_service "api/internal/users/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for handling HTTP requests related to alerts
+type AlertHandler interface {
+	// Handles the HTTP request to create an alert rule
+	CreateRule(c *gin.Context)
+	// Handles the HTTP request to edit an alert rule
+	EditRule(c *gin.Context)
+	// Handles the HTTP request to delete an alert rule
+	DeleteRule(c *gin.Context)
+	// Handles the HTTP request to list the user's alert rules
+	ListRules(c *gin.Context)
+	// Handles the HTTP request to list the user's alerts
+	ListAlerts(c *gin.Context)
+	// Handles the HTTP request to acknowledge a firing alert
+	AcknowledgeAlert(c *gin.Context)
+}
+
+// Structure request to create or edit an alert rule
+type AlertRuleRequest struct {
+	// Rule UUID (edit only)
+	ID uuid.UUID `json:"uuid"`
+	// Sensor UUID (create only)
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Name shown in the notifications
+	Name string `json:"name"`
+	// Type of rule: threshold or no_data (create only)
+	Type string `json:"type"`
+	// Comparison operator of threshold rules: >, >=, < or <=
+	Operator string `json:"operator"`
+	// Value compared against the readings in threshold rules
+	Threshold float64 `json:"threshold"`
+	// Minutes the condition must hold before firing
+	DurationMinutes int `json:"durationMinutes"`
+	// Whether the rule is evaluated, defaults to true
+	Enabled *bool `json:"enabled"`
+}
+
+// Structure request identifying a rule or an alert
+type AlertUuidRequest struct {
+	// Rule or alert UUID
+	ID uuid.UUID `json:"uuid" binding:"required"`
+}
+
+// Structure request for listing rules
+type AlertRulesListRequest struct {
+	// Sensor UUID, empty for the rules of every sensor
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+}
+
+// Structure request for listing alerts
+type AlertsListRequest struct {
+	// State of the alerts: firing, acknowledged or resolved (empty for all)
+	State string `json:"state"`
+}
+
+// Process HTTP requests and interaction with AlertService/UserService for alert operations
+type AlertHandlerImpl struct {
+	AlertService alert_service.AlertService
+	UserService  user_service.UserService
+}
+
+func NewAlertHandler(alertService alert_service.AlertService, userService user_service.UserService) AlertHandler {
+	return &AlertHandlerImpl{
+		AlertService: alertService,
+		UserService:  userService,
+	}
+}
+
+// Gets the uuid of the user authenticated by the request's token
+func (h *AlertHandlerImpl) getUserUuid(c *gin.Context) (uuid.UUID, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	return h.UserService.GetUserByToken(c.Request.Context(), str)
+}
+
+// Maps errors from the alert service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrAlertRuleNotFound), errors.Is(err, domain.ErrAlertNotFound), errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// Converts the request to a rule
+func (req *AlertRuleRequest) toRule() *domain.AlertRule {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	return &domain.AlertRule{
+		ID:              req.ID,
+		SensorUuid:      req.SensorUuid,
+		Name:            req.Name,
+		Type:            req.Type,
+		Operator:        req.Operator,
+		Threshold:       req.Threshold,
+		DurationMinutes: req.DurationMinutes,
+		Enabled:         enabled,
+	}
+}
+
+func (h *AlertHandlerImpl) CreateRule(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AlertRuleRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	rule := req.toRule()
+	if err = h.AlertService.CreateRule(c.Request.Context(), rule, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"uuid": rule.ID})
+}
+
+func (h *AlertHandlerImpl) EditRule(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AlertRuleRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.AlertService.EditRule(c.Request.Context(), req.toRule(), userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *AlertHandlerImpl) DeleteRule(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AlertUuidRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.AlertService.DeleteRule(c.Request.Context(), req.ID, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *AlertHandlerImpl) ListRules(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AlertRulesListRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	rules, err := h.AlertService.ListRules(c.Request.Context(), userUuid, req.SensorUuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if rules == nil {
+		rules = []domain.AlertRule{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *AlertHandlerImpl) ListAlerts(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AlertsListRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	alerts, err := h.AlertService.ListAlerts(c.Request.Context(), userUuid, req.State)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if alerts == nil {
+		alerts = []domain.Alert{}
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+func (h *AlertHandlerImpl) AcknowledgeAlert(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AlertUuidRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.AlertService.AcknowledgeAlert(c.Request.Context(), req.ID, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,462 @@
+package repository
+
+import (
+	config "api/configs"
+	"api/internal/alerts/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for alert rules and alerts data operations
+type AlertRepository interface {
+	// Stores a new rule
+	CreateRule(ctx context.Context, rule *domain.AlertRule) error
+	// Updates the details of an existing rule of the owner
+	EditRule(ctx context.Context, rule *domain.AlertRule) error
+	// Deletes a rule of the owner and its alerts
+	DeleteRule(ctx context.Context, ruleUuid uuid.UUID, ownerUuid uuid.UUID) error
+	// Retrieves a rule of the owner
+	GetRule(ctx context.Context, ruleUuid uuid.UUID, ownerUuid uuid.UUID) (*domain.AlertRule, error)
+	// Retrieves the rules of the owner, optionally only for one sensor (nil uuid for all)
+	ListRules(ctx context.Context, ownerUuid uuid.UUID, sensorUuid uuid.UUID) ([]domain.AlertRule, error)
+	// Retrieves the enabled rules of a sensor
+	ListSensorRules(ctx context.Context, sensorUuid uuid.UUID) ([]domain.AlertRule, error)
+	// Retrieves every enabled rule
+	ListEnabledRules(ctx context.Context) ([]domain.AlertRule, error)
+	// Stores since when the threshold condition of the rule holds (nil when it doesn't)
+	// and the timestamp of the last reading evaluated
+	SetConditionSince(ctx context.Context, ruleUuid uuid.UUID, since *time.Time, evaluatedUntil time.Time) error
+	// Retrieves the alert of the rule that is not resolved yet, nil if there is none
+	GetActiveAlert(ctx context.Context, ruleUuid uuid.UUID) (*domain.Alert, error)
+	// Stores a new alert, failing with ErrAlertAlreadyFiring if the rule has an open one
+	CreateAlert(ctx context.Context, alert *domain.Alert) error
+	// Marks the alert as resolved
+	ResolveAlert(ctx context.Context, alertUuid uuid.UUID, resolvedAt time.Time) error
+	// Marks a firing alert of the owner's rules as acknowledged, returns false if there was none
+	AcknowledgeAlert(ctx context.Context, alertUuid uuid.UUID, ownerUuid uuid.UUID, acknowledgedAt time.Time) (bool, error)
+	// Retrieves the alerts of the owner's rules, optionally filtered by state (empty for all)
+	ListAlerts(ctx context.Context, ownerUuid uuid.UUID, state string) ([]domain.Alert, error)
+	// Retrieves the uuid of the user who owns the sensor
+	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error)
+	// Retrieves the sensor name and its owner's name and email
+	GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorContact, error)
+	// Retrieves the timestamp of the most recent reading of the sensor, nil if it has no data
+	GetLastReadingTime(ctx context.Context, sensorUuid uuid.UUID) (*time.Time, error)
+}
+
+// Performs alert's data operations using database/sql to interact with the database
+type AlertRepositoryImpl struct {
+	DB *sql.DB
+}
+
+func NewAlertRepository() (AlertRepository, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	return &AlertRepositoryImpl{DB: db}, nil
+}
+
+// Columns selected for rules, in the order read by scanRules
+const ruleColumns = `uuid, sensorUuid, ownerUuid, name, type, operator, threshold, durationMinutes, enabled, conditionSince, evaluatedUntil`
+
+// Columns selected for alerts, in the order read by scanAlerts
+const alertColumns = `sensor_alerts.uuid, sensor_alerts.ruleUuid, sensor_alerts.sensorUuid, sensor_alerts.state, sensor_alerts.value,
+	sensor_alerts.message, sensor_alerts.firedAt, sensor_alerts.acknowledgedAt, sensor_alerts.resolvedAt`
+
+func scanRules(rows *sql.Rows) ([]domain.AlertRule, error) {
+	defer rows.Close()
+
+	var rules []domain.AlertRule
+	for rows.Next() {
+		var rule domain.AlertRule
+		var conditionSince, evaluatedUntil sql.NullTime
+		if err := rows.Scan(&rule.ID, &rule.SensorUuid, &rule.OwnerUuid, &rule.Name, &rule.Type, &rule.Operator,
+			&rule.Threshold, &rule.DurationMinutes, &rule.Enabled, &conditionSince, &evaluatedUntil); err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %v", err)
+		}
+		if conditionSince.Valid {
+			rule.ConditionSince = &conditionSince.Time
+		}
+		if evaluatedUntil.Valid {
+			rule.EvaluatedUntil = &evaluatedUntil.Time
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating alert rules: %v", err)
+	}
+
+	return rules, nil
+}
+
+func scanAlerts(rows *sql.Rows) ([]domain.Alert, error) {
+	defer rows.Close()
+
+	var alerts []domain.Alert
+	for rows.Next() {
+		var alert domain.Alert
+		var value sql.NullFloat64
+		var acknowledgedAt, resolvedAt sql.NullTime
+		if err := rows.Scan(&alert.ID, &alert.RuleUuid, &alert.SensorUuid, &alert.State, &value,
+			&alert.Message, &alert.FiredAt, &acknowledgedAt, &resolvedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %v", err)
+		}
+		if value.Valid {
+			alert.Value = &value.Float64
+		}
+		if acknowledgedAt.Valid {
+			alert.AcknowledgedAt = &acknowledgedAt.Time
+		}
+		if resolvedAt.Valid {
+			alert.ResolvedAt = &resolvedAt.Time
+		}
+		alerts = append(alerts, alert)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating alerts: %v", err)
+	}
+
+	return alerts, nil
+}
+
+func (r *AlertRepositoryImpl) CreateRule(ctx context.Context, rule *domain.AlertRule) error {
+	query := `
+		INSERT INTO alert_rules (uuid, sensorUuid, ownerUuid, name, type, operator, threshold, durationMinutes, enabled)
+		VALUES (@uuid, @sensorUuid, @ownerUuid, @name, @type, @operator, @threshold, @durationMinutes, @enabled)
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", rule.ID),
+		sql.Named("sensorUuid", rule.SensorUuid),
+		sql.Named("ownerUuid", rule.OwnerUuid),
+		sql.Named("name", rule.Name),
+		sql.Named("type", rule.Type),
+		sql.Named("operator", rule.Operator),
+		sql.Named("threshold", rule.Threshold),
+		sql.Named("durationMinutes", rule.DurationMinutes),
+		sql.Named("enabled", rule.Enabled),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create alert rule: %v", err)
+	}
+	return nil
+}
+
+func (r *AlertRepositoryImpl) EditRule(ctx context.Context, rule *domain.AlertRule) error {
+	// Changing the rule restarts the tracking of its condition
+	query := `
+		UPDATE alert_rules
+		SET
+			name = @name,
+			operator = @operator,
+			threshold = @threshold,
+			durationMinutes = @durationMinutes,
+			enabled = @enabled,
+			conditionSince = NULL
+		WHERE uuid = @uuid AND ownerUuid = @ownerUuid
+	`
+
+	result, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", rule.ID),
+		sql.Named("ownerUuid", rule.OwnerUuid),
+		sql.Named("name", rule.Name),
+		sql.Named("operator", rule.Operator),
+		sql.Named("threshold", rule.Threshold),
+		sql.Named("durationMinutes", rule.DurationMinutes),
+		sql.Named("enabled", rule.Enabled),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule: %v", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+func (r *AlertRepositoryImpl) DeleteRule(ctx context.Context, ruleUuid uuid.UUID, ownerUuid uuid.UUID) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE sensor_alerts
+		FROM sensor_alerts
+		INNER JOIN alert_rules ON alert_rules.uuid = sensor_alerts.ruleUuid
+		WHERE alert_rules.uuid = @uuid AND alert_rules.ownerUuid = @ownerUuid
+	`
+	if _, err = tx.ExecContext(ctx, query, sql.Named("uuid", ruleUuid), sql.Named("ownerUuid", ownerUuid)); err != nil {
+		return fmt.Errorf("failed to delete alerts: %v", err)
+	}
+
+	query = `DELETE FROM alert_rules WHERE uuid = @uuid AND ownerUuid = @ownerUuid`
+	result, err := tx.ExecContext(ctx, query, sql.Named("uuid", ruleUuid), sql.Named("ownerUuid", ownerUuid))
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %v", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrAlertRuleNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (r *AlertRepositoryImpl) GetRule(ctx context.Context, ruleUuid uuid.UUID, ownerUuid uuid.UUID) (*domain.AlertRule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM alert_rules
+		WHERE uuid = @uuid AND ownerUuid = @ownerUuid
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("uuid", ruleUuid), sql.Named("ownerUuid", ownerUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %v", err)
+	}
+
+	rules, err := scanRules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, domain.ErrAlertRuleNotFound
+	}
+	return &rules[0], nil
+}
+
+func (r *AlertRepositoryImpl) ListRules(ctx context.Context, ownerUuid uuid.UUID, sensorUuid uuid.UUID) ([]domain.AlertRule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM alert_rules
+		WHERE ownerUuid = @ownerUuid
+		AND (@sensorUuid = '00000000-0000-0000-0000-000000000000' OR sensorUuid = @sensorUuid)
+		ORDER BY name
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("ownerUuid", ownerUuid), sql.Named("sensorUuid", sensorUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %v", err)
+	}
+	return scanRules(rows)
+}
+
+func (r *AlertRepositoryImpl) ListSensorRules(ctx context.Context, sensorUuid uuid.UUID) ([]domain.AlertRule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM alert_rules
+		WHERE sensorUuid = @sensorUuid AND enabled = 1
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("sensorUuid", sensorUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %v", err)
+	}
+	return scanRules(rows)
+}
+
+func (r *AlertRepositoryImpl) ListEnabledRules(ctx context.Context) ([]domain.AlertRule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM alert_rules
+		WHERE enabled = 1
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert rules: %v", err)
+	}
+	return scanRules(rows)
+}
+
+func (r *AlertRepositoryImpl) SetConditionSince(ctx context.Context, ruleUuid uuid.UUID, since *time.Time, evaluatedUntil time.Time) error {
+	query := `UPDATE alert_rules SET conditionSince = @since, evaluatedUntil = @evaluatedUntil WHERE uuid = @uuid`
+
+	var value sql.NullTime
+	if since != nil {
+		value = sql.NullTime{Time: *since, Valid: true}
+	}
+
+	_, err := r.DB.ExecContext(ctx, query, sql.Named("uuid", ruleUuid), sql.Named("since", value), sql.Named("evaluatedUntil", evaluatedUntil))
+	if err != nil {
+		return fmt.Errorf("failed to update alert rule condition: %v", err)
+	}
+	return nil
+}
+
+func (r *AlertRepositoryImpl) GetActiveAlert(ctx context.Context, ruleUuid uuid.UUID) (*domain.Alert, error) {
+	query := `
+		SELECT TOP 1 ` + alertColumns + `
+		FROM sensor_alerts
+		WHERE ruleUuid = @ruleUuid AND state <> @resolved
+		ORDER BY firedAt DESC
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("ruleUuid", ruleUuid), sql.Named("resolved", domain.ALERT_STATE_RESOLVED))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active alert: %v", err)
+	}
+
+	alerts, err := scanAlerts(rows)
+	if err != nil || len(alerts) == 0 {
+		return nil, err
+	}
+	return &alerts[0], nil
+}
+
+func (r *AlertRepositoryImpl) CreateAlert(ctx context.Context, alert *domain.Alert) error {
+	query := `
+		INSERT INTO sensor_alerts (uuid, ruleUuid, sensorUuid, state, value, message, firedAt)
+		VALUES (@uuid, @ruleUuid, @sensorUuid, @state, @value, @message, @firedAt)
+	`
+
+	var value sql.NullFloat64
+	if alert.Value != nil {
+		value = sql.NullFloat64{Float64: *alert.Value, Valid: true}
+	}
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", alert.ID),
+		sql.Named("ruleUuid", alert.RuleUuid),
+		sql.Named("sensorUuid", alert.SensorUuid),
+		sql.Named("state", alert.State),
+		sql.Named("value", value),
+		sql.Named("message", alert.Message),
+		sql.Named("firedAt", alert.FiredAt),
+	)
+	if err != nil {
+		// Only one alert of a rule can be open, another evaluation fired it first
+		var sqlErr mssql.Error
+		if errors.As(err, &sqlErr) && (sqlErr.Number == 2601 || sqlErr.Number == 2627) {
+			return domain.ErrAlertAlreadyFiring
+		}
+		return fmt.Errorf("failed to create alert: %v", err)
+	}
+	return nil
+}
+
+func (r *AlertRepositoryImpl) ResolveAlert(ctx context.Context, alertUuid uuid.UUID, resolvedAt time.Time) error {
+	query := `
+		UPDATE sensor_alerts
+		SET state = @resolved, resolvedAt = @resolvedAt
+		WHERE uuid = @uuid
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", alertUuid),
+		sql.Named("resolved", domain.ALERT_STATE_RESOLVED),
+		sql.Named("resolvedAt", resolvedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to resolve alert: %v", err)
+	}
+	return nil
+}
+
+func (r *AlertRepositoryImpl) AcknowledgeAlert(ctx context.Context, alertUuid uuid.UUID, ownerUuid uuid.UUID, acknowledgedAt time.Time) (bool, error) {
+	query := `
+		UPDATE sensor_alerts
+		SET state = @acknowledged, acknowledgedAt = @acknowledgedAt
+		FROM sensor_alerts
+		INNER JOIN alert_rules ON alert_rules.uuid = sensor_alerts.ruleUuid
+		WHERE sensor_alerts.uuid = @uuid
+		AND alert_rules.ownerUuid = @ownerUuid
+		AND sensor_alerts.state = @firing
+	`
+
+	result, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", alertUuid),
+		sql.Named("ownerUuid", ownerUuid),
+		sql.Named("acknowledged", domain.ALERT_STATE_ACKNOWLEDGED),
+		sql.Named("firing", domain.ALERT_STATE_FIRING),
+		sql.Named("acknowledgedAt", acknowledgedAt),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to acknowledge alert: %v", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to acknowledge alert: %v", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *AlertRepositoryImpl) ListAlerts(ctx context.Context, ownerUuid uuid.UUID, state string) ([]domain.Alert, error) {
+	query := `
+		SELECT ` + alertColumns + `
+		FROM sensor_alerts
+		INNER JOIN alert_rules ON alert_rules.uuid = sensor_alerts.ruleUuid
+		WHERE alert_rules.ownerUuid = @ownerUuid
+		AND (@state = '' OR sensor_alerts.state = @state)
+		ORDER BY sensor_alerts.firedAt DESC
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("ownerUuid", ownerUuid), sql.Named("state", state))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %v", err)
+	}
+	return scanAlerts(rows)
+}
+
+func (r *AlertRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT sensorOwnerUuid FROM Sensors WHERE uuid = @sensorUuid`
+
+	var ownerUuid uuid.UUID
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&ownerUuid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.NilUUID, domain.ErrSensorNotFound
+		}
+		return uuid.NilUUID, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return ownerUuid, nil
+}
+
+func (r *AlertRepositoryImpl) GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorContact, error) {
+	query := `
+		SELECT Sensors.name, users.name, users.email
+		FROM Sensors
+		INNER JOIN users ON users.uuid = Sensors.sensorOwnerUuid
+		WHERE Sensors.uuid = @sensorUuid
+	`
+
+	var contact domain.SensorContact
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&contact.SensorName, &contact.OwnerName, &contact.OwnerEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrSensorNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return &contact, nil
+}
+
+func (r *AlertRepositoryImpl) GetLastReadingTime(ctx context.Context, sensorUuid uuid.UUID) (*time.Time, error) {
+	query := `SELECT MAX(timestamp) FROM SensorData WHERE sensorUuid = @sensorUuid AND quality <> 'missing'`
+
+	var last sql.NullTime
+	if err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&last); err != nil {
+		return nil, fmt.Errorf("failed to retrieve last reading: %v", err)
+	}
+
+	if !last.Valid {
+		return nil, nil
+	}
+	return &last.Time, nil
+}
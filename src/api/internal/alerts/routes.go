@@ -0,0 +1,74 @@
+package alerts
+
+import (
+	"api/internal/alerts/handler"
+	alert_repository "api/internal/alerts/repository"
+	alert_service "api/internal/alerts/usecase"
+	auth_repository "api/internal/auth/repository"
+	auth_service "api/internal/auth/usecase"
+	user_repository "api/internal/users/repository"
+	user_service "api/internal/users/usecase"
+	webhook_repository "api/internal/webhooks/repository"
+	webhook_service "api/internal/webhooks/usecase"
+	middleware "api/utils"
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAlertRoutes declares the routes that can be accessed for alert management
+// and starts the background evaluation of the rules. It returns the alert service,
+// to evaluate the rules against the readings as they're added.
+func RegisterAlertRoutes(router *gin.Engine) alert_service.AlertService {
+
+	alertRepo, err := alert_repository.NewAlertRepository()
+	if err != nil {
+		log.Fatalf("Failed to create alert repository: %v", err)
+	}
+
+	usersRepos, err := user_repository.NewUserRepository()
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	authRepo, err := auth_repository.NewAuthRepository()
+	if err != nil {
+		log.Fatalf("Failed to create auth repository: %v", err)
+	}
+
+	webhookRepo, err := webhook_repository.NewWebhookRepository()
+	if err != nil {
+		log.Fatalf("Failed to create webhook repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	// Fired alerts are also sent to the webhook subscriptions
+	alertService := alert_service.NewAlertService(alertRepo, middleware.CreateEmail, webhook_service.NewWebhookService(webhookRepo))
+	authService := auth_service.NewAuthService(authRepo, usersRepos)
+
+	h := handler.NewAlertHandler(alertService, userService)
+
+	// Rules that depend on elapsed time (no data, held thresholds) are evaluated periodically
+	go alertService.Run(context.Background(), alert_service.DefaultEvaluationInterval)
+
+	// Alert routes
+	api := router.Group("/v1/alerts/")
+	api.Use(middleware.AuthMiddleware(authService))
+	{
+		// Create alert rule
+		api.POST("rules/create", h.CreateRule)
+		// Update alert rule
+		api.POST("rules/edit", h.EditRule)
+		// Delete alert rule and its alerts
+		api.POST("rules/delete", h.DeleteRule)
+		// List alert rules
+		api.POST("rules/list", h.ListRules)
+		// List alerts
+		api.POST("list", h.ListAlerts)
+		// Acknowledge firing alert
+		api.POST("acknowledge", h.AcknowledgeAlert)
+	}
+
+	return alertService
+}
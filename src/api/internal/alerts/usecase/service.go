@@ -0,0 +1,429 @@
+package usecase
+
+import (
+	"api/internal/alerts/domain"
+	"api/internal/alerts/repository"
+	sensor_data_domain "api/internal/sensors_data/domain"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interval between evaluations of the background ticker
+const DefaultEvaluationInterval = time.Minute
+
+// Interface for alert's services
+type AlertService interface {
+	// Creates a new rule on a sensor owned by the user
+	CreateRule(ctx context.Context, rule *domain.AlertRule, userUuid uuid.UUID) error
+	// Updates an existing rule of the user
+	EditRule(ctx context.Context, rule *domain.AlertRule, userUuid uuid.UUID) error
+	// Deletes a rule of the user and its alerts
+	DeleteRule(ctx context.Context, ruleUuid uuid.UUID, userUuid uuid.UUID) error
+	// Lists the rules of the user, optionally only for one sensor (nil uuid for all)
+	ListRules(ctx context.Context, userUuid uuid.UUID, sensorUuid uuid.UUID) ([]domain.AlertRule, error)
+	// Lists the alerts of the user's rules, optionally filtered by state (empty for all)
+	ListAlerts(ctx context.Context, userUuid uuid.UUID, state string) ([]domain.Alert, error)
+	// Marks a firing alert of the user as acknowledged
+	AcknowledgeAlert(ctx context.Context, alertUuid uuid.UUID, userUuid uuid.UUID) error
+	// Evaluates the rules of the sensors that received readings
+	OnSensorData(ctx context.Context, sensorData []*sensor_data_domain.SensorData)
+	// Evaluates the rules that depend on the passing of time (no data and held thresholds)
+	EvaluateAll(ctx context.Context, now time.Time) error
+	// Evaluates every rule at each interval until the context is cancelled
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// Receives the alerts fired by the service
+type AlertListener interface {
+	// Called after an alert is stored, with the rule that fired it
+	OnAlertFired(ctx context.Context, rule *domain.AlertRule, alert *domain.Alert)
+}
+
+// Handles alert's logic and interaction with the repository
+type AlertServiceImpl struct {
+	Repo repository.AlertRepository
+	// Sends a notification email to the sensor's owner
+	Notify    NotifyFunc
+	Listeners []AlertListener
+}
+
+// Sends an email, matching utils.CreateEmail
+type NotifyFunc func(to, subject, body string) error
+
+func NewAlertService(repo repository.AlertRepository, notify NotifyFunc, listeners ...AlertListener) AlertService {
+	return &AlertServiceImpl{
+		Repo:      repo,
+		Notify:    notify,
+		Listeners: listeners,
+	}
+}
+
+// Checks the fields of the rule according to its type
+func validateRule(rule *domain.AlertRule) error {
+	if rule.Name == "" {
+		return errors.New("name is required")
+	}
+	if rule.DurationMinutes < 0 {
+		return errors.New("durationMinutes can't be negative")
+	}
+
+	switch rule.Type {
+	case domain.ALERT_RULE_THRESHOLD:
+		switch rule.Operator {
+		case domain.ALERT_OPERATOR_GREATER, domain.ALERT_OPERATOR_GREATER_OR_EQUAL,
+			domain.ALERT_OPERATOR_LESS, domain.ALERT_OPERATOR_LESS_OR_EQUAL:
+		default:
+			return errors.New("operator must be one of >, >=, < or <=")
+		}
+	case domain.ALERT_RULE_NO_DATA:
+		if rule.DurationMinutes == 0 {
+			return errors.New("durationMinutes is required for no_data rules")
+		}
+		rule.Operator = ""
+		rule.Threshold = 0
+	default:
+		return errors.New("type must be threshold or no_data")
+	}
+	return nil
+}
+
+func (s *AlertServiceImpl) CreateRule(ctx context.Context, rule *domain.AlertRule, userUuid uuid.UUID) error {
+	if err := validateRule(rule); err != nil {
+		return err
+	}
+
+	ownerUuid, err := s.Repo.GetSensorOwner(ctx, rule.SensorUuid)
+	if err != nil {
+		return err
+	}
+	if ownerUuid != userUuid {
+		return domain.ErrNotSensorOwner
+	}
+
+	rule.ID = uuid.NewV4()
+	rule.OwnerUuid = userUuid
+	rule.ConditionSince = nil
+
+	return s.Repo.CreateRule(ctx, rule)
+}
+
+func (s *AlertServiceImpl) EditRule(ctx context.Context, rule *domain.AlertRule, userUuid uuid.UUID) error {
+	existing, err := s.Repo.GetRule(ctx, rule.ID, userUuid)
+	if err != nil {
+		return err
+	}
+
+	// The sensor and type of a rule can't be changed
+	rule.SensorUuid = existing.SensorUuid
+	rule.Type = existing.Type
+	rule.OwnerUuid = userUuid
+	// Changing the rule restarts the tracking of its condition
+	rule.ConditionSince = nil
+
+	if err = validateRule(rule); err != nil {
+		return err
+	}
+
+	if err = s.Repo.EditRule(ctx, rule); err != nil {
+		return err
+	}
+
+	// A disabled rule isn't evaluated anymore, so its open alert would never be resolved
+	if !rule.Enabled {
+		active, err := s.Repo.GetActiveAlert(ctx, rule.ID)
+		if err != nil {
+			return err
+		}
+		if active != nil {
+			return s.resolve(ctx, rule, active, time.Now().UTC())
+		}
+	}
+	return nil
+}
+
+func (s *AlertServiceImpl) DeleteRule(ctx context.Context, ruleUuid uuid.UUID, userUuid uuid.UUID) error {
+	return s.Repo.DeleteRule(ctx, ruleUuid, userUuid)
+}
+
+func (s *AlertServiceImpl) ListRules(ctx context.Context, userUuid uuid.UUID, sensorUuid uuid.UUID) ([]domain.AlertRule, error) {
+	rules, err := s.Repo.ListRules(ctx, userUuid, sensorUuid)
+	if err != nil {
+		return nil, errors.New("failed to retrieve alert rules")
+	}
+	return rules, nil
+}
+
+func (s *AlertServiceImpl) ListAlerts(ctx context.Context, userUuid uuid.UUID, state string) ([]domain.Alert, error) {
+	switch state {
+	case "", domain.ALERT_STATE_FIRING, domain.ALERT_STATE_ACKNOWLEDGED, domain.ALERT_STATE_RESOLVED:
+	default:
+		return nil, errors.New("state must be firing, acknowledged or resolved")
+	}
+
+	alerts, err := s.Repo.ListAlerts(ctx, userUuid, state)
+	if err != nil {
+		return nil, errors.New("failed to retrieve alerts")
+	}
+	return alerts, nil
+}
+
+func (s *AlertServiceImpl) AcknowledgeAlert(ctx context.Context, alertUuid uuid.UUID, userUuid uuid.UUID) error {
+	acknowledged, err := s.Repo.AcknowledgeAlert(ctx, alertUuid, userUuid, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !acknowledged {
+		return domain.ErrAlertNotFound
+	}
+	return nil
+}
+
+// Imported readings are history, they don't fire or resolve alerts
+func (s *AlertServiceImpl) SkipsImportedData() bool {
+	return true
+}
+
+func (s *AlertServiceImpl) OnSensorData(ctx context.Context, sensorData []*sensor_data_domain.SensorData) {
+	// Readings are evaluated per sensor in chronological order, missing placeholders carry no value to evaluate
+	bySensor := make(map[uuid.UUID][]*sensor_data_domain.SensorData)
+	for _, data := range sensorData {
+		if data.Quality == sensor_data_domain.SENSOR_DATA_QUALITY_MISSING {
+			continue
+		}
+		bySensor[data.SensorUuid] = append(bySensor[data.SensorUuid], data)
+	}
+
+	for sensorUuid, readings := range bySensor {
+		sort.SliceStable(readings, func(i, j int) bool {
+			return readings[i].Timestamp.Before(readings[j].Timestamp)
+		})
+
+		if err := s.evaluateReadings(ctx, sensorUuid, readings); err != nil {
+			log.Printf("Failed to evaluate alert rules of sensor %s: %v", sensorUuid.String(), err)
+		}
+	}
+}
+
+// Evaluates the enabled rules of a sensor against its new readings
+func (s *AlertServiceImpl) evaluateReadings(ctx context.Context, sensorUuid uuid.UUID, readings []*sensor_data_domain.SensorData) error {
+	rules, err := s.Repo.ListSensorRules(ctx, sensorUuid)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+
+		// Readings older than the last one evaluated (e.g. late or back-dated) don't change the rule's state
+		fresh := newerReadings(readings, rule.EvaluatedUntil)
+		if len(fresh) == 0 {
+			continue
+		}
+		last := fresh[len(fresh)-1]
+
+		active, err := s.Repo.GetActiveAlert(ctx, rule.ID)
+		if err != nil {
+			return err
+		}
+
+		switch rule.Type {
+		case domain.ALERT_RULE_NO_DATA:
+			// Any reading means the sensor is sending data again
+			if active != nil {
+				if err = s.resolve(ctx, rule, active, last.Timestamp); err != nil {
+					return err
+				}
+			}
+			if err = s.Repo.SetConditionSince(ctx, rule.ID, nil, last.Timestamp); err != nil {
+				return err
+			}
+		case domain.ALERT_RULE_THRESHOLD:
+			if err = s.evaluateThreshold(ctx, rule, active, fresh); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the chronologically sorted readings after the given timestamp (all of them when nil)
+func newerReadings(readings []*sensor_data_domain.SensorData, after *time.Time) []*sensor_data_domain.SensorData {
+	if after == nil {
+		return readings
+	}
+	first := sort.Search(len(readings), func(i int) bool {
+		return readings[i].Timestamp.After(*after)
+	})
+	return readings[first:]
+}
+
+// Tracks since when the threshold holds through the readings, firing once it held
+// for the rule's duration and resolving when a reading no longer breaches it
+func (s *AlertServiceImpl) evaluateThreshold(ctx context.Context, rule *domain.AlertRule, active *domain.Alert, readings []*sensor_data_domain.SensorData) error {
+	since := rule.ConditionSince
+
+	for _, reading := range readings {
+		if !rule.Matches(reading.Value) {
+			since = nil
+			if active != nil {
+				if err := s.resolve(ctx, rule, active, reading.Timestamp); err != nil {
+					return err
+				}
+				active = nil
+			}
+			continue
+		}
+
+		if since == nil {
+			timestamp := reading.Timestamp
+			since = &timestamp
+		}
+
+		if active == nil && reading.Timestamp.Sub(*since) >= rule.Duration() {
+			value := reading.Value
+			message := fmt.Sprintf("value %g %s %g since %s", value, rule.Operator, rule.Threshold, since.UTC().Format(time.RFC3339))
+			alert, err := s.fire(ctx, rule, &value, message, reading.Timestamp)
+			if err != nil {
+				return err
+			}
+			active = alert
+		}
+	}
+
+	return s.Repo.SetConditionSince(ctx, rule.ID, since, readings[len(readings)-1].Timestamp)
+}
+
+func (s *AlertServiceImpl) EvaluateAll(ctx context.Context, now time.Time) error {
+	rules, err := s.Repo.ListEnabledRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if err = s.evaluateElapsed(ctx, rule, now); err != nil {
+			log.Printf("Failed to evaluate alert rule %s: %v", rule.ID.String(), err)
+		}
+	}
+	return nil
+}
+
+// Evaluates the conditions of a rule that can be met without new readings
+func (s *AlertServiceImpl) evaluateElapsed(ctx context.Context, rule *domain.AlertRule, now time.Time) error {
+	active, err := s.Repo.GetActiveAlert(ctx, rule.ID)
+	if err != nil {
+		return err
+	}
+
+	switch rule.Type {
+	case domain.ALERT_RULE_THRESHOLD:
+		if active != nil || rule.ConditionSince == nil || now.Sub(*rule.ConditionSince) < rule.Duration() {
+			return nil
+		}
+		message := fmt.Sprintf("value %s %g since %s", rule.Operator, rule.Threshold, rule.ConditionSince.UTC().Format(time.RFC3339))
+		_, err = s.fire(ctx, rule, nil, message, now)
+		return err
+
+	case domain.ALERT_RULE_NO_DATA:
+		last, err := s.Repo.GetLastReadingTime(ctx, rule.SensorUuid)
+		if err != nil {
+			return err
+		}
+
+		if last != nil && now.Sub(*last) < rule.Duration() {
+			if active != nil {
+				return s.resolve(ctx, rule, active, *last)
+			}
+			return nil
+		}
+
+		if active != nil {
+			return nil
+		}
+
+		message := "no data received yet"
+		if last != nil {
+			message = fmt.Sprintf("no data since %s", last.UTC().Format(time.RFC3339))
+		}
+		_, err = s.fire(ctx, rule, nil, message, now)
+		return err
+	}
+	return nil
+}
+
+func (s *AlertServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.EvaluateAll(ctx, now.UTC()); err != nil {
+				log.Printf("Failed to evaluate alert rules: %v", err)
+			}
+		}
+	}
+}
+
+// Stores a firing alert of the rule and notifies the sensor's owner
+func (s *AlertServiceImpl) fire(ctx context.Context, rule *domain.AlertRule, value *float64, message string, firedAt time.Time) (*domain.Alert, error) {
+	alert := &domain.Alert{
+		ID:         uuid.NewV4(),
+		RuleUuid:   rule.ID,
+		SensorUuid: rule.SensorUuid,
+		State:      domain.ALERT_STATE_FIRING,
+		Value:      value,
+		Message:    message,
+		FiredAt:    firedAt,
+	}
+
+	if err := s.Repo.CreateAlert(ctx, alert); err != nil {
+		// Another evaluation fired it concurrently, it was already notified
+		if errors.Is(err, domain.ErrAlertAlreadyFiring) {
+			return s.Repo.GetActiveAlert(ctx, rule.ID)
+		}
+		return nil, err
+	}
+
+	s.notify(ctx, rule, "Alert", alert.Message)
+	for _, listener := range s.Listeners {
+		listener.OnAlertFired(ctx, rule, alert)
+	}
+	return alert, nil
+}
+
+// Marks the alert as resolved and notifies the sensor's owner
+func (s *AlertServiceImpl) resolve(ctx context.Context, rule *domain.AlertRule, alert *domain.Alert, resolvedAt time.Time) error {
+	if err := s.Repo.ResolveAlert(ctx, alert.ID, resolvedAt); err != nil {
+		return err
+	}
+
+	s.notify(ctx, rule, "Resolved", fmt.Sprintf("condition cleared at %s", resolvedAt.UTC().Format(time.RFC3339)))
+	return nil
+}
+
+// Emails the owner of the rule's sensor without blocking the evaluation
+func (s *AlertServiceImpl) notify(ctx context.Context, rule *domain.AlertRule, status string, detail string) {
+	contact, err := s.Repo.GetSensorContact(ctx, rule.SensorUuid)
+	if err != nil {
+		log.Printf("Failed to notify alert rule %s: %v", rule.ID.String(), err)
+		return
+	}
+
+	subject := fmt.Sprintf("[%s] %s on sensor %s", status, rule.Name, contact.SensorName)
+	body := fmt.Sprintf("Hello %s,\n\nAlert rule \"%s\" on sensor \"%s\": %s.", contact.OwnerName, rule.Name, contact.SensorName, detail)
+
+	go func() {
+		if err := s.Notify(contact.OwnerEmail, subject, body); err != nil {
+			log.Printf("Failed to send alert email to %s: %v", contact.OwnerEmail, err)
+		}
+	}()
+}
@@ -0,0 +1,340 @@
+package usecase
+
+import (
+	"api/internal/alerts/domain"
+	"api/internal/alerts/repository"
+	sensor_data_domain "api/internal/sensors_data/domain"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// In-memory repository holding the rules and alerts of one sensor
+type fakeRepository struct {
+	repository.AlertRepository
+	rules      []domain.AlertRule
+	alerts     []*domain.Alert
+	lastUpdate *time.Time
+}
+
+func (r *fakeRepository) ListSensorRules(ctx context.Context, sensorUuid uuid.UUID) ([]domain.AlertRule, error) {
+	return append([]domain.AlertRule(nil), r.rules...), nil
+}
+
+func (r *fakeRepository) ListEnabledRules(ctx context.Context) ([]domain.AlertRule, error) {
+	return append([]domain.AlertRule(nil), r.rules...), nil
+}
+
+func (r *fakeRepository) GetRule(ctx context.Context, ruleUuid uuid.UUID, ownerUuid uuid.UUID) (*domain.AlertRule, error) {
+	for i := range r.rules {
+		if r.rules[i].ID == ruleUuid && r.rules[i].OwnerUuid == ownerUuid {
+			rule := r.rules[i]
+			return &rule, nil
+		}
+	}
+	return nil, domain.ErrAlertRuleNotFound
+}
+
+func (r *fakeRepository) EditRule(ctx context.Context, rule *domain.AlertRule) error {
+	for i := range r.rules {
+		if r.rules[i].ID == rule.ID {
+			r.rules[i] = *rule
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) SetConditionSince(ctx context.Context, ruleUuid uuid.UUID, since *time.Time, evaluatedUntil time.Time) error {
+	for i := range r.rules {
+		if r.rules[i].ID == ruleUuid {
+			r.rules[i].ConditionSince = since
+			r.rules[i].EvaluatedUntil = &evaluatedUntil
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetActiveAlert(ctx context.Context, ruleUuid uuid.UUID) (*domain.Alert, error) {
+	for _, alert := range r.alerts {
+		if alert.RuleUuid == ruleUuid && alert.State != domain.ALERT_STATE_RESOLVED {
+			return alert, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRepository) CreateAlert(ctx context.Context, alert *domain.Alert) error {
+	// Like the unique index, a rule can only have one open alert
+	if active, _ := r.GetActiveAlert(ctx, alert.RuleUuid); active != nil {
+		return domain.ErrAlertAlreadyFiring
+	}
+	r.alerts = append(r.alerts, alert)
+	return nil
+}
+
+func (r *fakeRepository) ResolveAlert(ctx context.Context, alertUuid uuid.UUID, resolvedAt time.Time) error {
+	for _, alert := range r.alerts {
+		if alert.ID == alertUuid {
+			alert.State = domain.ALERT_STATE_RESOLVED
+			alert.ResolvedAt = &resolvedAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorContact, error) {
+	return &domain.SensorContact{SensorName: "greenhouse", OwnerName: "owner", OwnerEmail: "owner@example.com"}, nil
+}
+
+func (r *fakeRepository) GetLastReadingTime(ctx context.Context, sensorUuid uuid.UUID) (*time.Time, error) {
+	return r.lastUpdate, nil
+}
+
+func newTestService(rule domain.AlertRule) (*AlertServiceImpl, *fakeRepository, *sync.WaitGroup) {
+	repo := &fakeRepository{rules: []domain.AlertRule{rule}}
+	var sent sync.WaitGroup
+	service := &AlertServiceImpl{
+		Repo: repo,
+		Notify: func(to, subject, body string) error {
+			sent.Done()
+			return nil
+		},
+	}
+	return service, repo, &sent
+}
+
+func reading(sensorUuid uuid.UUID, at time.Time, value float64) *sensor_data_domain.SensorData {
+	return &sensor_data_domain.SensorData{SensorUuid: sensorUuid, Timestamp: at, Value: value}
+}
+
+func TestThresholdFiresAfterDurationAndResolves(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	rule := domain.AlertRule{
+		ID:              uuid.NewV4(),
+		SensorUuid:      sensorUuid,
+		Name:            "too hot",
+		Type:            domain.ALERT_RULE_THRESHOLD,
+		Operator:        domain.ALERT_OPERATOR_GREATER,
+		Threshold:       30,
+		DurationMinutes: 10,
+		Enabled:         true,
+	}
+	service, repo, sent := newTestService(rule)
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	// Breaching for 5 minutes isn't enough
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{
+		reading(sensorUuid, start.Add(5*time.Minute), 32),
+		reading(sensorUuid, start, 31),
+	})
+	if len(repo.alerts) != 0 {
+		t.Fatalf("expected no alert after 5 minutes, got %d", len(repo.alerts))
+	}
+	if repo.rules[0].ConditionSince == nil || !repo.rules[0].ConditionSince.Equal(start) {
+		t.Fatalf("expected condition to hold since %v, got %v", start, repo.rules[0].ConditionSince)
+	}
+
+	// Still breaching 10 minutes after the first reading
+	sent.Add(1)
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{reading(sensorUuid, start.Add(10*time.Minute), 33)})
+	if len(repo.alerts) != 1 || repo.alerts[0].State != domain.ALERT_STATE_FIRING || *repo.alerts[0].Value != 33 {
+		t.Fatalf("expected one firing alert with value 33, got %+v", repo.alerts)
+	}
+
+	// Breaching further doesn't fire again
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{reading(sensorUuid, start.Add(15*time.Minute), 34)})
+	if len(repo.alerts) != 1 {
+		t.Fatalf("expected a single alert while firing, got %d", len(repo.alerts))
+	}
+
+	// Back under the threshold resolves the alert
+	sent.Add(1)
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{reading(sensorUuid, start.Add(20*time.Minute), 25)})
+	if repo.alerts[0].State != domain.ALERT_STATE_RESOLVED {
+		t.Fatalf("expected the alert to be resolved, got %s", repo.alerts[0].State)
+	}
+	if repo.rules[0].ConditionSince != nil {
+		t.Fatalf("expected condition to be cleared, got %v", repo.rules[0].ConditionSince)
+	}
+
+	sent.Wait()
+}
+
+func TestNoDataFiresAndResolvesOnReading(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	rule := domain.AlertRule{
+		ID:              uuid.NewV4(),
+		SensorUuid:      sensorUuid,
+		Name:            "offline",
+		Type:            domain.ALERT_RULE_NO_DATA,
+		DurationMinutes: 30,
+		Enabled:         true,
+	}
+	service, repo, sent := newTestService(rule)
+	last := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo.lastUpdate = &last
+	ctx := context.Background()
+
+	if err := service.EvaluateAll(ctx, last.Add(20*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.alerts) != 0 {
+		t.Fatalf("expected no alert before 30 minutes, got %d", len(repo.alerts))
+	}
+
+	sent.Add(1)
+	if err := service.EvaluateAll(ctx, last.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.alerts) != 1 || repo.alerts[0].State != domain.ALERT_STATE_FIRING {
+		t.Fatalf("expected one firing alert, got %+v", repo.alerts)
+	}
+
+	sent.Add(1)
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{reading(sensorUuid, last.Add(40*time.Minute), 20)})
+	if repo.alerts[0].State != domain.ALERT_STATE_RESOLVED {
+		t.Fatalf("expected the alert to be resolved, got %s", repo.alerts[0].State)
+	}
+
+	sent.Wait()
+}
+
+func TestMissingReadingsAreIgnored(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	rule := domain.AlertRule{
+		ID:              uuid.NewV4(),
+		SensorUuid:      sensorUuid,
+		Name:            "offline",
+		Type:            domain.ALERT_RULE_NO_DATA,
+		DurationMinutes: 30,
+		Enabled:         true,
+	}
+	service, repo, sent := newTestService(rule)
+	repo.rules = append(repo.rules, thresholdRule(sensorUuid))
+	last := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	repo.lastUpdate = &last
+	ctx := context.Background()
+
+	sent.Add(1)
+	if err := service.EvaluateAll(ctx, last.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A placeholder for a missed reading neither resolves the no data alert nor breaches the threshold
+	missing := reading(sensorUuid, last.Add(40*time.Minute), 40)
+	missing.Quality = sensor_data_domain.SENSOR_DATA_QUALITY_MISSING
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{missing})
+	if len(repo.alerts) != 1 || repo.alerts[0].State != domain.ALERT_STATE_FIRING {
+		t.Fatalf("expected the no data alert to keep firing, got %+v", repo.alerts)
+	}
+	if repo.rules[1].ConditionSince != nil || repo.rules[1].EvaluatedUntil != nil {
+		t.Fatalf("expected the threshold not to be evaluated, got condition since %v", repo.rules[1].ConditionSince)
+	}
+
+	sent.Wait()
+}
+
+func thresholdRule(sensorUuid uuid.UUID) domain.AlertRule {
+	return domain.AlertRule{
+		ID:              uuid.NewV4(),
+		SensorUuid:      sensorUuid,
+		OwnerUuid:       uuid.NewV4(),
+		Name:            "too hot",
+		Type:            domain.ALERT_RULE_THRESHOLD,
+		Operator:        domain.ALERT_OPERATOR_GREATER,
+		Threshold:       30,
+		DurationMinutes: 10,
+		Enabled:         true,
+	}
+}
+
+func TestReadingsOlderThanEvaluatedAreIgnored(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	rule := thresholdRule(sensorUuid)
+	evaluated := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	rule.EvaluatedUntil = &evaluated
+	service, repo, _ := newTestService(rule)
+	ctx := context.Background()
+
+	// Back-dated readings breaching for an hour don't fire
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{
+		reading(sensorUuid, evaluated.Add(-time.Hour), 40),
+		reading(sensorUuid, evaluated, 40),
+	})
+	if len(repo.alerts) != 0 || repo.rules[0].ConditionSince != nil {
+		t.Fatalf("expected back-dated readings to be ignored, got %+v and condition since %v", repo.alerts, repo.rules[0].ConditionSince)
+	}
+
+	// Only the newer readings of a batch are evaluated
+	service.OnSensorData(ctx, []*sensor_data_domain.SensorData{
+		reading(sensorUuid, evaluated.Add(-time.Hour), 40),
+		reading(sensorUuid, evaluated.Add(time.Minute), 40),
+	})
+	if since := repo.rules[0].ConditionSince; since == nil || !since.Equal(evaluated.Add(time.Minute)) {
+		t.Fatalf("expected the condition to hold since the newer reading, got %v", since)
+	}
+	if len(repo.alerts) != 0 {
+		t.Fatalf("expected no alert, got %+v", repo.alerts)
+	}
+}
+
+func TestFireWhenAlreadyFiring(t *testing.T) {
+	rule := thresholdRule(uuid.NewV4())
+	service, repo, sent := newTestService(rule)
+	ctx := context.Background()
+
+	sent.Add(1)
+	first, err := service.fire(ctx, &rule, nil, "first", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A concurrent evaluation gets the open alert instead of firing and notifying again
+	second, err := service.fire(ctx, &rule, nil, "second", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == nil || second.ID != first.ID || len(repo.alerts) != 1 {
+		t.Fatalf("expected the open alert to be returned, got %+v", repo.alerts)
+	}
+
+	sent.Wait()
+}
+
+func TestDisablingRuleResolvesAlert(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	rule := thresholdRule(sensorUuid)
+	since := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	rule.ConditionSince = &since
+	service, repo, sent := newTestService(rule)
+	ctx := context.Background()
+
+	sent.Add(1)
+	if _, err := service.fire(ctx, &rule, nil, "value > 30", since.Add(10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	edited := rule
+	edited.Enabled = false
+	sent.Add(1)
+	if err := service.EditRule(ctx, &edited, rule.OwnerUuid); err != nil {
+		t.Fatal(err)
+	}
+	if repo.alerts[0].State != domain.ALERT_STATE_RESOLVED {
+		t.Fatalf("expected the alert to be resolved, got %s", repo.alerts[0].State)
+	}
+	if repo.rules[0].ConditionSince != nil {
+		t.Fatalf("expected the condition to be reset, got %v", repo.rules[0].ConditionSince)
+	}
+
+	if err := service.EditRule(ctx, &edited, uuid.NewV4()); !errors.Is(err, domain.ErrAlertRuleNotFound) {
+		t.Errorf("expected the rule of another user to be hidden, got %v", err)
+	}
+
+	sent.Wait()
+}
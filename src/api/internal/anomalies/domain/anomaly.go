@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+const (
+	// Detection methods
+	// Distance to the mean of the baseline, in standard deviations
+	ANOMALY_METHOD_ZSCORE string = "zscore"
+	// Distance to the median of the baseline, in median absolute deviations (modified z-score)
+	ANOMALY_METHOD_MAD string = "mad"
+)
+
+const (
+	// Number of previous readings the baseline is computed over when not set
+	DefaultWindow = 30
+	// Bounds of the baseline window
+	MinWindow = 5
+	MaxWindow = 1000
+)
+
+// Score above which a reading is flagged when no threshold is set, for each method
+var DefaultThresholds = map[string]float64{
+	ANOMALY_METHOD_ZSCORE: 3,
+	ANOMALY_METHOD_MAD:    3.5,
+}
+
+var (
+	// Returned when the sensor has no anomaly detection settings
+	ErrSettingsNotFound = errors.New("anomaly detection is not enabled on this sensor")
+	// Returned when the sensor doesn't exist
+	ErrSensorNotFound = errors.New("sensor not found")
+	// Returned when the user manages the settings of a sensor they don't own
+	ErrNotSensorOwner = errors.New("user is not the owner of the sensor")
+)
+
+// AnomalySettings enables the detection of anomalies on the readings of a sensor
+type AnomalySettings struct {
+	// UUID of the sensor the readings are analyzed of
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Detection method: zscore or mad
+	Method string `json:"method"`
+	// Number of previous readings, not flagged as anomalies, the baseline is computed over
+	Window int `json:"window"`
+	// Score above which a reading is flagged
+	Threshold float64 `json:"threshold"`
+	// Whether the owner is emailed when anomalies are detected
+	Notify bool `json:"notify"`
+}
+
+// SensorContact holds what is needed to email the owner of a sensor
+type SensorContact struct {
+	// Name of the sensor
+	SensorName string
+	// Name of the owner
+	OwnerName string
+	// Email of the owner
+	OwnerEmail string
+}
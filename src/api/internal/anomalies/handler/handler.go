@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"api/internal/anomalies/domain"
+	anomaly_service "api/internal/anomalies/usecase"
+	user_service "api/internal/users/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for handling HTTP requests related to anomaly detection
+type AnomalyHandler interface {
+	// Handles the HTTP request to enable or update the anomaly detection of a sensor
+	SetSettings(c *gin.Context)
+	// Handles the HTTP request to disable the anomaly detection of a sensor
+	DeleteSettings(c *gin.Context)
+	// Handles the HTTP request to list the anomaly detection settings of the user's sensors
+	ListSettings(c *gin.Context)
+}
+
+// Structure request to set the anomaly detection of a sensor
+type AnomalySettingsRequest struct {
+	// Sensor UUID
+	SensorUuid uuid.UUID `json:"sensorUuid" binding:"required"`
+	// Detection method: zscore (default) or mad
+	Method string `json:"method"`
+	// Number of previous readings the baseline is computed over, defaults to 30
+	Window int `json:"window"`
+	// Score above which a reading is flagged, defaults to 3 for zscore and 3.5 for mad
+	Threshold float64 `json:"threshold"`
+	// Whether the owner is emailed when anomalies are detected, defaults to true
+	Notify *bool `json:"notify"`
+}
+
+// Structure request identifying the sensor of the settings
+type AnomalySensorRequest struct {
+	// Sensor UUID
+	SensorUuid uuid.UUID `json:"sensorUuid" binding:"required"`
+}
+
+// Process HTTP requests and interaction with AnomalyService/UserService for anomaly detection operations
+type AnomalyHandlerImpl struct {
+	AnomalyService anomaly_service.AnomalyService
+	UserService    user_service.UserService
+}
+
+func NewAnomalyHandler(anomalyService anomaly_service.AnomalyService, userService user_service.UserService) AnomalyHandler {
+	return &AnomalyHandlerImpl{
+		AnomalyService: anomalyService,
+		UserService:    userService,
+	}
+}
+
+// Gets the uuid of the user authenticated by the request's token
+func (h *AnomalyHandlerImpl) getUserUuid(c *gin.Context) (uuid.UUID, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	return h.UserService.GetUserByToken(c.Request.Context(), str)
+}
+
+// Maps errors from the anomaly service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSettingsNotFound), errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func (h *AnomalyHandlerImpl) SetSettings(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AnomalySettingsRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	notify := true
+	if req.Notify != nil {
+		notify = *req.Notify
+	}
+
+	settings := &domain.AnomalySettings{
+		SensorUuid: req.SensorUuid,
+		Method:     req.Method,
+		Window:     req.Window,
+		Threshold:  req.Threshold,
+		Notify:     notify,
+	}
+	if err = h.AnomalyService.SetSettings(c.Request.Context(), settings, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+func (h *AnomalyHandlerImpl) DeleteSettings(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req AnomalySensorRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.AnomalyService.DeleteSettings(c.Request.Context(), req.SensorUuid, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *AnomalyHandlerImpl) ListSettings(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	settings, err := h.AnomalyService.ListSettings(c.Request.Context(), userUuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if settings == nil {
+		settings = []domain.AnomalySettings{}
+	}
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	config "api/configs"
+	"api/internal/anomalies/domain"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for anomaly detection data operations
+type AnomalyRepository interface {
+	// Stores the settings of a sensor, replacing the existing ones
+	SetSettings(ctx context.Context, settings *domain.AnomalySettings) error
+	// Retrieves the settings of a sensor
+	GetSettings(ctx context.Context, sensorUuid uuid.UUID) (*domain.AnomalySettings, error)
+	// Deletes the settings of a sensor
+	DeleteSettings(ctx context.Context, sensorUuid uuid.UUID) error
+	// Retrieves the settings of the sensors owned by the user
+	ListSettings(ctx context.Context, userUuid uuid.UUID) ([]domain.AnomalySettings, error)
+	// Retrieves the settings of the sensors that have some, by sensor
+	ListSensorSettings(ctx context.Context, sensorUuids []uuid.UUID) (map[uuid.UUID]domain.AnomalySettings, error)
+	// Retrieves, oldest first, the last limit values of the sensor before the given time that aren't anomalies nor missing
+	GetBaseline(ctx context.Context, sensorUuid uuid.UUID, before time.Time, limit int) ([]float64, error)
+	// Retrieves the uuid of the user who owns the sensor
+	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error)
+	// Retrieves the name of the sensor and the name and email of its owner
+	GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorContact, error)
+}
+
+// Performs anomaly detection data operations using database/sql to interact with the database
+type AnomalyRepositoryImpl struct {
+	DB *sql.DB
+}
+
+func NewAnomalyRepository() (AnomalyRepository, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	return &AnomalyRepositoryImpl{DB: db}, nil
+}
+
+// Columns selected for settings, in the order read by scanSettings
+const settingsColumns = `anomaly_settings.sensorUuid, anomaly_settings.method, anomaly_settings.windowSize,
+	anomaly_settings.threshold, anomaly_settings.notify`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSettings(row scanner) (*domain.AnomalySettings, error) {
+	var settings domain.AnomalySettings
+	if err := row.Scan(&settings.SensorUuid, &settings.Method, &settings.Window, &settings.Threshold, &settings.Notify); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *AnomalyRepositoryImpl) SetSettings(ctx context.Context, settings *domain.AnomalySettings) error {
+	query := `
+		MERGE anomaly_settings AS target
+		USING (SELECT @sensorUuid AS sensorUuid) AS source
+		ON target.sensorUuid = source.sensorUuid
+		WHEN MATCHED THEN
+			UPDATE SET method = @method, windowSize = @window, threshold = @threshold, notify = @notify
+		WHEN NOT MATCHED THEN
+			INSERT (sensorUuid, method, windowSize, threshold, notify)
+			VALUES (@sensorUuid, @method, @window, @threshold, @notify);
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("sensorUuid", settings.SensorUuid),
+		sql.Named("method", settings.Method),
+		sql.Named("window", settings.Window),
+		sql.Named("threshold", settings.Threshold),
+		sql.Named("notify", settings.Notify),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set anomaly settings: %v", err)
+	}
+	return nil
+}
+
+func (r *AnomalyRepositoryImpl) GetSettings(ctx context.Context, sensorUuid uuid.UUID) (*domain.AnomalySettings, error) {
+	query := `SELECT ` + settingsColumns + ` FROM anomaly_settings WHERE sensorUuid = @sensorUuid`
+
+	settings, err := scanSettings(r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrSettingsNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve anomaly settings: %v", err)
+	}
+	return settings, nil
+}
+
+func (r *AnomalyRepositoryImpl) DeleteSettings(ctx context.Context, sensorUuid uuid.UUID) error {
+	query := `DELETE FROM anomaly_settings WHERE sensorUuid = @sensorUuid`
+
+	result, err := r.DB.ExecContext(ctx, query, sql.Named("sensorUuid", sensorUuid))
+	if err != nil {
+		return fmt.Errorf("failed to delete anomaly settings: %v", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrSettingsNotFound
+	}
+	return nil
+}
+
+// Reads every settings row of the query
+func (r *AnomalyRepositoryImpl) querySettings(ctx context.Context, query string, args ...any) ([]domain.AnomalySettings, error) {
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list anomaly settings: %v", err)
+	}
+	defer rows.Close()
+
+	var settings []domain.AnomalySettings
+	for rows.Next() {
+		sensorSettings, err := scanSettings(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan anomaly settings: %v", err)
+		}
+		settings = append(settings, *sensorSettings)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating anomaly settings: %v", err)
+	}
+
+	return settings, nil
+}
+
+func (r *AnomalyRepositoryImpl) ListSettings(ctx context.Context, userUuid uuid.UUID) ([]domain.AnomalySettings, error) {
+	query := `
+		SELECT ` + settingsColumns + `
+		FROM anomaly_settings
+		INNER JOIN Sensors ON Sensors.uuid = anomaly_settings.sensorUuid
+		WHERE Sensors.sensorOwnerUuid = @userUuid
+		ORDER BY Sensors.name
+	`
+
+	return r.querySettings(ctx, query, sql.Named("userUuid", userUuid))
+}
+
+func (r *AnomalyRepositoryImpl) ListSensorSettings(ctx context.Context, sensorUuids []uuid.UUID) (map[uuid.UUID]domain.AnomalySettings, error) {
+	var bySensor = make(map[uuid.UUID]domain.AnomalySettings)
+	if len(sensorUuids) == 0 {
+		return bySensor, nil
+	}
+
+	// One named parameter per sensor
+	var names []string
+	var args []any
+	for i, sensorUuid := range sensorUuids {
+		var name = fmt.Sprintf("sensor%d", i)
+		names = append(names, "@"+name)
+		args = append(args, sql.Named(name, sensorUuid))
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM anomaly_settings WHERE sensorUuid IN (%s)`, settingsColumns, strings.Join(names, ", "))
+
+	settings, err := r.querySettings(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, sensorSettings := range settings {
+		bySensor[sensorSettings.SensorUuid] = sensorSettings
+	}
+	return bySensor, nil
+}
+
+func (r *AnomalyRepositoryImpl) GetBaseline(ctx context.Context, sensorUuid uuid.UUID, before time.Time, limit int) ([]float64, error) {
+	// Only raw readings are used, rollups average away the spread the scores rely on
+	query := `
+		SELECT TOP (@limit) value
+		FROM SensorData
+		WHERE sensorUuid = @sensorUuid
+		AND timestamp < @before
+		AND anomaly = 0
+		AND quality <> 'missing'
+		ORDER BY timestamp DESC
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query,
+		sql.Named("limit", limit),
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("before", before),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch baseline: %v", err)
+	}
+	defer rows.Close()
+
+	var values []float64
+	for rows.Next() {
+		var value float64
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan baseline: %v", err)
+		}
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating baseline: %v", err)
+	}
+
+	// Oldest first
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	return values, nil
+}
+
+func (r *AnomalyRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT sensorOwnerUuid FROM Sensors WHERE uuid = @sensorUuid`
+
+	var ownerUuid uuid.UUID
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&ownerUuid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.NilUUID, domain.ErrSensorNotFound
+		}
+		return uuid.NilUUID, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return ownerUuid, nil
+}
+
+func (r *AnomalyRepositoryImpl) GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorContact, error) {
+	query := `
+		SELECT Sensors.name, users.name, users.email
+		FROM Sensors
+		INNER JOIN users ON users.uuid = Sensors.sensorOwnerUuid
+		WHERE Sensors.uuid = @sensorUuid
+	`
+
+	var contact domain.SensorContact
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&contact.SensorName, &contact.OwnerName, &contact.OwnerEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrSensorNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return &contact, nil
+}
@@ -0,0 +1,60 @@
+package anomalies
+
+import (
+	"api/internal/anomalies/handler"
+	anomaly_repository "api/internal/anomalies/repository"
+	anomaly_service "api/internal/anomalies/usecase"
+	auth_repository "api/internal/auth/repository"
+	auth_service "api/internal/auth/usecase"
+	user_repository "api/internal/users/repository"
+	user_service "api/internal/users/usecase"
+	webhook_repository "api/internal/webhooks/repository"
+	webhook_service "api/internal/webhooks/usecase"
+	middleware "api/utils"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAnomalyRoutes declares the routes that can be accessed for anomaly detection management.
+// The detection itself runs when sensor data is added.
+func RegisterAnomalyRoutes(router *gin.Engine) {
+
+	anomalyRepo, err := anomaly_repository.NewAnomalyRepository()
+	if err != nil {
+		log.Fatalf("Failed to create anomaly repository: %v", err)
+	}
+
+	usersRepos, err := user_repository.NewUserRepository()
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	authRepo, err := auth_repository.NewAuthRepository()
+	if err != nil {
+		log.Fatalf("Failed to create auth repository: %v", err)
+	}
+
+	webhookRepo, err := webhook_repository.NewWebhookRepository()
+	if err != nil {
+		log.Fatalf("Failed to create webhook repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	anomalyService := anomaly_service.NewAnomalyService(anomalyRepo, middleware.CreateEmail, webhook_service.NewWebhookService(webhookRepo))
+	authService := auth_service.NewAuthService(authRepo, usersRepos)
+
+	h := handler.NewAnomalyHandler(anomalyService, userService)
+
+	// Anomaly detection routes
+	api := router.Group("/v1/anomalies/")
+	api.Use(middleware.AuthMiddleware(authService))
+	{
+		// Enable or update the anomaly detection of a sensor
+		api.POST("settings/set", h.SetSettings)
+		// Disable the anomaly detection of a sensor
+		api.POST("settings/delete", h.DeleteSettings)
+		// List the anomaly detection settings of the user's sensors
+		api.POST("settings/list", h.ListSettings)
+	}
+}
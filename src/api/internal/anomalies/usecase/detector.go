@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"api/internal/anomalies/domain"
+	"math"
+	"sort"
+)
+
+// Minimum number of baseline values a reading is scored against
+const MinBaseline = 5
+
+// Scales the median absolute deviation to the standard deviation of normally distributed values
+const madScale = 0.6745
+
+// Computes the anomaly score of the value against the baseline with the method.
+// Returns false when the reading can't be scored: the baseline is too short or has no spread.
+func score(method string, baseline []float64, value float64) (float64, bool) {
+	if len(baseline) < MinBaseline {
+		return 0, false
+	}
+
+	switch method {
+	case domain.ANOMALY_METHOD_ZSCORE:
+		mean, stdDev := meanStdDev(baseline)
+		if stdDev == 0 {
+			return 0, false
+		}
+		return math.Abs(value-mean) / stdDev, true
+
+	case domain.ANOMALY_METHOD_MAD:
+		center := median(baseline)
+		deviations := make([]float64, len(baseline))
+		for i, v := range baseline {
+			deviations[i] = math.Abs(v - center)
+		}
+		mad := median(deviations)
+		if mad == 0 {
+			return 0, false
+		}
+		return madScale * math.Abs(value-center) / mad, true
+	}
+
+	return 0, false
+}
+
+// Mean and population standard deviation of the values
+func meanStdDev(values []float64) (float64, float64) {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
+
+// Median of the values, without reordering them
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// Flags the values that are anomalies against the baseline, in order. Each value that isn't flagged
+// joins the baseline of the following ones, which is kept to the last window values.
+func detect(settings domain.AnomalySettings, baseline []float64, values []float64) []bool {
+	flags := make([]bool, len(values))
+	window := append([]float64(nil), baseline...)
+
+	for i, value := range values {
+		if len(window) > settings.Window {
+			window = window[len(window)-settings.Window:]
+		}
+
+		if s, ok := score(settings.Method, window, value); ok && s > settings.Threshold {
+			flags[i] = true
+			continue
+		}
+		window = append(window, value)
+	}
+	return flags
+}
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"api/internal/anomalies/domain"
+	"math"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	baseline := []float64{20, 21, 19, 20, 22, 18, 20}
+
+	tests := []struct {
+		name     string
+		method   string
+		baseline []float64
+		value    float64
+		want     float64
+		ok       bool
+	}{
+		// mean 20, population standard deviation 1.195
+		{"zscore", domain.ANOMALY_METHOD_ZSCORE, baseline, 26, 5.0199, true},
+		// median 20, median absolute deviation 1
+		{"mad", domain.ANOMALY_METHOD_MAD, baseline, 26, 4.047, true},
+		{"baseline too short", domain.ANOMALY_METHOD_ZSCORE, baseline[:MinBaseline-1], 26, 0, false},
+		{"flat baseline", domain.ANOMALY_METHOD_ZSCORE, []float64{5, 5, 5, 5, 5}, 6, 0, false},
+		{"unknown method", "iqr", baseline, 26, 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := score(test.method, test.baseline, test.value)
+			if ok != test.ok {
+				t.Fatalf("expected scored %v, got %v", test.ok, ok)
+			}
+			if math.Abs(got-test.want) > 1e-3 {
+				t.Errorf("expected score %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	settings := domain.AnomalySettings{Method: domain.ANOMALY_METHOD_MAD, Window: 6, Threshold: 3.5}
+	baseline := []float64{20, 21, 19, 20, 22, 18}
+
+	// The spike is flagged and kept out of the baseline, so the following reading is scored against normal values
+	flags := detect(settings, baseline, []float64{20.5, 60, 61, 19.5})
+
+	want := []bool{false, true, true, false}
+	for i := range want {
+		if flags[i] != want[i] {
+			t.Errorf("reading %d: expected anomaly %v, got %v", i, want[i], flags[i])
+		}
+	}
+}
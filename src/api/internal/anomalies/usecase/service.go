@@ -0,0 +1,247 @@
+package usecase
+
+import (
+	"api/internal/anomalies/domain"
+	"api/internal/anomalies/repository"
+	sensor_data_domain "api/internal/sensors_data/domain"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Minimum time between two anomaly emails about the same sensor
+const NotificationCooldown = time.Hour
+
+// Interface for anomaly detection services
+type AnomalyService interface {
+	// Enables or updates the detection on a sensor owned by the user
+	SetSettings(ctx context.Context, settings *domain.AnomalySettings, userUuid uuid.UUID) error
+	// Disables the detection on a sensor owned by the user
+	DeleteSettings(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) error
+	// Lists the settings of the user's sensors
+	ListSettings(ctx context.Context, userUuid uuid.UUID) ([]domain.AnomalySettings, error)
+	// Flags the readings that deviate from the baseline of their sensor, before they're stored
+	Analyze(ctx context.Context, sensorData []*sensor_data_domain.SensorData)
+	// Notifies the anomalies among the stored readings
+	OnSensorData(ctx context.Context, sensorData []*sensor_data_domain.SensorData)
+}
+
+// Receives the anomalies detected by the service
+type AnomalyListener interface {
+	// Called after readings flagged as anomalies are stored, with the readings of one sensor
+	OnAnomalies(ctx context.Context, sensorUuid uuid.UUID, anomalies []*sensor_data_domain.SensorData)
+}
+
+// Sends an email
+type NotifyFunc func(to, subject, body string) error
+
+// Handles anomaly detection logic and interaction with the repository
+type AnomalyServiceImpl struct {
+	Repo      repository.AnomalyRepository
+	Notify    NotifyFunc
+	Listeners []AnomalyListener
+
+	// When the owner of each sensor was last emailed
+	mu           sync.Mutex
+	lastNotified map[uuid.UUID]time.Time
+}
+
+func NewAnomalyService(repo repository.AnomalyRepository, notify NotifyFunc, listeners ...AnomalyListener) AnomalyService {
+	return &AnomalyServiceImpl{
+		Repo:         repo,
+		Notify:       notify,
+		Listeners:    listeners,
+		lastNotified: make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Checks the settings, filling the window and threshold when they're not set
+func validateSettings(settings *domain.AnomalySettings) error {
+	settings.Method = strings.ToLower(settings.Method)
+	if settings.Method == "" {
+		settings.Method = domain.ANOMALY_METHOD_ZSCORE
+	}
+
+	defaultThreshold, ok := domain.DefaultThresholds[settings.Method]
+	if !ok {
+		return errors.New("method must be zscore or mad")
+	}
+
+	if settings.Window == 0 {
+		settings.Window = domain.DefaultWindow
+	}
+	if settings.Window < domain.MinWindow || settings.Window > domain.MaxWindow {
+		return fmt.Errorf("window must be between %d and %d readings", domain.MinWindow, domain.MaxWindow)
+	}
+
+	if settings.Threshold == 0 {
+		settings.Threshold = defaultThreshold
+	}
+	if settings.Threshold < 0 || math.IsNaN(settings.Threshold) || math.IsInf(settings.Threshold, 0) {
+		return errors.New("threshold must be a positive number")
+	}
+	return nil
+}
+
+// Checks that the sensor exists and belongs to the user
+func (s *AnomalyServiceImpl) checkOwner(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) error {
+	ownerUuid, err := s.Repo.GetSensorOwner(ctx, sensorUuid)
+	if err != nil {
+		return err
+	}
+	if ownerUuid != userUuid {
+		return domain.ErrNotSensorOwner
+	}
+	return nil
+}
+
+func (s *AnomalyServiceImpl) SetSettings(ctx context.Context, settings *domain.AnomalySettings, userUuid uuid.UUID) error {
+	if err := validateSettings(settings); err != nil {
+		return err
+	}
+	if err := s.checkOwner(ctx, settings.SensorUuid, userUuid); err != nil {
+		return err
+	}
+	return s.Repo.SetSettings(ctx, settings)
+}
+
+func (s *AnomalyServiceImpl) DeleteSettings(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) error {
+	if err := s.checkOwner(ctx, sensorUuid, userUuid); err != nil {
+		return err
+	}
+	return s.Repo.DeleteSettings(ctx, sensorUuid)
+}
+
+func (s *AnomalyServiceImpl) ListSettings(ctx context.Context, userUuid uuid.UUID) ([]domain.AnomalySettings, error) {
+	settings, err := s.Repo.ListSettings(ctx, userUuid)
+	if err != nil {
+		return nil, errors.New("failed to retrieve anomaly settings")
+	}
+	return settings, nil
+}
+
+// Groups the readings by sensor, in the order the sensors first appear
+func groupBySensor(sensorData []*sensor_data_domain.SensorData) ([]uuid.UUID, map[uuid.UUID][]*sensor_data_domain.SensorData) {
+	var order []uuid.UUID
+	bySensor := make(map[uuid.UUID][]*sensor_data_domain.SensorData)
+	for _, data := range sensorData {
+		if _, ok := bySensor[data.SensorUuid]; !ok {
+			order = append(order, data.SensorUuid)
+		}
+		bySensor[data.SensorUuid] = append(bySensor[data.SensorUuid], data)
+	}
+	return order, bySensor
+}
+
+func (s *AnomalyServiceImpl) Analyze(ctx context.Context, sensorData []*sensor_data_domain.SensorData) {
+	order, bySensor := groupBySensor(sensorData)
+
+	// The analysis is optional, readings are stored unflagged when it fails
+	settings, err := s.Repo.ListSensorSettings(ctx, order)
+	if err != nil {
+		log.Printf("Failed to load anomaly settings: %v", err)
+		return
+	}
+
+	for _, sensorUuid := range order {
+		sensorSettings, ok := settings[sensorUuid]
+		if !ok {
+			continue
+		}
+
+		// Missing readings are placeholders, their values aren't scored
+		var readings []*sensor_data_domain.SensorData
+		for _, data := range bySensor[sensorUuid] {
+			if data.Quality != sensor_data_domain.SENSOR_DATA_QUALITY_MISSING {
+				readings = append(readings, data)
+			}
+		}
+		if len(readings) == 0 {
+			continue
+		}
+		sort.SliceStable(readings, func(i, j int) bool { return readings[i].Timestamp.Before(readings[j].Timestamp) })
+
+		baseline, err := s.Repo.GetBaseline(ctx, sensorUuid, readings[0].Timestamp, sensorSettings.Window)
+		if err != nil {
+			log.Printf("Failed to load baseline of sensor %s: %v", sensorUuid.String(), err)
+			continue
+		}
+
+		values := make([]float64, len(readings))
+		for i, data := range readings {
+			values[i] = data.Value
+		}
+
+		for i, flagged := range detect(sensorSettings, baseline, values) {
+			readings[i].Anomaly = flagged
+		}
+	}
+}
+
+func (s *AnomalyServiceImpl) OnSensorData(ctx context.Context, sensorData []*sensor_data_domain.SensorData) {
+	var anomalies []*sensor_data_domain.SensorData
+	for _, data := range sensorData {
+		if data.Anomaly {
+			anomalies = append(anomalies, data)
+		}
+	}
+	if len(anomalies) == 0 {
+		return
+	}
+
+	order, bySensor := groupBySensor(anomalies)
+
+	settings, err := s.Repo.ListSensorSettings(ctx, order)
+	if err != nil {
+		log.Printf("Failed to load anomaly settings: %v", err)
+	}
+
+	for _, sensorUuid := range order {
+		if sensorSettings, ok := settings[sensorUuid]; ok && sensorSettings.Notify && s.shouldNotify(sensorUuid, time.Now()) {
+			s.notify(ctx, sensorUuid, bySensor[sensorUuid])
+		}
+		for _, listener := range s.Listeners {
+			listener.OnAnomalies(ctx, sensorUuid, bySensor[sensorUuid])
+		}
+	}
+}
+
+// Reports whether the owner of the sensor can be emailed now, recording it if so
+func (s *AnomalyServiceImpl) shouldNotify(sensorUuid uuid.UUID, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if last, ok := s.lastNotified[sensorUuid]; ok && now.Sub(last) < NotificationCooldown {
+		return false
+	}
+	s.lastNotified[sensorUuid] = now
+	return true
+}
+
+// Emails the owner of the sensor without blocking the ingestion
+func (s *AnomalyServiceImpl) notify(ctx context.Context, sensorUuid uuid.UUID, anomalies []*sensor_data_domain.SensorData) {
+	contact, err := s.Repo.GetSensorContact(ctx, sensorUuid)
+	if err != nil {
+		log.Printf("Failed to notify anomalies of sensor %s: %v", sensorUuid.String(), err)
+		return
+	}
+
+	first := anomalies[0]
+	subject := fmt.Sprintf("[Anomaly] Unusual readings on sensor %s", contact.SensorName)
+	body := fmt.Sprintf("Hello %s,\n\n%d reading(s) of sensor \"%s\" deviate from its usual values, the first being %v at %s.",
+		contact.OwnerName, len(anomalies), contact.SensorName, first.Value, first.Timestamp.UTC().Format(time.RFC3339))
+
+	go func() {
+		if err := s.Notify(contact.OwnerEmail, subject, body); err != nil {
+			log.Printf("Failed to send anomaly email to %s: %v", contact.OwnerEmail, err)
+		}
+	}()
+}
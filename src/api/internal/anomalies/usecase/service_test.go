@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"api/internal/anomalies/domain"
+	"api/internal/anomalies/repository"
+	sensor_data_domain "api/internal/sensors_data/domain"
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Repository serving fixed settings and baselines
+type fakeRepository struct {
+	repository.AnomalyRepository
+	settings  map[uuid.UUID]domain.AnomalySettings
+	baselines map[uuid.UUID][]float64
+}
+
+func (r *fakeRepository) ListSensorSettings(ctx context.Context, sensorUuids []uuid.UUID) (map[uuid.UUID]domain.AnomalySettings, error) {
+	return r.settings, nil
+}
+
+func (r *fakeRepository) GetBaseline(ctx context.Context, sensorUuid uuid.UUID, before time.Time, limit int) ([]float64, error) {
+	return r.baselines[sensorUuid], nil
+}
+
+func (r *fakeRepository) GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorContact, error) {
+	return &domain.SensorContact{SensorName: "probe", OwnerName: "owner", OwnerEmail: "owner@example.com"}, nil
+}
+
+// Listener recording the anomalies it receives
+type recordingListener struct {
+	anomalies map[uuid.UUID]int
+}
+
+func (l *recordingListener) OnAnomalies(ctx context.Context, sensorUuid uuid.UUID, anomalies []*sensor_data_domain.SensorData) {
+	l.anomalies[sensorUuid] += len(anomalies)
+}
+
+func TestAnalyzeAndNotify(t *testing.T) {
+	analyzed, ignored := uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{
+		settings: map[uuid.UUID]domain.AnomalySettings{
+			analyzed: {SensorUuid: analyzed, Method: domain.ANOMALY_METHOD_ZSCORE, Window: 10, Threshold: 3, Notify: true},
+		},
+		baselines: map[uuid.UUID][]float64{
+			analyzed: {20, 21, 19, 20, 22, 18, 20},
+			ignored:  {20, 21, 19, 20, 22, 18, 20},
+		},
+	}
+	emails := make(chan string, 2)
+	listener := &recordingListener{anomalies: map[uuid.UUID]int{}}
+	service := NewAnomalyService(repo, func(to, subject, body string) error {
+		emails <- to
+		return nil
+	}, listener)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Readings are analyzed in time order, whatever order they're sent in
+	readings := []*sensor_data_domain.SensorData{
+		{SensorUuid: analyzed, Timestamp: start.Add(2 * time.Minute), Value: 20.5},
+		{SensorUuid: analyzed, Timestamp: start.Add(time.Minute), Value: 80},
+		{SensorUuid: ignored, Timestamp: start, Value: 80},
+	}
+
+	service.Analyze(context.Background(), readings)
+	if readings[0].Anomaly || !readings[1].Anomaly || readings[2].Anomaly {
+		t.Fatalf("expected only the spike of the analyzed sensor to be flagged, got %v %v %v", readings[0].Anomaly, readings[1].Anomaly, readings[2].Anomaly)
+	}
+
+	service.OnSensorData(context.Background(), readings)
+	service.OnSensorData(context.Background(), readings)
+
+	if listener.anomalies[analyzed] != 2 || listener.anomalies[ignored] != 0 {
+		t.Errorf("expected the anomaly to be published on each add, got %v", listener.anomalies)
+	}
+
+	// The second add is within the cooldown, the owner is emailed once
+	select {
+	case to := <-emails:
+		if to != "owner@example.com" {
+			t.Errorf("expected an email to the owner, got %s", to)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the owner to be emailed")
+	}
+	select {
+	case <-emails:
+		t.Error("expected a single email within the cooldown")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestValidateSettingsDefaults(t *testing.T) {
+	settings := &domain.AnomalySettings{Method: "MAD"}
+	if err := validateSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	if settings.Method != domain.ANOMALY_METHOD_MAD || settings.Window != domain.DefaultWindow || settings.Threshold != 3.5 {
+		t.Errorf("unexpected defaults %+v", settings)
+	}
+
+	if err := validateSettings(&domain.AnomalySettings{Window: domain.MinWindow - 1}); err == nil {
+		t.Error("expected a window below the minimum to be rejected")
+	}
+	if err := validateSettings(&domain.AnomalySettings{Method: "iqr"}); err == nil {
+		t.Error("expected an unknown method to be rejected")
+	}
+}
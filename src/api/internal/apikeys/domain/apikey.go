@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+const (
+	// Prefix of every generated key, to recognize them in configuration files and logs
+	KeyPrefix = "sk_"
+	// Number of characters of the key kept in clear to tell keys apart
+	DisplayedPrefixLength = 11
+)
+
+var (
+	// Returned when the key doesn't exist or belongs to a sensor of another user
+	ErrKeyNotFound = errors.New("api key not found")
+	// Returned when a revoked key is revoked again
+	ErrKeyRevoked = errors.New("api key is already revoked")
+	// Returned when the key is unknown or revoked
+	ErrInvalidKey = errors.New("invalid or revoked api key")
+	// Returned when the sensor doesn't exist
+	ErrSensorNotFound = errors.New("sensor not found")
+	// Returned when the user manages the keys of a sensor they don't own
+	ErrNotSensorOwner = errors.New("user is not the owner of the sensor")
+)
+
+// APIKey lets a device write the data of one sensor. Only a hash of the key is stored.
+type APIKey struct {
+	// Unique identifier for the key
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the sensor the key can write data for
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Name given to the key, e.g. the device using it
+	Name string `json:"name"`
+	// First characters of the key
+	Prefix string `json:"prefix"`
+	// SHA-256 of the key
+	Hash []byte `json:"-"`
+	// When the key was created
+	CreatedAt time.Time `json:"createdAt"`
+	// When the key was last used, nil if it never was
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	// When the key was revoked, nil while it's valid
+	RevokedAt *time.Time `json:"revokedAt"`
+}
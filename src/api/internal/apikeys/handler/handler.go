@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"api/internal/apikeys/domain"
+	apikey_service "api/internal/apikeys/usecase"
+	user_service "api/internal/users/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for handling HTTP requests related to device API keys
+type APIKeyHandler interface {
+	// Handles the HTTP request to create an API key for a sensor
+	CreateKey(c *gin.Context)
+	// Handles the HTTP request to revoke an API key
+	RevokeKey(c *gin.Context)
+	// Handles the HTTP request to list the API keys of the user's sensors
+	ListKeys(c *gin.Context)
+}
+
+// Structure request to create an API key
+type APIKeyCreateRequest struct {
+	// Sensor UUID the key can write data for
+	SensorUuid uuid.UUID `json:"sensorUuid" binding:"required"`
+	// Name of the key, e.g. the device using it
+	Name string `json:"name" binding:"required"`
+}
+
+// Structure request identifying an API key
+type APIKeyUuidRequest struct {
+	// Key UUID
+	ID uuid.UUID `json:"uuid" binding:"required"`
+}
+
+// Structure request to list API keys
+type APIKeyListRequest struct {
+	// Sensor UUID, omitted for the keys of every sensor of the user
+	SensorUuid *uuid.UUID `json:"sensorUuid"`
+}
+
+// Process HTTP requests and interaction with APIKeyService/UserService for API key operations
+type APIKeyHandlerImpl struct {
+	APIKeyService apikey_service.APIKeyService
+	UserService   user_service.UserService
+}
+
+func NewAPIKeyHandler(apiKeyService apikey_service.APIKeyService, userService user_service.UserService) APIKeyHandler {
+	return &APIKeyHandlerImpl{
+		APIKeyService: apiKeyService,
+		UserService:   userService,
+	}
+}
+
+// Gets the uuid of the user authenticated by the request's token
+func (h *APIKeyHandlerImpl) getUserUuid(c *gin.Context) (uuid.UUID, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	return h.UserService.GetUserByToken(c.Request.Context(), str)
+}
+
+// Maps errors from the API key service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrKeyNotFound), errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrKeyRevoked):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (h *APIKeyHandlerImpl) CreateKey(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req APIKeyCreateRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, secret, err := h.APIKeyService.CreateKey(c.Request.Context(), req.SensorUuid, req.Name, userUuid)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// The key is only returned here, it can't be retrieved afterwards
+	c.JSON(http.StatusCreated, gin.H{"apiKey": key, "key": secret})
+}
+
+func (h *APIKeyHandlerImpl) RevokeKey(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req APIKeyUuidRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.APIKeyService.RevokeKey(c.Request.Context(), req.ID, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *APIKeyHandlerImpl) ListKeys(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	// The body is optional, without it the keys of every sensor are listed
+	var req APIKeyListRequest
+	if c.Request.ContentLength != 0 {
+		if err = c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	keys, err := h.APIKeyService.ListKeys(c.Request.Context(), userUuid, req.SensorUuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+	c.JSON(http.StatusOK, gin.H{"apiKeys": keys})
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	config "api/configs"
+	"api/internal/apikeys/domain"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for API key data operations
+type APIKeyRepository interface {
+	// Stores a new key
+	CreateKey(ctx context.Context, key *domain.APIKey) error
+	// Retrieves a key, revoked or not
+	GetKey(ctx context.Context, keyUuid uuid.UUID) (*domain.APIKey, error)
+	// Retrieves the key that isn't revoked with the given hash
+	GetValidKeyByHash(ctx context.Context, hash []byte) (*domain.APIKey, error)
+	// Retrieves the keys of the sensors owned by the user, only those of one sensor when sensorUuid is set
+	ListKeys(ctx context.Context, userUuid uuid.UUID, sensorUuid *uuid.UUID) ([]domain.APIKey, error)
+	// Marks a key as revoked
+	RevokeKey(ctx context.Context, keyUuid uuid.UUID, revokedAt time.Time) error
+	// Records that a key was used, at most once a minute
+	TouchKey(ctx context.Context, keyUuid uuid.UUID, usedAt time.Time) error
+	// Retrieves the uuid of the user who owns the sensor
+	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error)
+}
+
+// Performs API key data operations using database/sql to interact with the database
+type APIKeyRepositoryImpl struct {
+	DB *sql.DB
+}
+
+func NewAPIKeyRepository() (APIKeyRepository, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	return &APIKeyRepositoryImpl{DB: db}, nil
+}
+
+// Columns selected for keys, in the order read by scanKey
+const keyColumns = `device_api_keys.uuid, device_api_keys.sensorUuid, device_api_keys.name, device_api_keys.prefix,
+	device_api_keys.keyHash, device_api_keys.createdAt, device_api_keys.lastUsedAt, device_api_keys.revokedAt`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (*domain.APIKey, error) {
+	var key domain.APIKey
+	var lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&key.ID, &key.SensorUuid, &key.Name, &key.Prefix, &key.Hash, &key.CreatedAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+func (r *APIKeyRepositoryImpl) CreateKey(ctx context.Context, key *domain.APIKey) error {
+	query := `
+		INSERT INTO device_api_keys (uuid, sensorUuid, name, prefix, keyHash, createdAt)
+		VALUES (@uuid, @sensorUuid, @name, @prefix, @keyHash, @createdAt)
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", key.ID),
+		sql.Named("sensorUuid", key.SensorUuid),
+		sql.Named("name", key.Name),
+		sql.Named("prefix", key.Prefix),
+		sql.Named("keyHash", key.Hash),
+		sql.Named("createdAt", key.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %v", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepositoryImpl) GetKey(ctx context.Context, keyUuid uuid.UUID) (*domain.APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM device_api_keys WHERE uuid = @uuid`
+
+	key, err := scanKey(r.DB.QueryRowContext(ctx, query, sql.Named("uuid", keyUuid)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve api key: %v", err)
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepositoryImpl) GetValidKeyByHash(ctx context.Context, hash []byte) (*domain.APIKey, error) {
+	query := `SELECT ` + keyColumns + ` FROM device_api_keys WHERE keyHash = @keyHash AND revokedAt IS NULL`
+
+	key, err := scanKey(r.DB.QueryRowContext(ctx, query, sql.Named("keyHash", hash)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrInvalidKey
+		}
+		return nil, fmt.Errorf("failed to retrieve api key: %v", err)
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepositoryImpl) ListKeys(ctx context.Context, userUuid uuid.UUID, sensorUuid *uuid.UUID) ([]domain.APIKey, error) {
+	query := `
+		SELECT ` + keyColumns + `
+		FROM device_api_keys
+		INNER JOIN Sensors ON Sensors.uuid = device_api_keys.sensorUuid
+		WHERE Sensors.sensorOwnerUuid = @userUuid
+		AND (@sensorUuid IS NULL OR device_api_keys.sensorUuid = @sensorUuid)
+		ORDER BY device_api_keys.createdAt
+	`
+
+	var sensor uuid.NullUUID
+	if sensorUuid != nil {
+		sensor = uuid.NullUUID{UUID: *sensorUuid, Valid: true}
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("userUuid", userUuid), sql.Named("sensorUuid", sensor))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %v", err)
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %v", err)
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating api keys: %v", err)
+	}
+
+	return keys, nil
+}
+
+func (r *APIKeyRepositoryImpl) RevokeKey(ctx context.Context, keyUuid uuid.UUID, revokedAt time.Time) error {
+	query := `UPDATE device_api_keys SET revokedAt = @revokedAt WHERE uuid = @uuid AND revokedAt IS NULL`
+
+	result, err := r.DB.ExecContext(ctx, query, sql.Named("uuid", keyUuid), sql.Named("revokedAt", revokedAt))
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %v", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrKeyRevoked
+	}
+	return nil
+}
+
+func (r *APIKeyRepositoryImpl) TouchKey(ctx context.Context, keyUuid uuid.UUID, usedAt time.Time) error {
+	// Devices sending readings every few seconds don't cause a write per request
+	query := `
+		UPDATE device_api_keys
+		SET lastUsedAt = @usedAt
+		WHERE uuid = @uuid
+		AND (lastUsedAt IS NULL OR lastUsedAt < DATEADD(MINUTE, -1, @usedAt))
+	`
+
+	_, err := r.DB.ExecContext(ctx, query, sql.Named("uuid", keyUuid), sql.Named("usedAt", usedAt))
+	if err != nil {
+		return fmt.Errorf("failed to update api key usage: %v", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT sensorOwnerUuid FROM Sensors WHERE uuid = @sensorUuid`
+
+	var ownerUuid uuid.UUID
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&ownerUuid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.NilUUID, domain.ErrSensorNotFound
+		}
+		return uuid.NilUUID, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return ownerUuid, nil
+}
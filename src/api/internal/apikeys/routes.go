@@ -0,0 +1,52 @@
+package apikeys
+
+import (
+	"api/internal/apikeys/handler"
+	apikey_repository "api/internal/apikeys/repository"
+	apikey_service "api/internal/apikeys/usecase"
+	auth_repository "api/internal/auth/repository"
+	auth_service "api/internal/auth/usecase"
+	user_repository "api/internal/users/repository"
+	user_service "api/internal/users/usecase"
+	middleware "api/utils"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterAPIKeyRoutes declares the routes that can be accessed for device API key management
+func RegisterAPIKeyRoutes(router *gin.Engine) {
+
+	apiKeyRepo, err := apikey_repository.NewAPIKeyRepository()
+	if err != nil {
+		log.Fatalf("Failed to create api key repository: %v", err)
+	}
+
+	usersRepos, err := user_repository.NewUserRepository()
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	authRepo, err := auth_repository.NewAuthRepository()
+	if err != nil {
+		log.Fatalf("Failed to create auth repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	apiKeyService := apikey_service.NewAPIKeyService(apiKeyRepo)
+	authService := auth_service.NewAuthService(authRepo, usersRepos)
+
+	h := handler.NewAPIKeyHandler(apiKeyService, userService)
+
+	// API key routes
+	api := router.Group("/v1/apikeys/")
+	api.Use(middleware.AuthMiddleware(authService))
+	{
+		// Create API key for a sensor
+		api.POST("create", h.CreateKey)
+		// Revoke API key
+		api.POST("revoke", h.RevokeKey)
+		// List API keys
+		api.POST("list", h.ListKeys)
+	}
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"api/internal/apikeys/domain"
+	"api/internal/apikeys/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Maximum length of the name of a key
+const MaxNameLength = 100
+
+// Interface for API key services
+type APIKeyService interface {
+	// Creates a key for a sensor of the user, returning it with the key itself, which can't be retrieved afterwards
+	CreateKey(ctx context.Context, sensorUuid uuid.UUID, name string, userUuid uuid.UUID) (*domain.APIKey, string, error)
+	// Revokes a key of a sensor of the user, the key is rejected from then on
+	RevokeKey(ctx context.Context, keyUuid uuid.UUID, userUuid uuid.UUID) error
+	// Lists the keys of the user's sensors, only those of one sensor when sensorUuid is set
+	ListKeys(ctx context.Context, userUuid uuid.UUID, sensorUuid *uuid.UUID) ([]domain.APIKey, error)
+	// Returns the sensor the key can write data for, failing if it's unknown or revoked
+	AuthenticateKey(ctx context.Context, key string) (uuid.UUID, error)
+}
+
+// Handles API key logic and interaction with the repository
+type APIKeyServiceImpl struct {
+	Repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &APIKeyServiceImpl{Repo: repo}
+}
+
+// Generates a random key with the recognizable prefix
+func generateKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return domain.KeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// Hash the key is stored and looked up by. Keys are random, so a fast hash is as safe as a slow one.
+func hashKey(key string) []byte {
+	hash := sha256.Sum256([]byte(key))
+	return hash[:]
+}
+
+// Checks that the sensor exists and belongs to the user
+func (s *APIKeyServiceImpl) checkOwner(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) error {
+	ownerUuid, err := s.Repo.GetSensorOwner(ctx, sensorUuid)
+	if err != nil {
+		return err
+	}
+	if ownerUuid != userUuid {
+		return domain.ErrNotSensorOwner
+	}
+	return nil
+}
+
+func (s *APIKeyServiceImpl) CreateKey(ctx context.Context, sensorUuid uuid.UUID, name string, userUuid uuid.UUID) (*domain.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > MaxNameLength {
+		return nil, "", fmt.Errorf("name is required and must be at most %d characters", MaxNameLength)
+	}
+
+	if err := s.checkOwner(ctx, sensorUuid, userUuid); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := generateKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %v", err)
+	}
+
+	key := &domain.APIKey{
+		ID:         uuid.NewV4(),
+		SensorUuid: sensorUuid,
+		Name:       name,
+		Prefix:     secret[:domain.DisplayedPrefixLength],
+		Hash:       hashKey(secret),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err = s.Repo.CreateKey(ctx, key); err != nil {
+		return nil, "", errors.New("failed to create api key")
+	}
+
+	return key, secret, nil
+}
+
+func (s *APIKeyServiceImpl) RevokeKey(ctx context.Context, keyUuid uuid.UUID, userUuid uuid.UUID) error {
+	key, err := s.Repo.GetKey(ctx, keyUuid)
+	if err != nil {
+		return err
+	}
+
+	// Keys of other users' sensors are reported as not found
+	if err = s.checkOwner(ctx, key.SensorUuid, userUuid); err != nil {
+		if errors.Is(err, domain.ErrNotSensorOwner) {
+			return domain.ErrKeyNotFound
+		}
+		return err
+	}
+
+	if key.RevokedAt != nil {
+		return domain.ErrKeyRevoked
+	}
+	return s.Repo.RevokeKey(ctx, keyUuid, time.Now().UTC())
+}
+
+func (s *APIKeyServiceImpl) ListKeys(ctx context.Context, userUuid uuid.UUID, sensorUuid *uuid.UUID) ([]domain.APIKey, error) {
+	keys, err := s.Repo.ListKeys(ctx, userUuid, sensorUuid)
+	if err != nil {
+		return nil, errors.New("failed to retrieve api keys")
+	}
+	return keys, nil
+}
+
+func (s *APIKeyServiceImpl) AuthenticateKey(ctx context.Context, key string) (uuid.UUID, error) {
+	if !strings.HasPrefix(key, domain.KeyPrefix) {
+		return uuid.NilUUID, domain.ErrInvalidKey
+	}
+
+	apiKey, err := s.Repo.GetValidKeyByHash(ctx, hashKey(key))
+	if err != nil {
+		return uuid.NilUUID, err
+	}
+
+	// The request goes on even if the usage can't be recorded
+	if err = s.Repo.TouchKey(ctx, apiKey.ID, time.Now().UTC()); err != nil {
+		log.Printf("Failed to record usage of api key %s: %v", apiKey.ID.String(), err)
+	}
+
+	return apiKey.SensorUuid, nil
+}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"api/internal/apikeys/domain"
+	"api/internal/apikeys/repository"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Repository keeping the keys in memory
+type fakeRepository struct {
+	repository.APIKeyRepository
+	owners map[uuid.UUID]uuid.UUID
+	keys   map[uuid.UUID]*domain.APIKey
+	used   int
+}
+
+func (r *fakeRepository) CreateKey(ctx context.Context, key *domain.APIKey) error {
+	r.keys[key.ID] = key
+	return nil
+}
+
+func (r *fakeRepository) GetKey(ctx context.Context, keyUuid uuid.UUID) (*domain.APIKey, error) {
+	if key, ok := r.keys[keyUuid]; ok {
+		return key, nil
+	}
+	return nil, domain.ErrKeyNotFound
+}
+
+func (r *fakeRepository) GetValidKeyByHash(ctx context.Context, hash []byte) (*domain.APIKey, error) {
+	for _, key := range r.keys {
+		if bytes.Equal(key.Hash, hash) && key.RevokedAt == nil {
+			return key, nil
+		}
+	}
+	return nil, domain.ErrInvalidKey
+}
+
+func (r *fakeRepository) RevokeKey(ctx context.Context, keyUuid uuid.UUID, revokedAt time.Time) error {
+	r.keys[keyUuid].RevokedAt = &revokedAt
+	return nil
+}
+
+func (r *fakeRepository) TouchKey(ctx context.Context, keyUuid uuid.UUID, usedAt time.Time) error {
+	r.used++
+	return nil
+}
+
+func (r *fakeRepository) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	if owner, ok := r.owners[sensorUuid]; ok {
+		return owner, nil
+	}
+	return uuid.NilUUID, domain.ErrSensorNotFound
+}
+
+func TestCreateAndAuthenticateKey(t *testing.T) {
+	owner, other, sensor := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{owners: map[uuid.UUID]uuid.UUID{sensor: owner}, keys: map[uuid.UUID]*domain.APIKey{}}
+	service := NewAPIKeyService(repo)
+	ctx := context.Background()
+
+	key, secret, err := service.CreateKey(ctx, sensor, " gateway ", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, domain.KeyPrefix) || key.Prefix != secret[:domain.DisplayedPrefixLength] || key.Name != "gateway" {
+		t.Errorf("unexpected key %+v for %s", key, secret)
+	}
+	// Only the hash of the key is stored
+	if bytes.Contains(key.Hash, []byte(secret)) || len(key.Hash) != 32 {
+		t.Errorf("expected the key to be stored hashed")
+	}
+
+	authenticated, err := service.AuthenticateKey(ctx, secret)
+	if err != nil || authenticated != sensor {
+		t.Fatalf("expected the key to authenticate its sensor, got %v %v", authenticated, err)
+	}
+	if repo.used != 1 {
+		t.Errorf("expected the usage to be recorded")
+	}
+
+	if _, err = service.AuthenticateKey(ctx, "not a key"); !errors.Is(err, domain.ErrInvalidKey) {
+		t.Errorf("expected a malformed key to be rejected, got %v", err)
+	}
+	if _, _, err = service.CreateKey(ctx, sensor, "gateway", other); !errors.Is(err, domain.ErrNotSensorOwner) {
+		t.Errorf("expected only the owner to create keys, got %v", err)
+	}
+	if _, _, err = service.CreateKey(ctx, sensor, "", owner); err == nil {
+		t.Error("expected a key without name to be rejected")
+	}
+}
+
+func TestRevokeKey(t *testing.T) {
+	owner, other, sensor := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{owners: map[uuid.UUID]uuid.UUID{sensor: owner}, keys: map[uuid.UUID]*domain.APIKey{}}
+	service := NewAPIKeyService(repo)
+	ctx := context.Background()
+
+	key, secret, err := service.CreateKey(ctx, sensor, "gateway", owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Keys of other users' sensors are hidden
+	if err = service.RevokeKey(ctx, key.ID, other); !errors.Is(err, domain.ErrKeyNotFound) {
+		t.Errorf("expected the key to be hidden from other users, got %v", err)
+	}
+
+	if err = service.RevokeKey(ctx, key.ID, owner); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.AuthenticateKey(ctx, secret); !errors.Is(err, domain.ErrInvalidKey) {
+		t.Errorf("expected a revoked key to be rejected, got %v", err)
+	}
+	if err = service.RevokeKey(ctx, key.ID, owner); !errors.Is(err, domain.ErrKeyRevoked) {
+		t.Errorf("expected revoking twice to fail, got %v", err)
+	}
+}
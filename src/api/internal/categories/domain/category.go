@@ -0,0 +1,30 @@
+package domain
+
+import "errors"
+
+var (
+	// Returned when the category doesn't exist
+	ErrCategoryNotFound = errors.New("category not found")
+	// Returned when creating or renaming a category to a name already taken
+	ErrCategoryExists = errors.New("a category with this name already exists")
+	// Returned when deleting a category that sensors still belong to
+	ErrCategoryInUse = errors.New("category is used by sensors")
+	// Returned when a user who isn't an admin manages categories
+	ErrNotAllowed = errors.New("only admins can manage categories")
+)
+
+// Category describes a type of measurement, the unit its readings are recorded in and their physical range
+type Category struct {
+	// Unique identifier for the category, referenced by the sensors
+	ID int `json:"id"`
+	// Name of the category
+	Name string `json:"name"`
+	// Unit of the recorded values
+	Unit string `json:"unit"`
+	// Lowest physically possible value, nil when unbounded
+	MinValue *float64 `json:"minValue"`
+	// Highest physically possible value, nil when unbounded
+	MaxValue *float64 `json:"maxValue"`
+	// Decimal places the values are displayed with
+	Precision int `json:"precision"`
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"api/internal/categories/domain"
+	category_service "api/internal/categories/usecase"
+	user_service "api/internal/users/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for handling HTTP requests related to sensor categories
+type CategoryHandler interface {
+	// Handles the HTTP request to create a category
+	CreateCategory(c *gin.Context)
+	// Handles the HTTP request to edit a category
+	EditCategory(c *gin.Context)
+	// Handles the HTTP request to delete a category
+	DeleteCategory(c *gin.Context)
+	// Handles the HTTP request to list the categories
+	ListCategories(c *gin.Context)
+}
+
+// Structure request to create or edit a category
+type CategoryRequest struct {
+	// Category id, only used when editing
+	ID int `json:"id"`
+	// Name of the category
+	Name string `json:"name" binding:"required"`
+	// Unit of the recorded values
+	Unit string `json:"unit"`
+	// Lowest physically possible value, omitted when unbounded
+	MinValue *float64 `json:"minValue"`
+	// Highest physically possible value, omitted when unbounded
+	MaxValue *float64 `json:"maxValue"`
+	// Decimal places the values are displayed with
+	Precision int `json:"precision"`
+}
+
+// Structure request identifying a category
+type CategoryIdRequest struct {
+	// Category id, a pointer so that the category 0 isn't taken as missing
+	ID *int `json:"id" binding:"required"`
+}
+
+// Process HTTP requests and interaction with CategoryService/UserService for category operations
+type CategoryHandlerImpl struct {
+	CategoryService category_service.CategoryService
+	UserService     user_service.UserService
+}
+
+func NewCategoryHandler(categoryService category_service.CategoryService, userService user_service.UserService) CategoryHandler {
+	return &CategoryHandlerImpl{
+		CategoryService: categoryService,
+		UserService:     userService,
+	}
+}
+
+// Gets whether the user authenticated by the request's token is an admin
+func (h *CategoryHandlerImpl) isAdmin(c *gin.Context) (bool, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	var role bool
+	var userUuid uuid.UUID
+	err := h.UserService.GetRoutesAuthorization(c.Request.Context(), str, &role, &userUuid)
+	return role, err
+}
+
+// Maps errors from the category service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrCategoryExists), errors.Is(err, domain.ErrCategoryInUse):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (req *CategoryRequest) toCategory() *domain.Category {
+	return &domain.Category{
+		ID:        req.ID,
+		Name:      req.Name,
+		Unit:      req.Unit,
+		MinValue:  req.MinValue,
+		MaxValue:  req.MaxValue,
+		Precision: req.Precision,
+	}
+}
+
+func (h *CategoryHandlerImpl) CreateCategory(c *gin.Context) {
+	isAdmin, err := h.isAdmin(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req CategoryRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	category := req.toCategory()
+	if err = h.CategoryService.CreateCategory(c.Request.Context(), category, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *CategoryHandlerImpl) EditCategory(c *gin.Context) {
+	isAdmin, err := h.isAdmin(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req CategoryRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	category := req.toCategory()
+	if err = h.CategoryService.EditCategory(c.Request.Context(), category, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+func (h *CategoryHandlerImpl) DeleteCategory(c *gin.Context) {
+	isAdmin, err := h.isAdmin(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req CategoryIdRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.CategoryService.DeleteCategory(c.Request.Context(), *req.ID, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *CategoryHandlerImpl) ListCategories(c *gin.Context) {
+	categories, err := h.CategoryService.ListCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if categories == nil {
+		categories = []domain.Category{}
+	}
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
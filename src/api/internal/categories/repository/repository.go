@@ -0,0 +1,189 @@
+package repository
+
+import (
+	config "api/configs"
+	"api/internal/categories/domain"
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Interface for sensor categories data operations
+type CategoryRepository interface {
+	// Creates a category, failing if the name is already taken, and sets its generated id
+	CreateCategory(ctx context.Context, category *domain.Category) error
+	// Updates a category, failing if another category has the same name
+	EditCategory(ctx context.Context, category *domain.Category) error
+	// Deletes a category with its retention policy, failing if sensors belong to it
+	DeleteCategory(ctx context.Context, id int) error
+	// Retrieves a category
+	GetCategory(ctx context.Context, id int) (*domain.Category, error)
+	// Retrieves every category ordered by name
+	ListCategories(ctx context.Context) ([]domain.Category, error)
+}
+
+// Performs categories data operations using database/sql to interact with the database
+type CategoryRepositoryImpl struct {
+	DB *sql.DB
+}
+
+func NewCategoryRepository() (CategoryRepository, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	return &CategoryRepositoryImpl{DB: db}, nil
+}
+
+// Columns selected for categories, in the order read by scanCategory
+const categoryColumns = `id, name, unit, minValue, maxValue, decimalPrecision`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCategory(row scanner) (*domain.Category, error) {
+	var category domain.Category
+	var minValue, maxValue sql.NullFloat64
+	if err := row.Scan(&category.ID, &category.Name, &category.Unit, &minValue, &maxValue, &category.Precision); err != nil {
+		return nil, err
+	}
+	if minValue.Valid {
+		category.MinValue = &minValue.Float64
+	}
+	if maxValue.Valid {
+		category.MaxValue = &maxValue.Float64
+	}
+	return &category, nil
+}
+
+func nullFloat(value *float64) sql.NullFloat64 {
+	if value == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *value, Valid: true}
+}
+
+func (r *CategoryRepositoryImpl) CreateCategory(ctx context.Context, category *domain.Category) error {
+	// Nothing is inserted, and no id returned, when the name is taken
+	query := `
+		INSERT INTO sensor_categories (name, unit, minValue, maxValue, decimalPrecision)
+		OUTPUT inserted.id
+		SELECT @name, @unit, @minValue, @maxValue, @precision
+		WHERE NOT EXISTS (SELECT 1 FROM sensor_categories WHERE name = @name)
+	`
+
+	err := r.DB.QueryRowContext(ctx, query,
+		sql.Named("name", category.Name),
+		sql.Named("unit", category.Unit),
+		sql.Named("minValue", nullFloat(category.MinValue)),
+		sql.Named("maxValue", nullFloat(category.MaxValue)),
+		sql.Named("precision", category.Precision),
+	).Scan(&category.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.ErrCategoryExists
+		}
+		return fmt.Errorf("failed to create category: %v", err)
+	}
+	return nil
+}
+
+func (r *CategoryRepositoryImpl) EditCategory(ctx context.Context, category *domain.Category) error {
+	query := `
+		UPDATE sensor_categories
+		SET name = @name, unit = @unit, minValue = @minValue, maxValue = @maxValue, decimalPrecision = @precision
+		WHERE id = @id AND NOT EXISTS (SELECT 1 FROM sensor_categories WHERE name = @name AND id <> @id)
+	`
+
+	result, err := r.DB.ExecContext(ctx, query,
+		sql.Named("id", category.ID),
+		sql.Named("name", category.Name),
+		sql.Named("unit", category.Unit),
+		sql.Named("minValue", nullFloat(category.MinValue)),
+		sql.Named("maxValue", nullFloat(category.MaxValue)),
+		sql.Named("precision", category.Precision),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to edit category: %v", err)
+	}
+
+	// The service checks the category exists beforehand, so no update means the name is taken
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrCategoryExists
+	}
+	return nil
+}
+
+func (r *CategoryRepositoryImpl) DeleteCategory(ctx context.Context, id int) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	var sensors int
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM Sensors WHERE category = @id`, sql.Named("id", id)).Scan(&sensors)
+	if err != nil {
+		return fmt.Errorf("failed to count sensors of category: %v", err)
+	}
+	if sensors > 0 {
+		return domain.ErrCategoryInUse
+	}
+
+	if _, err = tx.ExecContext(ctx, `DELETE FROM retention_policies WHERE sensorUuid IS NULL AND category = @id`, sql.Named("id", id)); err != nil {
+		return fmt.Errorf("failed to delete category retention policy: %v", err)
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM sensor_categories WHERE id = @id`, sql.Named("id", id))
+	if err != nil {
+		return fmt.Errorf("failed to delete category: %v", err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrCategoryNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (r *CategoryRepositoryImpl) GetCategory(ctx context.Context, id int) (*domain.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM sensor_categories WHERE id = @id`
+
+	category, err := scanCategory(r.DB.QueryRowContext(ctx, query, sql.Named("id", id)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve category: %v", err)
+	}
+	return category, nil
+}
+
+func (r *CategoryRepositoryImpl) ListCategories(ctx context.Context) ([]domain.Category, error) {
+	query := `SELECT ` + categoryColumns + ` FROM sensor_categories ORDER BY name`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %v", err)
+	}
+	defer rows.Close()
+
+	var categories []domain.Category
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan category: %v", err)
+		}
+		categories = append(categories, *category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %v", err)
+	}
+
+	return categories, nil
+}
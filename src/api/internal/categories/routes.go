@@ -0,0 +1,54 @@
+package categories
+
+import (
+	auth_repository "api/internal/auth/repository"
+	auth_service "api/internal/auth/usecase"
+	"api/internal/categories/handler"
+	category_repository "api/internal/categories/repository"
+	category_service "api/internal/categories/usecase"
+	user_repository "api/internal/users/repository"
+	user_service "api/internal/users/usecase"
+	middleware "api/utils"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterCategoryRoutes declares the routes that can be accessed for sensor categories management
+func RegisterCategoryRoutes(router *gin.Engine) {
+
+	categoryRepo, err := category_repository.NewCategoryRepository()
+	if err != nil {
+		log.Fatalf("Failed to create category repository: %v", err)
+	}
+
+	usersRepos, err := user_repository.NewUserRepository()
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	authRepo, err := auth_repository.NewAuthRepository()
+	if err != nil {
+		log.Fatalf("Failed to create auth repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	categoryService := category_service.NewCategoryService(categoryRepo)
+	authService := auth_service.NewAuthService(authRepo, usersRepos)
+
+	h := handler.NewCategoryHandler(categoryService, userService)
+
+	// Category routes
+	api := router.Group("/v1/categories/")
+	api.Use(middleware.AuthMiddleware(authService))
+	{
+		// Create category (admin only)
+		api.POST("create", h.CreateCategory)
+		// Edit category (admin only)
+		api.POST("edit", h.EditCategory)
+		// Delete category no sensor belongs to (admin only)
+		api.POST("delete", h.DeleteCategory)
+		// List categories
+		api.POST("list", h.ListCategories)
+	}
+}
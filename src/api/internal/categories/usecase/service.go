@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"api/internal/categories/domain"
+	"api/internal/categories/repository"
+	"context"
+	"errors"
+	"math"
+	"strings"
+)
+
+// Highest number of decimal places a category can be displayed with
+const MaxPrecision = 6
+
+// Interface for categories' services
+type CategoryService interface {
+	// Creates a category (admin only)
+	CreateCategory(ctx context.Context, category *domain.Category, isAdmin bool) error
+	// Updates a category (admin only)
+	EditCategory(ctx context.Context, category *domain.Category, isAdmin bool) error
+	// Deletes a category no sensor belongs to (admin only)
+	DeleteCategory(ctx context.Context, id int, isAdmin bool) error
+	// Lists every category
+	ListCategories(ctx context.Context) ([]domain.Category, error)
+}
+
+// Handles categories' logic and interaction with the repository
+type CategoryServiceImpl struct {
+	Repo repository.CategoryRepository
+}
+
+func NewCategoryService(repo repository.CategoryRepository) CategoryService {
+	return &CategoryServiceImpl{Repo: repo}
+}
+
+// Checks the fields of the category, trimming its name and unit
+func validateCategory(category *domain.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	category.Unit = strings.TrimSpace(category.Unit)
+
+	if category.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(category.Name) > 100 {
+		return errors.New("name must be at most 100 characters")
+	}
+	if len(category.Unit) > 20 {
+		return errors.New("unit must be at most 20 characters")
+	}
+	for _, bound := range []*float64{category.MinValue, category.MaxValue} {
+		if bound != nil && (math.IsNaN(*bound) || math.IsInf(*bound, 0)) {
+			return errors.New("minValue and maxValue must be finite numbers")
+		}
+	}
+	if category.MinValue != nil && category.MaxValue != nil && *category.MinValue > *category.MaxValue {
+		return errors.New("minValue must not be greater than maxValue")
+	}
+	if category.Precision < 0 || category.Precision > MaxPrecision {
+		return errors.New("precision must be between 0 and 6")
+	}
+	return nil
+}
+
+func (s *CategoryServiceImpl) CreateCategory(ctx context.Context, category *domain.Category, isAdmin bool) error {
+	if !isAdmin {
+		return domain.ErrNotAllowed
+	}
+	if err := validateCategory(category); err != nil {
+		return err
+	}
+	return s.Repo.CreateCategory(ctx, category)
+}
+
+func (s *CategoryServiceImpl) EditCategory(ctx context.Context, category *domain.Category, isAdmin bool) error {
+	if !isAdmin {
+		return domain.ErrNotAllowed
+	}
+	if err := validateCategory(category); err != nil {
+		return err
+	}
+	if _, err := s.Repo.GetCategory(ctx, category.ID); err != nil {
+		return err
+	}
+	return s.Repo.EditCategory(ctx, category)
+}
+
+func (s *CategoryServiceImpl) DeleteCategory(ctx context.Context, id int, isAdmin bool) error {
+	if !isAdmin {
+		return domain.ErrNotAllowed
+	}
+	return s.Repo.DeleteCategory(ctx, id)
+}
+
+func (s *CategoryServiceImpl) ListCategories(ctx context.Context) ([]domain.Category, error) {
+	categories, err := s.Repo.ListCategories(ctx)
+	if err != nil {
+		return nil, errors.New("failed to retrieve categories")
+	}
+	return categories, nil
+}
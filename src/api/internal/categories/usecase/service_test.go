@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"api/internal/categories/domain"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateCategory(t *testing.T) {
+	zero, hundred := 0.0, 100.0
+
+	tests := []struct {
+		name     string
+		category domain.Category
+		valid    bool
+	}{
+		{"bounded range", domain.Category{Name: "Humidity", Unit: "%", MinValue: &zero, MaxValue: &hundred, Precision: 1}, true},
+		{"unbounded range", domain.Category{Name: "Power", Unit: "W"}, true},
+		{"single bound", domain.Category{Name: "Flow", Unit: "L/min", MinValue: &zero}, true},
+		{"empty name", domain.Category{Name: "  ", Unit: "ppm"}, false},
+		{"inverted range", domain.Category{Name: "CO2", Unit: "ppm", MinValue: &hundred, MaxValue: &zero}, false},
+		{"negative precision", domain.Category{Name: "Light", Unit: "lx", Precision: -1}, false},
+		{"excessive precision", domain.Category{Name: "Light", Unit: "lx", Precision: MaxPrecision + 1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateCategory(&test.category)
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestManagingCategoriesRequiresAdmin(t *testing.T) {
+	service := &CategoryServiceImpl{}
+	ctx := context.Background()
+
+	if err := service.CreateCategory(ctx, &domain.Category{Name: "CO2", Unit: "ppm"}, false); !errors.Is(err, domain.ErrNotAllowed) {
+		t.Errorf("expected %v on create, got %v", domain.ErrNotAllowed, err)
+	}
+	if err := service.EditCategory(ctx, &domain.Category{ID: 3, Name: "CO2", Unit: "ppm"}, false); !errors.Is(err, domain.ErrNotAllowed) {
+		t.Errorf("expected %v on edit, got %v", domain.ErrNotAllowed, err)
+	}
+	if err := service.DeleteCategory(ctx, 3, false); !errors.Is(err, domain.ErrNotAllowed) {
+		t.Errorf("expected %v on delete, got %v", domain.ErrNotAllowed, err)
+	}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Size of the buckets raw readings are compacted into
+const RollupBucket = time.Hour
+
+var (
+	// Returned when the policy doesn't exist or the user can't see it
+	ErrPolicyNotFound = errors.New("retention policy not found")
+	// Returned when the sensor of a policy doesn't exist
+	ErrSensorNotFound = errors.New("sensor not found")
+	// Returned when the user manages a policy they aren't allowed to
+	ErrNotAllowed = errors.New("only the sensor owner or an admin can manage this retention policy")
+)
+
+// RetentionPolicy defines how long the readings of a sensor, or of every sensor of a category, are kept
+type RetentionPolicy struct {
+	// Unique identifier for the policy
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the sensor the policy applies to, nil for a category policy
+	SensorUuid *uuid.UUID `json:"sensorUuid"`
+	// Category the policy applies to, nil for a sensor policy
+	Category *int `json:"category"`
+	// Days raw readings are kept before being compacted into hourly rollups
+	RawDays int `json:"rawDays"`
+	// Days hourly rollups are kept, 0 to keep them forever
+	RollupDays int `json:"rollupDays"`
+}
+
+// SensorRetention is the policy in effect for a sensor, its own or its category's
+type SensorRetention struct {
+	// UUID of the sensor
+	SensorUuid uuid.UUID
+	// Days raw readings are kept
+	RawDays int
+	// Days hourly rollups are kept, 0 to keep them forever
+	RollupDays int
+}
+
+// CompactionReport sums up a run of the compaction job
+type CompactionReport struct {
+	// Sensors that had a policy in effect
+	Sensors int `json:"sensors"`
+	// Raw readings compacted into rollups
+	RawCompacted int64 `json:"rawCompacted"`
+	// Rollups removed after their retention
+	RollupsDeleted int64 `json:"rollupsDeleted"`
+}
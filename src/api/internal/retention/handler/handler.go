@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"api/internal/retention/domain"
+	retention_service "api/internal/retention/usecase"
+	user_service "api/internal/users/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for handling HTTP requests related to data retention
+type RetentionHandler interface {
+	// Handles the HTTP request to set the retention policy of a sensor or category
+	SetPolicy(c *gin.Context)
+	// Handles the HTTP request to delete a retention policy
+	DeletePolicy(c *gin.Context)
+	// Handles the HTTP request to list the retention policies
+	ListPolicies(c *gin.Context)
+}
+
+// Structure request to set a retention policy
+type RetentionPolicyRequest struct {
+	// Sensor UUID, omitted for a category policy
+	SensorUuid *uuid.UUID `json:"sensorUuid"`
+	// Category, omitted for a sensor policy
+	Category *int `json:"category"`
+	// Days raw readings are kept before being compacted into hourly rollups
+	RawDays int `json:"rawDays"`
+	// Days hourly rollups are kept, 0 to keep them forever
+	RollupDays int `json:"rollupDays"`
+}
+
+// Structure request identifying a retention policy
+type RetentionUuidRequest struct {
+	// Policy UUID
+	ID uuid.UUID `json:"uuid" binding:"required"`
+}
+
+// Process HTTP requests and interaction with RetentionService/UserService for retention operations
+type RetentionHandlerImpl struct {
+	RetentionService retention_service.RetentionService
+	UserService      user_service.UserService
+}
+
+func NewRetentionHandler(retentionService retention_service.RetentionService, userService user_service.UserService) RetentionHandler {
+	return &RetentionHandlerImpl{
+		RetentionService: retentionService,
+		UserService:      userService,
+	}
+}
+
+// Gets the uuid and role of the user authenticated by the request's token
+func (h *RetentionHandlerImpl) getUser(c *gin.Context) (uuid.UUID, bool, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	var role bool
+	var userUuid uuid.UUID
+	err := h.UserService.GetRoutesAuthorization(c.Request.Context(), str, &role, &userUuid)
+	return userUuid, role, err
+}
+
+// Maps errors from the retention service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrPolicyNotFound), errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotAllowed):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func (h *RetentionHandlerImpl) SetPolicy(c *gin.Context) {
+	userUuid, isAdmin, err := h.getUser(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req RetentionPolicyRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	policy := &domain.RetentionPolicy{
+		SensorUuid: req.SensorUuid,
+		Category:   req.Category,
+		RawDays:    req.RawDays,
+		RollupDays: req.RollupDays,
+	}
+	if err = h.RetentionService.SetPolicy(c.Request.Context(), policy, userUuid, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"uuid": policy.ID})
+}
+
+func (h *RetentionHandlerImpl) DeletePolicy(c *gin.Context) {
+	userUuid, isAdmin, err := h.getUser(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req RetentionUuidRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.RetentionService.DeletePolicy(c.Request.Context(), req.ID, userUuid, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *RetentionHandlerImpl) ListPolicies(c *gin.Context) {
+	userUuid, isAdmin, err := h.getUser(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	policies, err := h.RetentionService.ListPolicies(c.Request.Context(), userUuid, isAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if policies == nil {
+		policies = []domain.RetentionPolicy{}
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	config "api/configs"
+	"api/internal/retention/domain"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for retention policies and compaction data operations
+type RetentionRepository interface {
+	// Stores the policy, replacing the existing one of the same sensor or category
+	SetPolicy(ctx context.Context, policy *domain.RetentionPolicy) error
+	// Retrieves a policy
+	GetPolicy(ctx context.Context, policyUuid uuid.UUID) (*domain.RetentionPolicy, error)
+	// Deletes a policy
+	DeletePolicy(ctx context.Context, policyUuid uuid.UUID) error
+	// Retrieves the category policies and the sensor policies of the user's sensors (every policy when all is set)
+	ListPolicies(ctx context.Context, userUuid uuid.UUID, all bool) ([]domain.RetentionPolicy, error)
+	// Retrieves the uuid of the user who owns the sensor
+	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error)
+	// Retrieves the policy in effect for every sensor that has one
+	ListSensorRetentions(ctx context.Context) ([]domain.SensorRetention, error)
+	// Compacts the raw readings of the sensor older than rawBefore into rollups and
+	// deletes the rollups older than rollupBefore (nil to keep them), returns the number of rows affected of each
+	CompactSensorData(ctx context.Context, sensorUuid uuid.UUID, rawBefore time.Time, rollupBefore *time.Time) (int64, int64, error)
+}
+
+// Performs retention's data operations using database/sql to interact with the database
+type RetentionRepositoryImpl struct {
+	DB *sql.DB
+}
+
+func NewRetentionRepository() (RetentionRepository, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	return &RetentionRepositoryImpl{DB: db}, nil
+}
+
+// Columns selected for policies, in the order read by scanPolicy
+const policyColumns = `retention_policies.uuid, retention_policies.sensorUuid, retention_policies.category,
+	retention_policies.rawDays, retention_policies.rollupDays`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPolicy(row scanner) (*domain.RetentionPolicy, error) {
+	var policy domain.RetentionPolicy
+	var sensorUuid uuid.NullUUID
+	var category sql.NullInt32
+	if err := row.Scan(&policy.ID, &sensorUuid, &category, &policy.RawDays, &policy.RollupDays); err != nil {
+		return nil, err
+	}
+	if sensorUuid.Valid {
+		policy.SensorUuid = &sensorUuid.UUID
+	}
+	if category.Valid {
+		value := int(category.Int32)
+		policy.Category = &value
+	}
+	return &policy, nil
+}
+
+func (r *RetentionRepositoryImpl) SetPolicy(ctx context.Context, policy *domain.RetentionPolicy) error {
+	// A sensor or a category has at most one policy
+	query := `
+		MERGE retention_policies AS target
+		USING (SELECT @sensorUuid AS sensorUuid, @category AS category) AS source
+		ON (target.sensorUuid = source.sensorUuid OR (target.sensorUuid IS NULL AND source.sensorUuid IS NULL AND target.category = source.category))
+		WHEN MATCHED THEN
+			UPDATE SET rawDays = @rawDays, rollupDays = @rollupDays
+		WHEN NOT MATCHED THEN
+			INSERT (uuid, sensorUuid, category, rawDays, rollupDays)
+			VALUES (@uuid, @sensorUuid, @category, @rawDays, @rollupDays)
+		OUTPUT inserted.uuid;
+	`
+
+	var sensorUuid uuid.NullUUID
+	if policy.SensorUuid != nil {
+		sensorUuid = uuid.NullUUID{UUID: *policy.SensorUuid, Valid: true}
+	}
+	var category sql.NullInt32
+	if policy.Category != nil {
+		category = sql.NullInt32{Int32: int32(*policy.Category), Valid: true}
+	}
+
+	err := r.DB.QueryRowContext(ctx, query,
+		sql.Named("uuid", policy.ID),
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("category", category),
+		sql.Named("rawDays", policy.RawDays),
+		sql.Named("rollupDays", policy.RollupDays),
+	).Scan(&policy.ID)
+	if err != nil {
+		return fmt.Errorf("failed to set retention policy: %v", err)
+	}
+	return nil
+}
+
+func (r *RetentionRepositoryImpl) GetPolicy(ctx context.Context, policyUuid uuid.UUID) (*domain.RetentionPolicy, error) {
+	query := `SELECT ` + policyColumns + ` FROM retention_policies WHERE uuid = @uuid`
+
+	policy, err := scanPolicy(r.DB.QueryRowContext(ctx, query, sql.Named("uuid", policyUuid)))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrPolicyNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve retention policy: %v", err)
+	}
+	return policy, nil
+}
+
+func (r *RetentionRepositoryImpl) DeletePolicy(ctx context.Context, policyUuid uuid.UUID) error {
+	query := `DELETE FROM retention_policies WHERE uuid = @uuid`
+
+	result, err := r.DB.ExecContext(ctx, query, sql.Named("uuid", policyUuid))
+	if err != nil {
+		return fmt.Errorf("failed to delete retention policy: %v", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrPolicyNotFound
+	}
+	return nil
+}
+
+func (r *RetentionRepositoryImpl) ListPolicies(ctx context.Context, userUuid uuid.UUID, all bool) ([]domain.RetentionPolicy, error) {
+	query := `
+		SELECT ` + policyColumns + `
+		FROM retention_policies
+		LEFT JOIN Sensors ON Sensors.uuid = retention_policies.sensorUuid
+		WHERE @all = 1 OR retention_policies.sensorUuid IS NULL OR Sensors.sensorOwnerUuid = @userUuid
+		ORDER BY retention_policies.category, retention_policies.sensorUuid
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("userUuid", userUuid), sql.Named("all", all))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %v", err)
+	}
+	defer rows.Close()
+
+	var policies []domain.RetentionPolicy
+	for rows.Next() {
+		policy, err := scanPolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %v", err)
+		}
+		policies = append(policies, *policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retention policies: %v", err)
+	}
+
+	return policies, nil
+}
+
+func (r *RetentionRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT sensorOwnerUuid FROM Sensors WHERE uuid = @sensorUuid`
+
+	var ownerUuid uuid.UUID
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&ownerUuid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.NilUUID, domain.ErrSensorNotFound
+		}
+		return uuid.NilUUID, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return ownerUuid, nil
+}
+
+func (r *RetentionRepositoryImpl) ListSensorRetentions(ctx context.Context) ([]domain.SensorRetention, error) {
+	// The sensor's own policy takes precedence over its category's
+	query := `
+		SELECT
+			Sensors.uuid,
+			CASE WHEN sensorPolicy.uuid IS NOT NULL THEN sensorPolicy.rawDays ELSE categoryPolicy.rawDays END,
+			CASE WHEN sensorPolicy.uuid IS NOT NULL THEN sensorPolicy.rollupDays ELSE categoryPolicy.rollupDays END
+		FROM Sensors
+		LEFT JOIN retention_policies AS sensorPolicy ON sensorPolicy.sensorUuid = Sensors.uuid
+		LEFT JOIN retention_policies AS categoryPolicy ON categoryPolicy.sensorUuid IS NULL AND categoryPolicy.category = Sensors.category
+		WHERE sensorPolicy.uuid IS NOT NULL OR categoryPolicy.uuid IS NOT NULL
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sensor retentions: %v", err)
+	}
+	defer rows.Close()
+
+	var retentions []domain.SensorRetention
+	for rows.Next() {
+		var retention domain.SensorRetention
+		if err := rows.Scan(&retention.SensorUuid, &retention.RawDays, &retention.RollupDays); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor retention: %v", err)
+		}
+		retentions = append(retentions, retention)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sensor retentions: %v", err)
+	}
+
+	return retentions, nil
+}
+
+func (r *RetentionRepositoryImpl) CompactSensorData(ctx context.Context, sensorUuid uuid.UUID, rawBefore time.Time, rollupBefore *time.Time) (int64, int64, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	// Readings are grouped in buckets aligned to the unix epoch. A bucket that was already rolled up
+	// (readings backfilled after a compaction) is combined with the new readings.
+	// Missing readings are placeholders, they're deleted without being rolled up.
+	// DATEADD only takes an INT, which overflows for seconds past 2038, so the whole days
+	// of the bucket are added first and the remaining seconds after.
+	query := `
+		MERGE SensorDataRollup AS target
+		USING (
+			SELECT
+				DATEADD(SECOND, CAST(aligned.bucketSeconds % 86400 AS INT),
+					DATEADD(DAY, CAST(aligned.bucketSeconds / 86400 AS INT), CAST('1970-01-01' AS DATETIME2))) AS bucketStart,
+				COUNT(*) AS valueCount,
+				SUM(value) AS valueSum,
+				MIN(value) AS minValue,
+				MAX(value) AS maxValue
+			FROM SensorData
+			CROSS APPLY (SELECT DATEDIFF_BIG(SECOND, '1970-01-01', timestamp) / @bucket * @bucket AS bucketSeconds) AS aligned
+			WHERE sensorUuid = @sensorUuid AND timestamp < @rawBefore AND quality <> 'missing'
+			GROUP BY aligned.bucketSeconds
+		) AS source
+		ON target.sensorUuid = @sensorUuid AND target.bucketStart = source.bucketStart
+		WHEN MATCHED THEN
+			UPDATE SET
+				valueCount = target.valueCount + source.valueCount,
+				valueSum = target.valueSum + source.valueSum,
+				minValue = CASE WHEN source.minValue < target.minValue THEN source.minValue ELSE target.minValue END,
+				maxValue = CASE WHEN source.maxValue > target.maxValue THEN source.maxValue ELSE target.maxValue END
+		WHEN NOT MATCHED THEN
+			INSERT (sensorUuid, bucketStart, valueCount, valueSum, minValue, maxValue)
+			VALUES (@sensorUuid, source.bucketStart, source.valueCount, source.valueSum, source.minValue, source.maxValue);
+	`
+	_, err = tx.ExecContext(ctx, query,
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("rawBefore", rawBefore),
+		sql.Named("bucket", int64(domain.RollupBucket/time.Second)),
+	)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to roll up sensor data: %v", err)
+	}
+
+	query = `DELETE FROM SensorData WHERE sensorUuid = @sensorUuid AND timestamp < @rawBefore`
+	result, err := tx.ExecContext(ctx, query, sql.Named("sensorUuid", sensorUuid), sql.Named("rawBefore", rawBefore))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete compacted sensor data: %v", err)
+	}
+	compacted, _ := result.RowsAffected()
+
+	var rollupsDeleted int64
+	if rollupBefore != nil {
+		query = `DELETE FROM SensorDataRollup WHERE sensorUuid = @sensorUuid AND bucketStart < @rollupBefore`
+		result, err = tx.ExecContext(ctx, query, sql.Named("sensorUuid", sensorUuid), sql.Named("rollupBefore", *rollupBefore))
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to delete expired rollups: %v", err)
+		}
+		rollupsDeleted, _ = result.RowsAffected()
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return compacted, rollupsDeleted, nil
+}
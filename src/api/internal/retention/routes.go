@@ -0,0 +1,57 @@
+package retention
+
+import (
+	auth_repository "api/internal/auth/repository"
+	auth_service "api/internal/auth/usecase"
+	"api/internal/retention/handler"
+	retention_repository "api/internal/retention/repository"
+	retention_service "api/internal/retention/usecase"
+	user_repository "api/internal/users/repository"
+	user_service "api/internal/users/usecase"
+	middleware "api/utils"
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRetentionRoutes declares the routes that can be accessed for data retention management
+// and starts the compaction job.
+func RegisterRetentionRoutes(router *gin.Engine) {
+
+	retentionRepo, err := retention_repository.NewRetentionRepository()
+	if err != nil {
+		log.Fatalf("Failed to create retention repository: %v", err)
+	}
+
+	usersRepos, err := user_repository.NewUserRepository()
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	authRepo, err := auth_repository.NewAuthRepository()
+	if err != nil {
+		log.Fatalf("Failed to create auth repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	retentionService := retention_service.NewRetentionService(retentionRepo)
+	authService := auth_service.NewAuthService(authRepo, usersRepos)
+
+	h := handler.NewRetentionHandler(retentionService, userService)
+
+	// Raw readings past their retention are compacted into hourly rollups periodically
+	go retentionService.Run(context.Background(), retention_service.DefaultCompactionInterval)
+
+	// Retention routes
+	api := router.Group("/v1/retention/")
+	api.Use(middleware.AuthMiddleware(authService))
+	{
+		// Set the retention policy of a sensor or category
+		api.POST("set", h.SetPolicy)
+		// Delete retention policy
+		api.POST("delete", h.DeletePolicy)
+		// List retention policies
+		api.POST("list", h.ListPolicies)
+	}
+}
@@ -0,0 +1,172 @@
+package usecase
+
+import (
+	"api/internal/retention/domain"
+	"api/internal/retention/repository"
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interval between runs of the compaction job
+const DefaultCompactionInterval = time.Hour
+
+// Interface for retention's services
+type RetentionService interface {
+	// Sets the policy of a sensor (owner or admin) or of a category (admin only)
+	SetPolicy(ctx context.Context, policy *domain.RetentionPolicy, userUuid uuid.UUID, isAdmin bool) error
+	// Deletes a policy, with the same permissions as setting it
+	DeletePolicy(ctx context.Context, policyUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error
+	// Lists the category policies and the policies of the user's sensors (every policy for admins)
+	ListPolicies(ctx context.Context, userUuid uuid.UUID, isAdmin bool) ([]domain.RetentionPolicy, error)
+	// Compacts the readings older than the raw window of each sensor's policy and drops expired rollups
+	Compact(ctx context.Context, now time.Time) (*domain.CompactionReport, error)
+	// Runs the compaction at each interval until the context is cancelled
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// Handles retention's logic and interaction with the repository
+type RetentionServiceImpl struct {
+	Repo repository.RetentionRepository
+}
+
+func NewRetentionService(repo repository.RetentionRepository) RetentionService {
+	return &RetentionServiceImpl{Repo: repo}
+}
+
+// Checks the target and windows of the policy
+func validatePolicy(policy *domain.RetentionPolicy) error {
+	if (policy.SensorUuid == nil) == (policy.Category == nil) {
+		return errors.New("a policy applies to either a sensorUuid or a category")
+	}
+	if policy.RawDays < 1 {
+		return errors.New("rawDays must be at least 1")
+	}
+	if policy.RollupDays != 0 && policy.RollupDays < policy.RawDays {
+		return errors.New("rollupDays must be 0 (keep forever) or at least rawDays")
+	}
+	return nil
+}
+
+// Checks that the user can manage the policies of the sensor or category
+func (s *RetentionServiceImpl) checkAllowed(ctx context.Context, policy *domain.RetentionPolicy, userUuid uuid.UUID, isAdmin bool) error {
+	if isAdmin {
+		return nil
+	}
+	if policy.SensorUuid == nil {
+		return domain.ErrNotAllowed
+	}
+
+	ownerUuid, err := s.Repo.GetSensorOwner(ctx, *policy.SensorUuid)
+	if err != nil {
+		return err
+	}
+	if ownerUuid != userUuid {
+		return domain.ErrNotAllowed
+	}
+	return nil
+}
+
+func (s *RetentionServiceImpl) SetPolicy(ctx context.Context, policy *domain.RetentionPolicy, userUuid uuid.UUID, isAdmin bool) error {
+	if err := validatePolicy(policy); err != nil {
+		return err
+	}
+
+	// The ownership check already fails for unknown sensors, admins skip it
+	if isAdmin && policy.SensorUuid != nil {
+		if _, err := s.Repo.GetSensorOwner(ctx, *policy.SensorUuid); err != nil {
+			return err
+		}
+	}
+
+	if err := s.checkAllowed(ctx, policy, userUuid, isAdmin); err != nil {
+		return err
+	}
+
+	policy.ID = uuid.NewV4()
+	return s.Repo.SetPolicy(ctx, policy)
+}
+
+func (s *RetentionServiceImpl) DeletePolicy(ctx context.Context, policyUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error {
+	policy, err := s.Repo.GetPolicy(ctx, policyUuid)
+	if err != nil {
+		return err
+	}
+
+	if err = s.checkAllowed(ctx, policy, userUuid, isAdmin); err != nil {
+		return err
+	}
+
+	return s.Repo.DeletePolicy(ctx, policyUuid)
+}
+
+func (s *RetentionServiceImpl) ListPolicies(ctx context.Context, userUuid uuid.UUID, isAdmin bool) ([]domain.RetentionPolicy, error) {
+	policies, err := s.Repo.ListPolicies(ctx, userUuid, isAdmin)
+	if err != nil {
+		return nil, errors.New("failed to retrieve retention policies")
+	}
+	return policies, nil
+}
+
+// Returns the time before which raw readings are compacted and the one before which
+// rollups are deleted (nil when they're kept forever), aligned to the rollup buckets
+func cutoffs(retention domain.SensorRetention, now time.Time) (time.Time, *time.Time) {
+	day := 24 * time.Hour
+
+	// Compacting up to a bucket boundary never splits a bucket between raw and rolled up readings
+	rawBefore := now.Add(-time.Duration(retention.RawDays) * day).Truncate(domain.RollupBucket)
+	if retention.RollupDays == 0 {
+		return rawBefore, nil
+	}
+
+	rollupBefore := now.Add(-time.Duration(retention.RollupDays) * day).Truncate(domain.RollupBucket)
+	return rawBefore, &rollupBefore
+}
+
+func (s *RetentionServiceImpl) Compact(ctx context.Context, now time.Time) (*domain.CompactionReport, error) {
+	retentions, err := s.Repo.ListSensorRetentions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.CompactionReport{Sensors: len(retentions)}
+	for _, retention := range retentions {
+		rawBefore, rollupBefore := cutoffs(retention, now)
+
+		compacted, rollupsDeleted, err := s.Repo.CompactSensorData(ctx, retention.SensorUuid, rawBefore, rollupBefore)
+		if err != nil {
+			// A failing sensor doesn't stop the compaction of the others, it's retried on the next run
+			log.Printf("Failed to compact data of sensor %s: %v", retention.SensorUuid.String(), err)
+			continue
+		}
+
+		report.RawCompacted += compacted
+		report.RollupsDeleted += rollupsDeleted
+	}
+
+	return report, nil
+}
+
+func (s *RetentionServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			report, err := s.Compact(ctx, now.UTC())
+			if err != nil {
+				log.Printf("Failed to compact sensor data: %v", err)
+				continue
+			}
+			if report.RawCompacted > 0 || report.RollupsDeleted > 0 {
+				log.Printf("Compacted %d readings into rollups and deleted %d expired rollups", report.RawCompacted, report.RollupsDeleted)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"api/internal/retention/domain"
+	"api/internal/retention/repository"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Repository recording the compactions requested by the service
+type fakeRepository struct {
+	repository.RetentionRepository
+	retentions  []domain.SensorRetention
+	compactions map[uuid.UUID][2]*time.Time
+}
+
+func (r *fakeRepository) ListSensorRetentions(ctx context.Context) ([]domain.SensorRetention, error) {
+	return r.retentions, nil
+}
+
+func (r *fakeRepository) CompactSensorData(ctx context.Context, sensorUuid uuid.UUID, rawBefore time.Time, rollupBefore *time.Time) (int64, int64, error) {
+	if r.compactions == nil {
+		r.compactions = make(map[uuid.UUID][2]*time.Time)
+	}
+	r.compactions[sensorUuid] = [2]*time.Time{&rawBefore, rollupBefore}
+	if rollupBefore == nil {
+		return 10, 0, nil
+	}
+	return 10, 2, nil
+}
+
+func TestCompactCutoffs(t *testing.T) {
+	kept, expiring := uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{retentions: []domain.SensorRetention{
+		{SensorUuid: kept, RawDays: 90},
+		{SensorUuid: expiring, RawDays: 7, RollupDays: 730},
+	}}
+	service := &RetentionServiceImpl{Repo: repo}
+	now := time.Date(2025, 6, 1, 10, 45, 30, 0, time.UTC)
+
+	report, err := service.Compact(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Sensors != 2 || report.RawCompacted != 20 || report.RollupsDeleted != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// Cutoffs are aligned to the start of the hour
+	if got, want := *repo.compactions[kept][0], time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected raw cutoff %v, got %v", want, got)
+	}
+	if repo.compactions[kept][1] != nil {
+		t.Errorf("expected rollups to be kept forever, got cutoff %v", repo.compactions[kept][1])
+	}
+	if got, want := *repo.compactions[expiring][1], time.Date(2023, 6, 2, 10, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("expected rollup cutoff %v, got %v", want, got)
+	}
+}
+
+func TestValidatePolicy(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	category := 0
+
+	tests := []struct {
+		name   string
+		policy domain.RetentionPolicy
+		valid  bool
+	}{
+		{"sensor policy", domain.RetentionPolicy{SensorUuid: &sensorUuid, RawDays: 90, RollupDays: 730}, true},
+		{"category policy keeping rollups forever", domain.RetentionPolicy{Category: &category, RawDays: 30}, true},
+		{"no target", domain.RetentionPolicy{RawDays: 30}, false},
+		{"both targets", domain.RetentionPolicy{SensorUuid: &sensorUuid, Category: &category, RawDays: 30}, false},
+		{"no raw window", domain.RetentionPolicy{Category: &category}, false},
+		{"rollups shorter than raw", domain.RetentionPolicy{Category: &category, RawDays: 90, RollupDays: 30}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validatePolicy(&test.policy)
+			if (err == nil) != test.valid {
+				t.Errorf("expected valid %v, got %v", test.valid, err)
+			}
+		})
+	}
+}
+
+func TestCategoryPolicyRequiresAdmin(t *testing.T) {
+	category := 1
+	service := &RetentionServiceImpl{Repo: &fakeRepository{}}
+
+	err := service.SetPolicy(context.Background(), &domain.RetentionPolicy{Category: &category, RawDays: 30}, uuid.NewV4(), false)
+	if !errors.Is(err, domain.ErrNotAllowed) {
+		t.Errorf("expected %v, got %v", domain.ErrNotAllowed, err)
+	}
+}
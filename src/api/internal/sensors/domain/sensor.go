@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"errors"
+	"time"
+
 	uuid "github.com/tentone/mssql-uuid"
 )
 
@@ -13,10 +16,40 @@ const (
 )
 
 const (
-	// Category constants
-	SENSOR_CATEGORY_TEMPERATURE int = 0
-	SENSOR_CATEGORY_HUMIDITY    int = 1
-	SENSOR_CATEGORY_PRESSURE    int = 2
+	// Sort fields of the sensor list
+	SENSOR_SORT_NAME         string = "name"
+	SENSOR_SORT_CREATED_AT   string = "createdAt"
+	SENSOR_SORT_LAST_READING string = "lastReading"
+)
+
+const (
+	// Reporting status of a monitored sensor
+	// Readings arrive at the expected interval
+	SENSOR_STATUS_ONLINE string = "online"
+	// No reading for StaleAfterIntervals intervals
+	SENSOR_STATUS_STALE string = "stale"
+	// No reading for OfflineAfterIntervals intervals
+	SENSOR_STATUS_OFFLINE string = "offline"
+)
+
+const (
+	// Number of reporting intervals without readings after which a sensor is stale
+	StaleAfterIntervals = 2
+	// Number of reporting intervals without readings after which a sensor is offline
+	OfflineAfterIntervals = 5
+)
+
+var (
+	// Returned when the sensor doesn't exist
+	ErrSensorNotFound = errors.New("sensor not found")
+	// Returned when the user is neither the owner of the sensor nor an admin
+	ErrNotSensorOwner = errors.New("user is not the owner of the sensor")
+	// Returned when archiving a sensor that is already archived
+	ErrSensorArchived = errors.New("sensor is already archived")
+	// Returned when restoring a sensor that isn't archived
+	ErrSensorNotArchived = errors.New("sensor is not archived")
+	// Returned when the sensor's category doesn't exist
+	ErrCategoryNotFound = errors.New("category not found")
 )
 
 // Sensor represents a device that collects and transmits data about its environment.
@@ -25,7 +58,7 @@ type Sensor struct {
 	ID uuid.UUID `json:"uuid"`
 	// Name of the sensor
 	Name string `json:"name"`
-	// Category specifies the type of data the sensor collects
+	// Id of the category of the data the sensor collects, which sets its unit and value range
 	Category int `json:"category"`
 	// Color for the sensor
 	Color string `json:"color"`
@@ -35,4 +68,105 @@ type Sensor struct {
 	Visibility bool `json:"visibility"`
 	// UUID of the user who owns the sensor
 	SensorOwnerUuid uuid.UUID `json:"sensorOwnerUuid"`
+	// Timestamp of the most recent reading (read only, nil if the sensor has no data)
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+	// Value of the most recent reading (read only, nil if the sensor has no data)
+	LastValue *float64 `json:"lastValue"`
+	// When the sensor was archived (read only, nil if it's active)
+	ArchivedAt *time.Time `json:"archivedAt"`
+	// When the sensor was created (read only)
+	CreatedAt time.Time `json:"createdAt"`
+	// Expected number of seconds between two readings, 0 when the sensor isn't monitored
+	ReportingInterval int `json:"reportingInterval"`
+	// Reporting status: online, stale or offline (read only, empty until the sensor is monitored)
+	Status string `json:"status"`
+	// When the status last changed (read only)
+	StatusChangedAt *time.Time `json:"statusChangedAt"`
+}
+
+// SensorFilter selects, sorts and paginates the sensors listed to a user
+type SensorFilter struct {
+	// Search term to filter sensors by name
+	Search string
+	// Only sensors of this category
+	Category *int
+	// Only sensors of this color
+	Color string
+	// Only public (true) or private (false) sensors
+	Visibility *bool
+	// Only sensors of this owner
+	OwnerUuid *uuid.UUID
+	// Only the sensors the user marked as favorite
+	FavoritesOnly bool
+	// Only the sensors the user owns
+	MineOnly bool
+	// Whether archived sensors are listed too
+	IncludeArchived bool
+	// Only sensors with this reporting status
+	Status string
+	// Field to sort by: name, createdAt or lastReading
+	SortBy string
+	// Sort direction: 1 for ascending, -1 for descending order
+	Sort int
+	// Maximum number of sensors returned
+	Limit int
+	// Number of sensors skipped
+	Offset int
+}
+
+// Statistics of the readings recorded by a sensor
+type SensorReadingStats struct {
+	// Number of readings, including the ones compacted into rollups
+	Count int64 `json:"count"`
+	// Timestamp of the oldest reading, nil if the sensor has no data
+	FirstTimestamp *time.Time `json:"firstTimestamp"`
+	// Timestamp of the most recent reading, nil if the sensor has no data
+	LastTimestamp *time.Time `json:"lastTimestamp"`
+}
+
+// SensorDetails is a sensor as seen by a user, with its owner and readings statistics
+type SensorDetails struct {
+	Sensor
+	// Display name of the owner
+	OwnerName string `json:"ownerName"`
+	// Whether the user marked the sensor as favorite
+	Favorite bool `json:"favorite"`
+	// Statistics of the readings
+	Stats SensorReadingStats `json:"stats"`
+}
+
+// MonitoredSensor is a sensor with a reporting interval, as checked by the status monitor
+type MonitoredSensor struct {
+	// UUID of the sensor
+	ID uuid.UUID
+	// Name of the sensor
+	Name string
+	// Expected number of seconds between two readings
+	ReportingInterval int
+	// Status stored by the previous check, empty before the first one
+	Status string
+	// Timestamp of the most recent reading, nil if the sensor has no data
+	LastSeenAt *time.Time
+	// When the sensor was created, the reference while it has no data
+	CreatedAt time.Time
+	// Display name of the owner
+	OwnerName string
+	// Email of the owner
+	OwnerEmail string
+}
+
+// SensorStatusChange is a transition of the reporting status of a sensor
+type SensorStatusChange struct {
+	// UUID of the sensor
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Name of the sensor
+	Name string `json:"name"`
+	// Status before the change
+	Previous string `json:"previous"`
+	// Status after the change
+	Status string `json:"status"`
+	// Timestamp of the most recent reading, nil if the sensor has no data
+	LastSeenAt *time.Time `json:"lastSeenAt"`
+	// When the change was detected
+	ChangedAt time.Time `json:"changedAt"`
 }
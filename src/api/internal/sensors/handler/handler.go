@@ -4,6 +4,7 @@ import (
 	"api/internal/sensors/domain"
 	sensor_service "api/internal/sensors/usecase"
 	user_service "api/internal/users/usecase"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,18 +16,63 @@ import (
 type SensorHandler interface {
 	// GetSensors handles the retrieval of all sensors.
 	ListSensors(c *gin.Context)
+	// Handles the retrieval of a single sensor with its details
+	GetSensor(c *gin.Context)
 	// Handles the HTTP request to create a new sensor
 	CreateSensor(c *gin.Context)
 	// Handles the HTTP request to edit sensor
 	EditSensor(c *gin.Context)
 	// Handles the HTTP request to mark/uncheck sensors as favorite
 	MarkSensorAsFavorite(c *gin.Context)
+	// Handles the HTTP request to archive a sensor
+	ArchiveSensor(c *gin.Context)
+	// Handles the HTTP request to restore an archived sensor
+	RestoreSensor(c *gin.Context)
+	// Handles the HTTP request to permanently delete a sensor
+	DeleteSensor(c *gin.Context)
 }
 
 // Structure request for list sensors
 type FilterSearch struct {
 	// Search term to filter sensors by name
 	Search string `json:"search"`
+	// Only sensors of this category
+	Category *int `json:"category"`
+	// Only sensors of this color
+	Color string `json:"color"`
+	// Only public (true) or private (false) sensors
+	Visibility *bool `json:"visibility"`
+	// Only sensors of this owner
+	OwnerUuid *uuid.UUID `json:"ownerUuid"`
+	// Only the sensors marked as favorite
+	FavoritesOnly bool `json:"favoritesOnly"`
+	// Only the sensors of the user
+	MineOnly bool `json:"mineOnly"`
+	// Whether archived sensors are listed too
+	IncludeArchived bool `json:"includeArchived"`
+	// Only sensors with this reporting status: online, stale or offline
+	Status string `json:"status"`
+	// Field to sort by: name (default), createdAt or lastReading
+	SortBy string `json:"sortBy"`
+	// Sort direction: 1 for ascending, -1 for descending order
+	Sort int `json:"sort"`
+	// Maximum number of sensors returned (default 50, at most 500)
+	Limit int `json:"limit"`
+	// Number of sensors skipped
+	Offset int `json:"offset"`
+}
+
+// Structure request to edit a sensor
+type RequestEditSensor struct {
+	domain.Sensor
+	// Expected number of seconds between two readings, 0 to stop monitoring, kept when omitted
+	ReportingInterval *int `json:"reportingInterval"`
+}
+
+// Structure request identifying a sensor
+type RequestSensorUuid struct {
+	// Sensor UUID
+	SensorUuid uuid.UUID `json:"uuid" binding:"required"`
 }
 
 // Structure request for sensor's marked as favorites
@@ -93,13 +139,13 @@ func (h *SensorHandlerImpl) EditSensor(c *gin.Context) {
 		return
 	}
 
-	var sensor domain.Sensor
-	if err = c.ShouldBindJSON(&sensor); err != nil {
+	var req RequestEditSensor
+	if err = c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	err = h.SensorService.EditSensor(c.Request.Context(), &sensor, userUuid)
+	err = h.SensorService.EditSensor(c.Request.Context(), &req.Sensor, req.ReportingInterval, userUuid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -128,22 +174,63 @@ func (h *SensorHandlerImpl) ListSensors(c *gin.Context) {
 		return
 	}
 
-	sensors, err := h.SensorService.ListSensors(c.Request.Context(), userUuid, req.Search)
+	sensors, total, err := h.SensorService.ListSensors(c.Request.Context(), userUuid, domain.SensorFilter{
+		Search:          req.Search,
+		Category:        req.Category,
+		Color:           req.Color,
+		Visibility:      req.Visibility,
+		OwnerUuid:       req.OwnerUuid,
+		FavoritesOnly:   req.FavoritesOnly,
+		MineOnly:        req.MineOnly,
+		IncludeArchived: req.IncludeArchived,
+		Status:          req.Status,
+		SortBy:          req.SortBy,
+		Sort:            req.Sort,
+		Limit:           req.Limit,
+		Offset:          req.Offset,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	var response []gin.H
-	for _, sensor := range sensors {
-		response = append(response, gin.H{
-			"name":       sensor.Name,
-			"category":   sensor.Category,
-			"visibility": sensor.Visibility,
-		})
+	if sensors == nil {
+		sensors = []domain.Sensor{}
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, gin.H{
+		"sensors": sensors,
+		"total":   total,
+	})
+}
+
+func (h *SensorHandlerImpl) GetSensor(c *gin.Context) {
+
+	// Gets token from header
+	var tokenAuth, _ = c.Get("token")
+
+	var str, _ = tokenAuth.(string)
+
+	// Get user id from token (set by login)
+	var userUuid, err = h.UserService.GetUserByToken(c.Request.Context(), str)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req RequestSensorUuid
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sensor, err := h.SensorService.GetSensor(c.Request.Context(), req.SensorUuid, userUuid)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sensor)
 }
 
 func (h *SensorHandlerImpl) MarkSensorAsFavorite(c *gin.Context) {
@@ -177,3 +264,83 @@ func (h *SensorHandlerImpl) MarkSensorAsFavorite(c *gin.Context) {
 		"favorite":   req.Favorite,
 	})
 }
+
+// Maps errors from the sensor service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrSensorArchived), errors.Is(err, domain.ErrSensorNotArchived):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// Gets the uuid and role of the user authenticated by the request's token and the sensor of the request body
+func (h *SensorHandlerImpl) bindManagementRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool, bool) {
+
+	// Gets token from header
+	var tokenAuth, _ = c.Get("token")
+
+	var str, _ = tokenAuth.(string)
+
+	// Get user id and role from token (set by login)
+	var role bool
+	var userUuid uuid.UUID
+	if err := h.UserService.GetRoutesAuthorization(c.Request.Context(), str, &role, &userUuid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return uuid.NilUUID, uuid.NilUUID, false, false
+	}
+
+	var req RequestSensorUuid
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return uuid.NilUUID, uuid.NilUUID, false, false
+	}
+
+	return req.SensorUuid, userUuid, role, true
+}
+
+func (h *SensorHandlerImpl) ArchiveSensor(c *gin.Context) {
+	sensorUuid, userUuid, isAdmin, ok := h.bindManagementRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.SensorService.ArchiveSensor(c.Request.Context(), sensorUuid, userUuid, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *SensorHandlerImpl) RestoreSensor(c *gin.Context) {
+	sensorUuid, userUuid, isAdmin, ok := h.bindManagementRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.SensorService.RestoreSensor(c.Request.Context(), sensorUuid, userUuid, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *SensorHandlerImpl) DeleteSensor(c *gin.Context) {
+	sensorUuid, userUuid, isAdmin, ok := h.bindManagementRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.SensorService.DeleteSensor(c.Request.Context(), sensorUuid, userUuid, isAdmin); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
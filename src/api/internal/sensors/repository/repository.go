@@ -5,6 +5,7 @@ import (
 	"api/internal/sensors/domain"
 	"context"
 	"fmt"
+	"time"
 
 	"database/sql"
 
@@ -19,10 +20,24 @@ type SensorRepository interface {
 	EditSensor(ctx context.Context, sensor *domain.Sensor) error
 	// Returns true if sensorID has the same owner as userID
 	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID, userID uuid.UUID) (bool, error)
-	// Retrieves all sensors from the database.
-	ListSensors(ctx context.Context, userID uuid.UUID, search string) ([]domain.Sensor, error)
+	// Retrieves a sensor, archived or not
+	GetSensor(ctx context.Context, sensorUuid uuid.UUID) (*domain.Sensor, error)
+	// Retrieves a sensor with its owner, the user's favorite status and readings statistics
+	GetSensorDetails(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) (*domain.SensorDetails, error)
+	// Retrieves a page of the sensors visible to the user matching the filter, with the total number of matches
+	ListSensors(ctx context.Context, userID uuid.UUID, filter domain.SensorFilter) ([]domain.Sensor, int, error)
 	// Mark/uncheck sensors as favorites
 	MarkSensorFavorite(ctx context.Context, userUuid uuid.UUID, sensorUuid uuid.UUID, favorite bool) error
+	// Sets or clears (nil) the archival time of a sensor
+	SetSensorArchived(ctx context.Context, sensorUuid uuid.UUID, archivedAt *time.Time) error
+	// Deletes a sensor with its data, favorites, alert rules and webhook subscriptions
+	DeleteSensor(ctx context.Context, sensorUuid uuid.UUID) error
+	// Returns true if the category exists
+	CategoryExists(ctx context.Context, category int) (bool, error)
+	// Lists the sensors that aren't archived and have a reporting interval, with their last reading and owner
+	ListMonitoredSensors(ctx context.Context) ([]domain.MonitoredSensor, error)
+	// Stores the reporting status of a sensor and when it changed
+	SetSensorStatus(ctx context.Context, sensorUuid uuid.UUID, status string, changedAt time.Time) error
 }
 
 type SensorRepositoryImpl struct {
@@ -40,8 +55,8 @@ func NewSensorRepository() (SensorRepository, error) {
 
 func (r *SensorRepositoryImpl) CreateSensor(ctx context.Context, sensor *domain.Sensor) error {
 	query := `
-		INSERT INTO Sensors (uuid, name, category, color, description, visibility, sensorOwnerUuid)
-		VALUES (@uuid, @name, @category, @color, @description, @visibility, @sensorOwnerUuid)
+		INSERT INTO Sensors (uuid, name, category, color, description, visibility, sensorOwnerUuid, createdAt, reportingInterval)
+		VALUES (@uuid, @name, @category, @color, @description, @visibility, @sensorOwnerUuid, @createdAt, @reportingInterval)
 	`
 
 	_, err := r.DB.ExecContext(ctx, query,
@@ -52,6 +67,8 @@ func (r *SensorRepositoryImpl) CreateSensor(ctx context.Context, sensor *domain.
 		sql.Named("description", sensor.Description),
 		sql.Named("visibility", sensor.Visibility),
 		sql.Named("sensorOwnerUuid", sensor.SensorOwnerUuid),
+		sql.Named("createdAt", sensor.CreatedAt),
+		sql.Named("reportingInterval", sensor.ReportingInterval),
 	)
 	if err != nil {
 		return err
@@ -68,17 +85,22 @@ func (r *SensorRepositoryImpl) EditSensor(ctx context.Context, sensor *domain.Se
 			category = COALESCE(NULLIF(@category, ''), category),
 			color = COALESCE(NULLIF(@color, ''), color),
 			description = @description,
-			visibility = COALESCE(NULLIF(@visibility, ''), visibility)
+			visibility = COALESCE(NULLIF(@visibility, ''), visibility),
+			-- The status is derived from the interval, a new interval starts it over
+			status = CASE WHEN reportingInterval = @reportingInterval THEN status ELSE NULL END,
+			statusChangedAt = CASE WHEN reportingInterval = @reportingInterval THEN statusChangedAt ELSE NULL END,
+			reportingInterval = @reportingInterval
 		WHERE uuid = @uuid
 	`
 
 	_, err := r.DB.ExecContext(ctx, query,
-		sql.Named("id", sensor.ID),
+		sql.Named("uuid", sensor.ID),
 		sql.Named("name", sensor.Name),
 		sql.Named("category", sensor.Category),
 		sql.Named("color", sensor.Color),
 		sql.Named("description", sensor.Description),
 		sql.Named("visibility", sensor.Visibility),
+		sql.Named("reportingInterval", sensor.ReportingInterval),
 	)
 	if err != nil {
 		return err
@@ -101,37 +123,229 @@ func (r *SensorRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uu
 	return true, nil
 }
 
-func (r *SensorRepositoryImpl) ListSensors(ctx context.Context, userID uuid.UUID, search string) ([]domain.Sensor, error) {
+func (r *SensorRepositoryImpl) GetSensor(ctx context.Context, sensorUuid uuid.UUID) (*domain.Sensor, error) {
+	query := `
+		SELECT uuid, name, category, color, description, visibility, sensorOwnerUuid, archivedAt, createdAt,
+			reportingInterval, status, statusChangedAt
+		FROM sensors
+		WHERE uuid = @sensorUuid
+	`
+
+	var sensor domain.Sensor
+	var archivedAt, statusChangedAt sql.NullTime
+	var status sql.NullString
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&sensor.ID, &sensor.Name, &sensor.Category,
+		&sensor.Color, &sensor.Description, &sensor.Visibility, &sensor.SensorOwnerUuid, &archivedAt, &sensor.CreatedAt,
+		&sensor.ReportingInterval, &status, &statusChangedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrSensorNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve sensor: %v", err)
+	}
+	if archivedAt.Valid {
+		sensor.ArchivedAt = &archivedAt.Time
+	}
+	setStatus(&sensor, status, statusChangedAt)
+
+	return &sensor, nil
+}
+
+func (r *SensorRepositoryImpl) GetSensorDetails(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) (*domain.SensorDetails, error) {
+	// Readings compacted into rollups still count in the statistics
 	query := `
-		SELECT uuid, name, category, description, visibility, SensorOwnerUuid
+		SELECT sensors.uuid, sensors.name, sensors.category, sensors.color, sensors.description, sensors.visibility,
+			sensors.sensorOwnerUuid, sensors.archivedAt, sensors.createdAt,
+			sensors.reportingInterval, sensors.status, sensors.statusChangedAt, users.name,
+			CASE WHEN favorite.sensorUuid IS NULL THEN 0 ELSE 1 END,
+			raw.readings + COALESCE(rollup.readings, 0),
+			CASE WHEN rollup.firstTimestamp < raw.firstTimestamp OR raw.firstTimestamp IS NULL THEN rollup.firstTimestamp ELSE raw.firstTimestamp END,
+			COALESCE(raw.lastTimestamp, rollup.lastTimestamp),
+			latest.value
 		FROM sensors
+		INNER JOIN users ON users.uuid = sensors.sensorOwnerUuid
+		LEFT JOIN user_favorite_sensors AS favorite ON favorite.sensorUuid = sensors.uuid AND favorite.userUuid = @userUuid
+		OUTER APPLY (
+			SELECT COUNT_BIG(*) AS readings, MIN(timestamp) AS firstTimestamp, MAX(timestamp) AS lastTimestamp
+			FROM SensorData
+			WHERE SensorData.sensorUuid = sensors.uuid
+		) AS raw
+		OUTER APPLY (
+			SELECT SUM(CAST(valueCount AS BIGINT)) AS readings, MIN(bucketStart) AS firstTimestamp, MAX(bucketStart) AS lastTimestamp
+			FROM SensorDataRollup
+			WHERE SensorDataRollup.sensorUuid = sensors.uuid
+		) AS rollup
+		OUTER APPLY (
+			SELECT TOP 1 value
+			FROM SensorData
+			WHERE SensorData.sensorUuid = sensors.uuid
+			ORDER BY timestamp DESC
+		) AS latest
+		WHERE sensors.uuid = @sensorUuid
+	`
+
+	var details domain.SensorDetails
+	var archivedAt, firstTimestamp, lastTimestamp, statusChangedAt sql.NullTime
+	var status sql.NullString
+	var lastValue sql.NullFloat64
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid), sql.Named("userUuid", userUuid)).Scan(
+		&details.ID, &details.Name, &details.Category, &details.Color, &details.Description, &details.Visibility,
+		&details.SensorOwnerUuid, &archivedAt, &details.CreatedAt,
+		&details.ReportingInterval, &status, &statusChangedAt, &details.OwnerName, &details.Favorite,
+		&details.Stats.Count, &firstTimestamp, &lastTimestamp, &lastValue,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrSensorNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve sensor: %w", err)
+	}
+
+	if archivedAt.Valid {
+		details.ArchivedAt = &archivedAt.Time
+	}
+	setStatus(&details.Sensor, status, statusChangedAt)
+	if firstTimestamp.Valid {
+		details.Stats.FirstTimestamp = &firstTimestamp.Time
+	}
+	if lastTimestamp.Valid {
+		details.Stats.LastTimestamp = &lastTimestamp.Time
+		details.LastSeenAt = &lastTimestamp.Time
+	}
+	if lastValue.Valid {
+		details.LastValue = &lastValue.Float64
+	}
+
+	return &details, nil
+}
+
+// Sets the reporting status of the sensor from its nullable columns
+func setStatus(sensor *domain.Sensor, status sql.NullString, changedAt sql.NullTime) {
+	if status.Valid {
+		sensor.Status = status.String
+	}
+	if changedAt.Valid {
+		sensor.StatusChangedAt = &changedAt.Time
+	}
+}
+
+// Column of each sort field of the sensor list
+var sortColumns = map[string]string{
+	domain.SENSOR_SORT_NAME:         "sensors.name",
+	domain.SENSOR_SORT_CREATED_AT:   "sensors.createdAt",
+	domain.SENSOR_SORT_LAST_READING: "latest.timestamp",
+}
+
+func (r *SensorRepositoryImpl) ListSensors(ctx context.Context, userID uuid.UUID, filter domain.SensorFilter) ([]domain.Sensor, int, error) {
+	column, ok := sortColumns[filter.SortBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported sort field: %s", filter.SortBy)
+	}
+	direction := "ASC"
+	if filter.Sort == -1 {
+		direction = "DESC"
+	}
+
+	// Filters left empty (NULL) don't restrict the list
+	from := `
+		FROM sensors
+		OUTER APPLY (
+			SELECT TOP 1 timestamp, value
+			FROM SensorData
+			WHERE SensorData.sensorUuid = sensors.uuid
+			ORDER BY timestamp DESC
+		) AS latest
 		WHERE (visibility = 1 OR SensorOwnerUuid = @userUuid) 
 		AND (@search IS NULL OR name LIKE '%' + @search + '%')
+		AND (@includeArchived = 1 OR archivedAt IS NULL)
+		AND (@category IS NULL OR category = @category)
+		AND (@color IS NULL OR color = @color)
+		AND (@visibility IS NULL OR visibility = @visibility)
+		AND (@status IS NULL OR status = @status)
+		AND (@ownerUuid IS NULL OR SensorOwnerUuid = @ownerUuid)
+		AND (@mineOnly = 0 OR SensorOwnerUuid = @userUuid)
+		AND (@favoritesOnly = 0 OR EXISTS (
+			SELECT 1 FROM user_favorite_sensors
+			WHERE user_favorite_sensors.sensorUuid = sensors.uuid AND user_favorite_sensors.userUuid = @userUuid
+		))
 	`
 
-	rows, err := r.DB.QueryContext(ctx, query,
+	var category sql.NullInt32
+	if filter.Category != nil {
+		category = sql.NullInt32{Int32: int32(*filter.Category), Valid: true}
+	}
+	var visibility sql.NullBool
+	if filter.Visibility != nil {
+		visibility = sql.NullBool{Bool: *filter.Visibility, Valid: true}
+	}
+	var ownerUuid uuid.NullUUID
+	if filter.OwnerUuid != nil {
+		ownerUuid = uuid.NullUUID{UUID: *filter.OwnerUuid, Valid: true}
+	}
+	args := []any{
 		sql.Named("userUuid", userID),
-		sql.Named("search", search),
-	)
+		sql.Named("search", sql.NullString{String: filter.Search, Valid: filter.Search != ""}),
+		sql.Named("includeArchived", filter.IncludeArchived),
+		sql.Named("category", category),
+		sql.Named("color", sql.NullString{String: filter.Color, Valid: filter.Color != ""}),
+		sql.Named("visibility", visibility),
+		sql.Named("status", sql.NullString{String: filter.Status, Valid: filter.Status != ""}),
+		sql.Named("ownerUuid", ownerUuid),
+		sql.Named("mineOnly", filter.MineOnly),
+		sql.Named("favoritesOnly", filter.FavoritesOnly),
+	}
+
+	var total int
+	if err := r.DB.QueryRowContext(ctx, `SELECT COUNT(*) `+from, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count sensors: %v", err)
+	}
+
+	// The uuid breaks ties so pages don't overlap
+	query := fmt.Sprintf(`
+		SELECT sensors.uuid, sensors.name, sensors.category, sensors.color, sensors.description, sensors.visibility, sensors.SensorOwnerUuid,
+			latest.timestamp, latest.value, sensors.archivedAt, sensors.createdAt,
+			sensors.reportingInterval, sensors.status, sensors.statusChangedAt
+		%s
+		ORDER BY %s %s, sensors.uuid
+		OFFSET @offset ROWS FETCH NEXT @limit ROWS ONLY
+	`, from, column, direction)
+
+	args = append(args, sql.Named("offset", filter.Offset), sql.Named("limit", filter.Limit))
+	rows, err := r.DB.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list sensors: %v", err)
+		return nil, 0, fmt.Errorf("failed to list sensors: %v", err)
 	}
 	defer rows.Close()
 
 	var sensors []domain.Sensor
 	for rows.Next() {
 		var sensor domain.Sensor
-		if err := rows.Scan(&sensor.ID, &sensor.Name, &sensor.Category, &sensor.Description, &sensor.Visibility, &sensor.SensorOwnerUuid); err != nil {
-			return nil, fmt.Errorf("failed to scan sensor: %v", err)
+		var lastSeenAt sql.NullTime
+		var lastValue sql.NullFloat64
+		var archivedAt, statusChangedAt sql.NullTime
+		var status sql.NullString
+		if err := rows.Scan(&sensor.ID, &sensor.Name, &sensor.Category, &sensor.Color, &sensor.Description, &sensor.Visibility, &sensor.SensorOwnerUuid,
+			&lastSeenAt, &lastValue, &archivedAt, &sensor.CreatedAt, &sensor.ReportingInterval, &status, &statusChangedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan sensor: %v", err)
+		}
+		setStatus(&sensor, status, statusChangedAt)
+		if archivedAt.Valid {
+			sensor.ArchivedAt = &archivedAt.Time
+		}
+		if lastSeenAt.Valid {
+			sensor.LastSeenAt = &lastSeenAt.Time
+		}
+		if lastValue.Valid {
+			sensor.LastValue = &lastValue.Float64
 		}
 		sensors = append(sensors, sensor)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating sensors: %v", err)
+		return nil, 0, fmt.Errorf("error iterating sensors: %v", err)
 	}
 
-	return sensors, nil
+	return sensors, total, nil
 }
 
 func (r *SensorRepositoryImpl) MarkSensorFavorite(ctx context.Context, userUuid uuid.UUID, sensorUuid uuid.UUID, favorite bool) error {
@@ -163,3 +377,141 @@ func (r *SensorRepositoryImpl) MarkSensorFavorite(ctx context.Context, userUuid
 
 	return nil
 }
+
+func (r *SensorRepositoryImpl) SetSensorArchived(ctx context.Context, sensorUuid uuid.UUID, archivedAt *time.Time) error {
+	query := `UPDATE sensors SET archivedAt = @archivedAt WHERE uuid = @sensorUuid`
+
+	var value sql.NullTime
+	if archivedAt != nil {
+		value = sql.NullTime{Time: *archivedAt, Valid: true}
+	}
+
+	result, err := r.DB.ExecContext(ctx, query, sql.Named("sensorUuid", sensorUuid), sql.Named("archivedAt", value))
+	if err != nil {
+		return fmt.Errorf("failed to update sensor archival: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrSensorNotFound
+	}
+	return nil
+}
+
+func (r *SensorRepositoryImpl) DeleteSensor(ctx context.Context, sensorUuid uuid.UUID) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Rows referencing the sensor go first, the sensor itself last
+	queries := []string{
+		`DELETE FROM SensorData WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM SensorDataRollup WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM retention_policies WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM anomaly_settings WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM user_favorite_sensors WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM sensor_alerts WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM alert_rules WHERE sensorUuid = @sensorUuid`,
+		`DELETE webhook_deliveries
+		FROM webhook_deliveries
+		INNER JOIN webhook_subscriptions ON webhook_subscriptions.uuid = webhook_deliveries.subscriptionUuid
+		WHERE webhook_subscriptions.sensorUuid = @sensorUuid`,
+		`DELETE webhook_dead_letters
+		FROM webhook_dead_letters
+		INNER JOIN webhook_subscriptions ON webhook_subscriptions.uuid = webhook_dead_letters.subscriptionUuid
+		WHERE webhook_subscriptions.sensorUuid = @sensorUuid`,
+		`DELETE FROM webhook_subscriptions WHERE sensorUuid = @sensorUuid`,
+		`DELETE FROM device_api_keys WHERE sensorUuid = @sensorUuid`,
+	}
+	for _, query := range queries {
+		if _, err = tx.ExecContext(ctx, query, sql.Named("sensorUuid", sensorUuid)); err != nil {
+			return fmt.Errorf("failed to delete sensor references: %w", err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM sensors WHERE uuid = @sensorUuid`, sql.Named("sensorUuid", sensorUuid))
+	if err != nil {
+		return fmt.Errorf("failed to delete sensor: %w", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrSensorNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *SensorRepositoryImpl) CategoryExists(ctx context.Context, category int) (bool, error) {
+	query := `SELECT COUNT(*) FROM sensor_categories WHERE id = @category`
+
+	var count int
+	if err := r.DB.QueryRowContext(ctx, query, sql.Named("category", category)).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check category: %v", err)
+	}
+	return count > 0, nil
+}
+
+func (r *SensorRepositoryImpl) ListMonitoredSensors(ctx context.Context) ([]domain.MonitoredSensor, error) {
+	// Readings compacted into rollups are older than the raw ones, only raw readings can be the last one,
+	// and missing placeholders don't count as the sensor reporting
+	query := `
+		SELECT sensors.uuid, sensors.name, sensors.reportingInterval, sensors.status, latest.timestamp,
+			sensors.createdAt, users.name, users.email
+		FROM sensors
+		INNER JOIN users ON users.uuid = sensors.sensorOwnerUuid
+		OUTER APPLY (
+			SELECT MAX(timestamp) AS timestamp
+			FROM SensorData
+			WHERE SensorData.sensorUuid = sensors.uuid
+			AND quality <> 'missing'
+		) AS latest
+		WHERE sensors.reportingInterval > 0
+		AND sensors.archivedAt IS NULL
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list monitored sensors: %v", err)
+	}
+	defer rows.Close()
+
+	var sensors []domain.MonitoredSensor
+	for rows.Next() {
+		var sensor domain.MonitoredSensor
+		var status sql.NullString
+		var lastSeenAt sql.NullTime
+		if err := rows.Scan(&sensor.ID, &sensor.Name, &sensor.ReportingInterval, &status, &lastSeenAt,
+			&sensor.CreatedAt, &sensor.OwnerName, &sensor.OwnerEmail); err != nil {
+			return nil, fmt.Errorf("failed to scan monitored sensor: %v", err)
+		}
+		sensor.Status = status.String
+		if lastSeenAt.Valid {
+			sensor.LastSeenAt = &lastSeenAt.Time
+		}
+		sensors = append(sensors, sensor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating monitored sensors: %v", err)
+	}
+
+	return sensors, nil
+}
+
+func (r *SensorRepositoryImpl) SetSensorStatus(ctx context.Context, sensorUuid uuid.UUID, status string, changedAt time.Time) error {
+	query := `UPDATE sensors SET status = @status, statusChangedAt = @changedAt WHERE uuid = @sensorUuid`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("status", status),
+		sql.Named("changedAt", changedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update sensor status: %w", err)
+	}
+	return nil
+}
@@ -7,6 +7,10 @@ import (
 	sensor_service "api/internal/sensors/usecase"
 	users_repository "api/internal/users/repository"
 	users_service "api/internal/users/usecase"
+	webhook_repository "api/internal/webhooks/repository"
+	webhook_service "api/internal/webhooks/usecase"
+	middleware "api/utils"
+	"context"
 	"log"
 
 	"github.com/gin-gonic/gin"
@@ -30,12 +34,24 @@ func RegisterSensorRoutes(router *gin.Engine) {
 		log.Fatalf("Failed to create auth repository: %v", err)
 	}
 
+	webhookRepo, err := webhook_repository.NewWebhookRepository()
+	if err != nil {
+		log.Fatalf("Failed to create webhook repository: %v", err)
+	}
+
 	userService := users_service.NewUserService(usersRepos, authRepo)
-	sensorService := sensor_service.NewSensorService(sensorRepo)
+	webhookService := webhook_service.NewWebhookService(webhookRepo)
+	// Created and updated sensors are sent to the webhook subscriptions
+	sensorService := sensor_service.NewSensorService(sensorRepo, webhookService)
+	// Status changes are emailed to the owner when a sensor goes offline or comes back, and sent to the webhook subscriptions
+	statusMonitor := sensor_service.NewStatusMonitor(sensorRepo, middleware.CreateEmail, webhookService)
 	// authService := auth_service.NewAuthService(authRepo, usersRepos)
 
 	h := handler.NewSensorHandler(sensorService, userService)
 
+	// The status of the sensors with a reporting interval is updated from their last reading periodically
+	go statusMonitor.Run(context.Background(), sensor_service.DefaultMonitorInterval)
+
 	// Sensor routes
 	api := router.Group("/v1/sensor/")
 	// api.Use(utils.AuthMiddleware(authService))
@@ -44,9 +60,17 @@ func RegisterSensorRoutes(router *gin.Engine) {
 		api.POST("favorite", h.MarkSensorAsFavorite)
 		// List sensors
 		api.POST("list", h.ListSensors)
+		// Get sensor with its details
+		api.POST("get", h.GetSensor)
 		// Update sensor
 		api.POST("edit", h.EditSensor)
 		// Create new sensor
 		api.POST("create", h.CreateSensor)
+		// Archive sensor, hiding it from the list
+		api.POST("archive", h.ArchiveSensor)
+		// Restore archived sensor
+		api.POST("restore", h.RestoreSensor)
+		// Permanently delete sensor with its data
+		api.POST("delete", h.DeleteSensor)
 	}
 }
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"api/internal/sensors/domain"
+	"api/internal/sensors/repository"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Interval between two checks of the sensors' status
+const DefaultMonitorInterval = time.Minute
+
+// Interface for the monitor of the sensors' reporting status
+type StatusMonitor interface {
+	// Updates the status of every monitored sensor from its last reading, returns the number of changes
+	Check(ctx context.Context, now time.Time) (int, error)
+	// Runs the check at each interval until the context is cancelled
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// Receives the status changes detected by the monitor
+type SensorStatusListener interface {
+	// Called after the status of a sensor changed
+	OnSensorStatusChanged(ctx context.Context, change *domain.SensorStatusChange)
+}
+
+// Sends an email
+type NotifyFunc func(to, subject, body string) error
+
+// Derives the status of the sensors from their last reading and reports the changes
+type StatusMonitorImpl struct {
+	Repo repository.SensorRepository
+	// Emails the owner when a sensor goes offline or comes back
+	Notify    NotifyFunc
+	Listeners []SensorStatusListener
+}
+
+func NewStatusMonitor(repo repository.SensorRepository, notify NotifyFunc, listeners ...SensorStatusListener) StatusMonitor {
+	return &StatusMonitorImpl{
+		Repo:      repo,
+		Notify:    notify,
+		Listeners: listeners,
+	}
+}
+
+// Status of the sensor given the time elapsed since its last reading, or since its creation without data
+func sensorStatus(sensor domain.MonitoredSensor, now time.Time) string {
+	var since = sensor.CreatedAt
+	if sensor.LastSeenAt != nil {
+		since = *sensor.LastSeenAt
+	}
+
+	var interval = time.Duration(sensor.ReportingInterval) * time.Second
+	var elapsed = now.Sub(since)
+	switch {
+	case elapsed >= offlineAfter(interval):
+		return domain.SENSOR_STATUS_OFFLINE
+	case elapsed >= staleAfter(interval):
+		return domain.SENSOR_STATUS_STALE
+	}
+	return domain.SENSOR_STATUS_ONLINE
+}
+
+// Time without readings after which a sensor with the interval is stale
+func staleAfter(interval time.Duration) time.Duration {
+	return domain.StaleAfterIntervals * interval
+}
+
+// Time without readings after which a sensor with the interval is offline
+func offlineAfter(interval time.Duration) time.Duration {
+	return domain.OfflineAfterIntervals * interval
+}
+
+func (s *StatusMonitorImpl) Check(ctx context.Context, now time.Time) (int, error) {
+	sensors, err := s.Repo.ListMonitoredSensors(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var changes int
+	for _, sensor := range sensors {
+		status := sensorStatus(sensor, now)
+		if status == sensor.Status {
+			continue
+		}
+
+		if err := s.Repo.SetSensorStatus(ctx, sensor.ID, status, now); err != nil {
+			// Retried on the next check, the stored status is still the previous one
+			log.Printf("Failed to update status of sensor %s: %v", sensor.ID.String(), err)
+			continue
+		}
+		changes++
+
+		// The first check of a sensor only records its status
+		if sensor.Status == "" {
+			continue
+		}
+
+		change := &domain.SensorStatusChange{
+			SensorUuid: sensor.ID,
+			Name:       sensor.Name,
+			Previous:   sensor.Status,
+			Status:     status,
+			LastSeenAt: sensor.LastSeenAt,
+			ChangedAt:  now,
+		}
+		if status == domain.SENSOR_STATUS_OFFLINE || change.Previous == domain.SENSOR_STATUS_OFFLINE {
+			s.notify(sensor, change)
+		}
+		for _, listener := range s.Listeners {
+			listener.OnSensorStatusChanged(ctx, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// Emails the owner of the sensor that it went offline or came back
+func (s *StatusMonitorImpl) notify(sensor domain.MonitoredSensor, change *domain.SensorStatusChange) {
+	var lastSeen = "never"
+	if change.LastSeenAt != nil {
+		lastSeen = change.LastSeenAt.UTC().Format(time.RFC3339)
+	}
+
+	subject := fmt.Sprintf("[Sensor] %s is reporting again", sensor.Name)
+	body := fmt.Sprintf("Hello %s,\n\nSensor \"%s\" is reporting again, it's now %s. Its last reading was at %s.",
+		sensor.OwnerName, sensor.Name, change.Status, lastSeen)
+	if change.Status == domain.SENSOR_STATUS_OFFLINE {
+		subject = fmt.Sprintf("[Sensor] %s is offline", sensor.Name)
+		body = fmt.Sprintf("Hello %s,\n\nSensor \"%s\" hasn't sent any reading for %s. Its last reading was at %s.",
+			sensor.OwnerName, sensor.Name, offlineAfter(time.Duration(sensor.ReportingInterval)*time.Second), lastSeen)
+	}
+
+	if err := s.Notify(sensor.OwnerEmail, subject, body); err != nil {
+		log.Printf("Failed to send status email to %s: %v", sensor.OwnerEmail, err)
+	}
+}
+
+func (s *StatusMonitorImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			changes, err := s.Check(ctx, now.UTC())
+			if err != nil {
+				log.Printf("Failed to check sensor status: %v", err)
+				continue
+			}
+			if changes > 0 {
+				log.Printf("Updated the status of %d sensors", changes)
+			}
+		}
+	}
+}
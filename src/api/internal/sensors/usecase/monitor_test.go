@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"api/internal/sensors/domain"
+	"api/internal/sensors/repository"
+	"context"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Repository serving fixed monitored sensors and recording the statuses set,
+// it also keeps the managed sensors in memory
+type fakeRepository struct {
+	repository.SensorRepository
+	sensors  []domain.MonitoredSensor
+	statuses map[uuid.UUID]string
+	managed  map[uuid.UUID]*domain.Sensor
+	deleted  []uuid.UUID
+	filter   domain.SensorFilter
+}
+
+func (r *fakeRepository) ListMonitoredSensors(ctx context.Context) ([]domain.MonitoredSensor, error) {
+	return r.sensors, nil
+}
+
+func (r *fakeRepository) SetSensorStatus(ctx context.Context, sensorUuid uuid.UUID, status string, changedAt time.Time) error {
+	r.statuses[sensorUuid] = status
+	return nil
+}
+
+// Listener recording the status changes it receives
+type recordingListener struct {
+	changes []*domain.SensorStatusChange
+}
+
+func (l *recordingListener) OnSensorStatusChanged(ctx context.Context, change *domain.SensorStatusChange) {
+	l.changes = append(l.changes, change)
+}
+
+func TestSensorStatus(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		at := now.Add(-d)
+		return &at
+	}
+
+	tests := []struct {
+		name       string
+		lastSeenAt *time.Time
+		createdAt  time.Time
+		want       string
+	}{
+		{"recent reading", ago(30 * time.Second), now.Add(-time.Hour), domain.SENSOR_STATUS_ONLINE},
+		{"two intervals without readings", ago(2 * time.Minute), now.Add(-time.Hour), domain.SENSOR_STATUS_STALE},
+		{"five intervals without readings", ago(5 * time.Minute), now.Add(-time.Hour), domain.SENSOR_STATUS_OFFLINE},
+		// Without data the sensor is checked against its creation time
+		{"new sensor without data", nil, now.Add(-time.Minute), domain.SENSOR_STATUS_ONLINE},
+		{"old sensor without data", nil, now.Add(-time.Hour), domain.SENSOR_STATUS_OFFLINE},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sensor := domain.MonitoredSensor{ReportingInterval: 60, LastSeenAt: test.lastSeenAt, CreatedAt: test.createdAt}
+			if got := sensorStatus(sensor, now); got != test.want {
+				t.Errorf("expected %s, got %s", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckStatus(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	lastSeenAt := now.Add(-10 * time.Minute)
+	created := now.Add(-24 * time.Hour)
+
+	unchecked, dead, stale, revived := uuid.NewV4(), uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{
+		sensors: []domain.MonitoredSensor{
+			{ID: unchecked, ReportingInterval: 60, LastSeenAt: &lastSeenAt, CreatedAt: created, OwnerEmail: "a@example.com"},
+			{ID: dead, ReportingInterval: 60, Status: domain.SENSOR_STATUS_STALE, LastSeenAt: &lastSeenAt, CreatedAt: created, OwnerEmail: "b@example.com"},
+			{ID: stale, ReportingInterval: 300, Status: domain.SENSOR_STATUS_ONLINE, LastSeenAt: &lastSeenAt, CreatedAt: created, OwnerEmail: "c@example.com"},
+			{ID: revived, ReportingInterval: 3600, Status: domain.SENSOR_STATUS_OFFLINE, LastSeenAt: &lastSeenAt, CreatedAt: created, OwnerEmail: "d@example.com"},
+		},
+		statuses: map[uuid.UUID]string{},
+	}
+	var emails []string
+	listener := &recordingListener{}
+	monitor := NewStatusMonitor(repo, func(to, subject, body string) error {
+		emails = append(emails, to)
+		return nil
+	}, listener)
+
+	changes, err := monitor.Check(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes != 4 {
+		t.Errorf("expected 4 changes, got %d", changes)
+	}
+
+	want := map[uuid.UUID]string{
+		unchecked: domain.SENSOR_STATUS_OFFLINE,
+		dead:      domain.SENSOR_STATUS_OFFLINE,
+		stale:     domain.SENSOR_STATUS_STALE,
+		revived:   domain.SENSOR_STATUS_ONLINE,
+	}
+	for sensorUuid, status := range want {
+		if repo.statuses[sensorUuid] != status {
+			t.Errorf("expected status %s, got %s", status, repo.statuses[sensorUuid])
+		}
+	}
+
+	// The first check of a sensor is silent, only going offline or coming back is emailed
+	if len(listener.changes) != 3 {
+		t.Errorf("expected 3 published changes, got %d", len(listener.changes))
+	}
+	if len(emails) != 2 || emails[0] != "b@example.com" || emails[1] != "d@example.com" {
+		t.Errorf("expected the owners of the dead and revived sensors to be emailed, got %v", emails)
+	}
+}
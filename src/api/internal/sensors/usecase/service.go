@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	uuid "github.com/tentone/mssql-uuid"
 )
@@ -14,27 +15,68 @@ import (
 type SensorService interface {
 	// Creates a new sensor
 	CreateSensor(ctx context.Context, sensor *domain.Sensor, userUuid uuid.UUID) error
-	// Updates an existing sensor
-	EditSensor(ctx context.Context, sensor *domain.Sensor, userUuid uuid.UUID) error
-	// List all sensors
-	ListSensors(ctx context.Context, userUuid uuid.UUID, search string) ([]domain.Sensor, error)
+	// Updates an existing sensor, keeping its reporting interval when none is given
+	EditSensor(ctx context.Context, sensor *domain.Sensor, reportingInterval *int, userUuid uuid.UUID) error
+	// Gets a sensor visible to the user with its details
+	GetSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) (*domain.SensorDetails, error)
+	// List a page of the sensors matching the filter, with the total number of matches
+	ListSensors(ctx context.Context, userUuid uuid.UUID, filter domain.SensorFilter) ([]domain.Sensor, int, error)
 	// Mark/uncheck sensors as favorites
 	MarkSensorFavorite(ctx context.Context, userUuid uuid.UUID, sensorUuid uuid.UUID, favorite bool) error
+	// Archives a sensor of the user (or any sensor for admins), hiding it from the list
+	ArchiveSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error
+	// Restores an archived sensor of the user (or any sensor for admins)
+	RestoreSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error
+	// Permanently deletes a sensor of the user (or any sensor for admins) with all its data
+	DeleteSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error
+}
+
+const (
+	// Sensors listed per page when no limit is given
+	DefaultListLimit = 50
+	// Maximum sensors listed per page
+	MaxListLimit = 500
+	// Longest expected reporting interval, in seconds (one week)
+	MaxReportingInterval = 7 * 24 * 60 * 60
+)
+
+// Receives the sensors created or updated through the service
+type SensorListener interface {
+	// Called after a sensor is created
+	OnSensorCreated(ctx context.Context, sensor *domain.Sensor)
+	// Called after a sensor is updated
+	OnSensorUpdated(ctx context.Context, sensor *domain.Sensor)
 }
 
 // Handles sensor's logic and interaction with the repository
 type SensorServiceImpl struct {
-	Repo repository.SensorRepository
+	Repo      repository.SensorRepository
+	Listeners []SensorListener
 }
 
-func NewSensorService(repo repository.SensorRepository) SensorService {
-	return &SensorServiceImpl{Repo: repo}
+func NewSensorService(repo repository.SensorRepository, listeners ...SensorListener) SensorService {
+	return &SensorServiceImpl{
+		Repo:      repo,
+		Listeners: listeners,
+	}
 }
 
-// Checks the required fields of the Sensor
-func validateRequiredFields(sensor *domain.Sensor) error {
-	if sensor.Name == "" || (sensor.Category != domain.SENSOR_CATEGORY_TEMPERATURE && sensor.Category != domain.SENSOR_CATEGORY_PRESSURE && sensor.Category != domain.SENSOR_CATEGORY_HUMIDITY) {
-		return errors.New("name is required and category must be one of the predefined values: Temperature, Pressure or Humidity")
+// Checks the required fields and reporting interval of the Sensor and that its category exists
+func (s *SensorServiceImpl) validateRequiredFields(ctx context.Context, sensor *domain.Sensor) error {
+	if sensor.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if sensor.ReportingInterval < 0 || sensor.ReportingInterval > MaxReportingInterval {
+		return fmt.Errorf("reportingInterval must be between 0 (not monitored) and %d seconds", MaxReportingInterval)
+	}
+
+	exists, err := s.Repo.CategoryExists(ctx, sensor.Category)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return domain.ErrCategoryNotFound
 	}
 	return nil
 }
@@ -42,12 +84,13 @@ func validateRequiredFields(sensor *domain.Sensor) error {
 func (s *SensorServiceImpl) CreateSensor(ctx context.Context, sensor *domain.Sensor, userUuid uuid.UUID) error {
 
 	var err error
-	if err = validateRequiredFields(sensor); err != nil {
+	if err = s.validateRequiredFields(ctx, sensor); err != nil {
 		return err
 	}
 
 	sensor.ID = uuid.NewV4()
 	sensor.SensorOwnerUuid = userUuid
+	sensor.CreatedAt = time.Now().UTC()
 
 	validColors := map[string]bool{
 		domain.SENSOR_COLOR_RED:    true,
@@ -66,17 +109,31 @@ func (s *SensorServiceImpl) CreateSensor(ctx context.Context, sensor *domain.Sen
 		return errors.New("failed to create sensor")
 	}
 
+	for _, listener := range s.Listeners {
+		listener.OnSensorCreated(ctx, sensor)
+	}
+
 	return nil
 }
 
-func (s *SensorServiceImpl) EditSensor(ctx context.Context, sensor *domain.Sensor, userUuid uuid.UUID) error {
+func (s *SensorServiceImpl) EditSensor(ctx context.Context, sensor *domain.Sensor, reportingInterval *int, userUuid uuid.UUID) error {
 
 	var stateOwner, err = s.Repo.GetSensorOwner(ctx, sensor.ID, userUuid)
 	if err != nil && !stateOwner {
 		return err
 	}
 
-	if err = validateRequiredFields(sensor); err != nil {
+	if reportingInterval != nil {
+		sensor.ReportingInterval = *reportingInterval
+	} else {
+		current, err := s.Repo.GetSensor(ctx, sensor.ID)
+		if err != nil {
+			return err
+		}
+		sensor.ReportingInterval = current.ReportingInterval
+	}
+
+	if err = s.validateRequiredFields(ctx, sensor); err != nil {
 		return err
 	}
 
@@ -96,21 +153,63 @@ func (s *SensorServiceImpl) EditSensor(ctx context.Context, sensor *domain.Senso
 		return err
 	}
 
+	for _, listener := range s.Listeners {
+		listener.OnSensorUpdated(ctx, sensor)
+	}
+
 	return nil
 }
 
-func (s *SensorServiceImpl) ListSensors(ctx context.Context, userUuid uuid.UUID, search string) ([]domain.Sensor, error) {
+func (s *SensorServiceImpl) GetSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) (*domain.SensorDetails, error) {
+	details, err := s.Repo.GetSensorDetails(ctx, sensorUuid, userUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Same visibility rule as ListSensors, private sensors of other users are reported as not found
+	if !details.Visibility && details.SensorOwnerUuid != userUuid {
+		return nil, domain.ErrSensorNotFound
+	}
+	return details, nil
+}
+
+func (s *SensorServiceImpl) ListSensors(ctx context.Context, userUuid uuid.UUID, filter domain.SensorFilter) ([]domain.Sensor, int, error) {
+
+	if filter.Sort != 1 && filter.Sort != -1 && filter.Sort != 0 {
+		return nil, 0, errors.New("invalid sort direction: must be 1 (ASC) or -1 (DESC) or 0 (NO ORDER)")
+	}
+
+	switch filter.SortBy {
+	case "":
+		filter.SortBy = domain.SENSOR_SORT_NAME
+	case domain.SENSOR_SORT_NAME, domain.SENSOR_SORT_CREATED_AT, domain.SENSOR_SORT_LAST_READING:
+	default:
+		return nil, 0, errors.New("invalid sort field: must be name, createdAt or lastReading")
+	}
+
+	switch filter.Status {
+	case "", domain.SENSOR_STATUS_ONLINE, domain.SENSOR_STATUS_STALE, domain.SENSOR_STATUS_OFFLINE:
+	default:
+		return nil, 0, errors.New("invalid status: must be online, stale or offline")
+	}
+
+	if filter.Limit == 0 {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Limit < 0 || filter.Limit > MaxListLimit || filter.Offset < 0 {
+		return nil, 0, fmt.Errorf("limit must be between 1 and %d and offset can't be negative", MaxListLimit)
+	}
 
-	var sensors, err = s.Repo.ListSensors(ctx, userUuid, search)
+	var sensors, total, err = s.Repo.ListSensors(ctx, userUuid, filter)
 	if err != nil {
-		return nil, errors.New("failed to retrieve sensors")
+		return nil, 0, errors.New("failed to retrieve sensors")
 	}
 
-	if search != "" && len(sensors) == 0 {
-		return nil, errors.New("no result was found")
+	if filter.Search != "" && total == 0 {
+		return nil, 0, errors.New("no result was found")
 	}
 
-	return sensors, nil
+	return sensors, total, nil
 }
 
 func (s *SensorServiceImpl) MarkSensorFavorite(ctx context.Context, userUuid uuid.UUID, sensorUuid uuid.UUID, favorite bool) error {
@@ -121,3 +220,51 @@ func (s *SensorServiceImpl) MarkSensorFavorite(ctx context.Context, userUuid uui
 	}
 	return err
 }
+
+// Retrieves the sensor if the user owns it or is an admin
+func (s *SensorServiceImpl) getManagedSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) (*domain.Sensor, error) {
+	sensor, err := s.Repo.GetSensor(ctx, sensorUuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isAdmin && sensor.SensorOwnerUuid != userUuid {
+		return nil, domain.ErrNotSensorOwner
+	}
+	return sensor, nil
+}
+
+func (s *SensorServiceImpl) ArchiveSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error {
+	sensor, err := s.getManagedSensor(ctx, sensorUuid, userUuid, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if sensor.ArchivedAt != nil {
+		return domain.ErrSensorArchived
+	}
+
+	var archivedAt = time.Now().UTC()
+	return s.Repo.SetSensorArchived(ctx, sensorUuid, &archivedAt)
+}
+
+func (s *SensorServiceImpl) RestoreSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error {
+	sensor, err := s.getManagedSensor(ctx, sensorUuid, userUuid, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if sensor.ArchivedAt == nil {
+		return domain.ErrSensorNotArchived
+	}
+
+	return s.Repo.SetSensorArchived(ctx, sensorUuid, nil)
+}
+
+func (s *SensorServiceImpl) DeleteSensor(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID, isAdmin bool) error {
+	if _, err := s.getManagedSensor(ctx, sensorUuid, userUuid, isAdmin); err != nil {
+		return err
+	}
+
+	return s.Repo.DeleteSensor(ctx, sensorUuid)
+}
@@ -0,0 +1,302 @@
+package usecase
+
+import (
+	"api/internal/sensors/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+func (r *fakeRepository) GetSensor(ctx context.Context, sensorUuid uuid.UUID) (*domain.Sensor, error) {
+	if sensor, ok := r.managed[sensorUuid]; ok {
+		return sensor, nil
+	}
+	return nil, domain.ErrSensorNotFound
+}
+
+func (r *fakeRepository) SetSensorArchived(ctx context.Context, sensorUuid uuid.UUID, archivedAt *time.Time) error {
+	r.managed[sensorUuid].ArchivedAt = archivedAt
+	return nil
+}
+
+func (r *fakeRepository) DeleteSensor(ctx context.Context, sensorUuid uuid.UUID) error {
+	delete(r.managed, sensorUuid)
+	r.deleted = append(r.deleted, sensorUuid)
+	return nil
+}
+
+func (r *fakeRepository) GetSensorDetails(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) (*domain.SensorDetails, error) {
+	if sensor, ok := r.managed[sensorUuid]; ok {
+		return &domain.SensorDetails{Sensor: *sensor}, nil
+	}
+	return nil, domain.ErrSensorNotFound
+}
+
+// Lists the sensors visible to the user like the query does, archived ones only when asked
+func (r *fakeRepository) ListSensors(ctx context.Context, userUuid uuid.UUID, filter domain.SensorFilter) ([]domain.Sensor, int, error) {
+	r.filter = filter
+	var sensors []domain.Sensor
+	for _, sensor := range r.managed {
+		if !sensor.Visibility && sensor.SensorOwnerUuid != userUuid {
+			continue
+		}
+		if sensor.ArchivedAt != nil && !filter.IncludeArchived {
+			continue
+		}
+		sensors = append(sensors, *sensor)
+	}
+	return sensors, len(sensors), nil
+}
+
+func (r *fakeRepository) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID, userID uuid.UUID) (bool, error) {
+	if _, ok := r.managed[sensorUuid]; !ok {
+		return false, domain.ErrSensorNotFound
+	}
+	return true, nil
+}
+
+func (r *fakeRepository) CategoryExists(ctx context.Context, category int) (bool, error) {
+	return true, nil
+}
+
+// Stores the edited sensor, starting its status over like the query does when the interval changes
+func (r *fakeRepository) EditSensor(ctx context.Context, sensor *domain.Sensor) error {
+	stored := r.managed[sensor.ID]
+	if stored.ReportingInterval != sensor.ReportingInterval {
+		stored.Status, stored.StatusChangedAt = "", nil
+	}
+	stored.Name, stored.Color, stored.ReportingInterval = sensor.Name, sensor.Color, sensor.ReportingInterval
+	return nil
+}
+
+func newManagedRepository(sensors ...*domain.Sensor) *fakeRepository {
+	repo := &fakeRepository{managed: map[uuid.UUID]*domain.Sensor{}}
+	for _, sensor := range sensors {
+		repo.managed[sensor.ID] = sensor
+	}
+	return repo
+}
+
+func TestArchiveAndRestoreSensor(t *testing.T) {
+	owner, other := uuid.NewV4(), uuid.NewV4()
+	sensor := &domain.Sensor{ID: uuid.NewV4(), Name: "greenhouse", SensorOwnerUuid: owner}
+	repo := newManagedRepository(sensor)
+	service := NewSensorService(repo)
+	ctx := context.Background()
+
+	// Only the owner or an admin manage the sensor
+	if err := service.ArchiveSensor(ctx, sensor.ID, other, false); !errors.Is(err, domain.ErrNotSensorOwner) {
+		t.Fatalf("expected another user to be refused, got %v", err)
+	}
+	if err := service.ArchiveSensor(ctx, uuid.NewV4(), owner, false); !errors.Is(err, domain.ErrSensorNotFound) {
+		t.Fatalf("expected an unknown sensor to be reported, got %v", err)
+	}
+
+	if err := service.ArchiveSensor(ctx, sensor.ID, owner, false); err != nil {
+		t.Fatal(err)
+	}
+	if sensor.ArchivedAt == nil {
+		t.Fatal("expected the sensor to be archived")
+	}
+	if err := service.ArchiveSensor(ctx, sensor.ID, owner, false); !errors.Is(err, domain.ErrSensorArchived) {
+		t.Errorf("expected archiving twice to fail, got %v", err)
+	}
+
+	if err := service.RestoreSensor(ctx, sensor.ID, other, false); !errors.Is(err, domain.ErrNotSensorOwner) {
+		t.Fatalf("expected another user to be refused, got %v", err)
+	}
+	// Admins manage any sensor
+	if err := service.RestoreSensor(ctx, sensor.ID, other, true); err != nil {
+		t.Fatal(err)
+	}
+	if sensor.ArchivedAt != nil {
+		t.Fatal("expected the sensor to be restored")
+	}
+	if err := service.RestoreSensor(ctx, sensor.ID, owner, false); !errors.Is(err, domain.ErrSensorNotArchived) {
+		t.Errorf("expected restoring an active sensor to fail, got %v", err)
+	}
+}
+
+func TestEditSensorKeepsReportingInterval(t *testing.T) {
+	owner := uuid.NewV4()
+	changedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	sensor := &domain.Sensor{ID: uuid.NewV4(), Name: "greenhouse", Color: domain.SENSOR_COLOR_RED, SensorOwnerUuid: owner,
+		ReportingInterval: 300, Status: domain.SENSOR_STATUS_ONLINE, StatusChangedAt: &changedAt}
+	repo := newManagedRepository(sensor)
+	service := NewSensorService(repo)
+	ctx := context.Background()
+
+	// Edits without the interval keep it and the status derived from it
+	edit := &domain.Sensor{ID: sensor.ID, Name: "greenhouse 2", Color: domain.SENSOR_COLOR_BLUE}
+	if err := service.EditSensor(ctx, edit, nil, owner); err != nil {
+		t.Fatal(err)
+	}
+	if sensor.Name != "greenhouse 2" || sensor.ReportingInterval != 300 || sensor.Status != domain.SENSOR_STATUS_ONLINE {
+		t.Fatalf("expected the interval and status to be kept, got %+v", sensor)
+	}
+	if edit.ReportingInterval != 300 {
+		t.Errorf("expected the edited sensor to carry the kept interval, got %d", edit.ReportingInterval)
+	}
+
+	// An explicit 0 stops monitoring the sensor
+	stopped := 0
+	if err := service.EditSensor(ctx, &domain.Sensor{ID: sensor.ID, Name: "greenhouse 2", Color: domain.SENSOR_COLOR_BLUE}, &stopped, owner); err != nil {
+		t.Fatal(err)
+	}
+	if sensor.ReportingInterval != 0 || sensor.Status != "" {
+		t.Fatalf("expected the sensor not to be monitored anymore, got %+v", sensor)
+	}
+}
+
+func TestDeleteSensor(t *testing.T) {
+	owner, other, admin := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	own := &domain.Sensor{ID: uuid.NewV4(), SensorOwnerUuid: owner}
+	managed := &domain.Sensor{ID: uuid.NewV4(), SensorOwnerUuid: other}
+	repo := newManagedRepository(own, managed)
+	service := NewSensorService(repo)
+	ctx := context.Background()
+
+	if err := service.DeleteSensor(ctx, managed.ID, owner, false); !errors.Is(err, domain.ErrNotSensorOwner) {
+		t.Fatalf("expected the sensor of another user to be refused, got %v", err)
+	}
+	if len(repo.deleted) != 0 {
+		t.Fatalf("expected nothing deleted, got %v", repo.deleted)
+	}
+
+	if err := service.DeleteSensor(ctx, own.ID, owner, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.DeleteSensor(ctx, managed.ID, admin, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deleted) != 2 {
+		t.Fatalf("expected both sensors deleted, got %v", repo.deleted)
+	}
+	if err := service.DeleteSensor(ctx, own.ID, owner, false); !errors.Is(err, domain.ErrSensorNotFound) {
+		t.Errorf("expected a deleted sensor to be reported as not found, got %v", err)
+	}
+}
+
+func TestListSensorsHidesArchived(t *testing.T) {
+	owner := uuid.NewV4()
+	archivedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	active := &domain.Sensor{ID: uuid.NewV4(), SensorOwnerUuid: owner}
+	archived := &domain.Sensor{ID: uuid.NewV4(), SensorOwnerUuid: owner, ArchivedAt: &archivedAt}
+	service := NewSensorService(newManagedRepository(active, archived))
+	ctx := context.Background()
+
+	sensors, total, err := service.ListSensors(ctx, owner, domain.SensorFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || sensors[0].ID != active.ID {
+		t.Fatalf("expected only the active sensor, got %+v", sensors)
+	}
+
+	if _, total, err = service.ListSensors(ctx, owner, domain.SensorFilter{IncludeArchived: true}); err != nil || total != 2 {
+		t.Fatalf("expected the archived sensor to be listed when asked, got %d (%v)", total, err)
+	}
+}
+
+func TestGetSensorVisibility(t *testing.T) {
+	owner, other := uuid.NewV4(), uuid.NewV4()
+	public := &domain.Sensor{ID: uuid.NewV4(), SensorOwnerUuid: owner, Visibility: true}
+	private := &domain.Sensor{ID: uuid.NewV4(), SensorOwnerUuid: owner}
+	service := NewSensorService(newManagedRepository(public, private))
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		sensor uuid.UUID
+		user   uuid.UUID
+		err    error
+	}{
+		{"public sensor to its owner", public.ID, owner, nil},
+		{"public sensor to another user", public.ID, other, nil},
+		{"private sensor to its owner", private.ID, owner, nil},
+		// Private sensors of other users are hidden as if they didn't exist
+		{"private sensor to another user", private.ID, other, domain.ErrSensorNotFound},
+		{"unknown sensor", uuid.NewV4(), owner, domain.ErrSensorNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			details, err := service.GetSensor(ctx, test.sensor, test.user)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if err == nil && details.ID != test.sensor {
+				t.Errorf("expected sensor %s, got %+v", test.sensor, details)
+			}
+		})
+	}
+}
+
+func TestListSensorsValidation(t *testing.T) {
+	owner := uuid.NewV4()
+	repo := newManagedRepository(&domain.Sensor{ID: uuid.NewV4(), Name: "greenhouse", SensorOwnerUuid: owner})
+	service := NewSensorService(repo)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		filter domain.SensorFilter
+		valid  bool
+	}{
+		{"defaults", domain.SensorFilter{}, true},
+		{"descending by last reading", domain.SensorFilter{SortBy: domain.SENSOR_SORT_LAST_READING, Sort: -1}, true},
+		{"status", domain.SensorFilter{Status: domain.SENSOR_STATUS_STALE}, true},
+		{"largest page", domain.SensorFilter{Limit: MaxListLimit, Offset: 1000}, true},
+		{"unknown sort direction", domain.SensorFilter{Sort: 2}, false},
+		{"unknown sort field", domain.SensorFilter{SortBy: "color"}, false},
+		{"unknown status", domain.SensorFilter{Status: "broken"}, false},
+		{"negative limit", domain.SensorFilter{Limit: -1}, false},
+		{"limit too large", domain.SensorFilter{Limit: MaxListLimit + 1}, false},
+		{"negative offset", domain.SensorFilter{Offset: -1}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := service.ListSensors(ctx, owner, test.filter)
+			if test.valid && err != nil {
+				t.Errorf("expected the filter to be accepted, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Error("expected the filter to be rejected")
+			}
+		})
+	}
+}
+
+func TestListSensorsDefaults(t *testing.T) {
+	owner := uuid.NewV4()
+	repo := newManagedRepository(&domain.Sensor{ID: uuid.NewV4(), Name: "greenhouse", SensorOwnerUuid: owner})
+	service := NewSensorService(repo)
+	ctx := context.Background()
+
+	if _, _, err := service.ListSensors(ctx, owner, domain.SensorFilter{}); err != nil {
+		t.Fatal(err)
+	}
+	if repo.filter.SortBy != domain.SENSOR_SORT_NAME || repo.filter.Limit != DefaultListLimit {
+		t.Errorf("expected sorting by name and the default page size, got %+v", repo.filter)
+	}
+
+	// The other fields are passed through as given
+	category := 3
+	filter := domain.SensorFilter{Category: &category, Color: domain.SENSOR_COLOR_RED, FavoritesOnly: true, Limit: 10, Offset: 20}
+	if _, _, err := service.ListSensors(ctx, owner, filter); err != nil {
+		t.Fatal(err)
+	}
+	if repo.filter.Category != &category || repo.filter.Color != domain.SENSOR_COLOR_RED || !repo.filter.FavoritesOnly ||
+		repo.filter.Limit != 10 || repo.filter.Offset != 20 {
+		t.Errorf("expected the filter to be passed through, got %+v", repo.filter)
+	}
+
+	// A search without matches is reported
+	if _, _, err := service.ListSensors(ctx, uuid.NewV4(), domain.SensorFilter{Search: "greenhouse"}); err == nil {
+		t.Error("expected a search without results to fail")
+	}
+}
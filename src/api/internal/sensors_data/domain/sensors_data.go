@@ -1,11 +1,96 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	uuid "github.com/tentone/mssql-uuid"
 )
 
+const (
+	// Aggregate functions applied to each bucket of sensor data
+	SENSOR_DATA_AGGREGATE_MIN   string = "min"
+	SENSOR_DATA_AGGREGATE_MAX   string = "max"
+	SENSOR_DATA_AGGREGATE_AVG   string = "avg"
+	SENSOR_DATA_AGGREGATE_SUM   string = "sum"
+	SENSOR_DATA_AGGREGATE_COUNT string = "count"
+	SENSOR_DATA_AGGREGATE_FIRST string = "first"
+	SENSOR_DATA_AGGREGATE_LAST  string = "last"
+)
+
+const (
+	// Periods the statistics of sensor data can be grouped by
+	SENSOR_DATA_GROUP_DAY  string = "day"
+	SENSOR_DATA_GROUP_WEEK string = "week"
+)
+
+const (
+	// Quality of a reading, as reported by the device
+	SENSOR_DATA_QUALITY_OK        string = "ok"
+	SENSOR_DATA_QUALITY_ESTIMATED string = "estimated"
+	SENSOR_DATA_QUALITY_SUSPECT   string = "suspect"
+	// Placeholder for a reading the device couldn't take, its value is meaningless
+	SENSOR_DATA_QUALITY_MISSING string = "missing"
+)
+
+const (
+	// Export formats for sensor data
+	SENSOR_DATA_EXPORT_CSV    string = "csv"
+	SENSOR_DATA_EXPORT_NDJSON string = "ndjson"
+)
+
+const (
+	// Policies applied when a reading already exists for the same sensor and timestamp
+	SENSOR_DATA_CONFLICT_REJECT    string = "reject"
+	SENSOR_DATA_CONFLICT_IGNORE    string = "ignore"
+	SENSOR_DATA_CONFLICT_OVERWRITE string = "overwrite"
+)
+
+// Returned when readings are rejected because they already exist for the same sensor and timestamp
+var ErrDuplicateSensorData = errors.New("sensor data already exists for some of the timestamps")
+
+// Returned when a bucket interval can't be parsed
+var ErrInvalidInterval = errors.New("invalid interval")
+
+// Returned when the aggregate function of a bucketed read is unknown
+var ErrInvalidAggregate = errors.New("invalid aggregate: must be one of min, max, avg, sum, count, first or last")
+
+// Returned when a downsampled read asks for too few points to keep the shape of the series
+var ErrInvalidMaxPoints = errors.New("maxPoints must be at least 3")
+
+// Returned when a page cursor wasn't returned by a previous page
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Returned when the conflict policy of an add or import is unknown
+var ErrInvalidConflictPolicy = errors.New("invalid onConflict: must be reject, ignore or overwrite")
+
+// Returned when a read is given no sensors or more than it accepts
+var ErrInvalidSensorCount = errors.New("invalid number of sensors")
+
+// Returned when an aligned read would have more buckets than it accepts
+var ErrTooManyBuckets = errors.New("too many buckets for the time range: use a larger interval")
+
+// Returned when the values read with an aggregate can't be converted to another unit
+var ErrUnitNotConvertible = errors.New("unit conversion not supported")
+
+// Returned when the sensor doesn't exist
+var ErrSensorNotFound = errors.New("sensor not found")
+
+// Returned when the user doesn't own the sensor
+var ErrNotSensorOwner = errors.New("sensor does not belong to the user")
+
+// Returned when a device API key is used to write the data of another sensor than its own
+var ErrKeyNotForSensor = errors.New("api key is not valid for the sensor")
+
+// Returned when readings are rejected because they're outside the physical range of the sensor's category
+var ErrValueOutOfRange = errors.New("value is outside the range of the sensor's category")
+
+// Returned when the quality flag of a reading is unknown
+var ErrInvalidQuality = errors.New("invalid quality")
+
+// Returned when the gaps of a sensor without reporting interval are read without giving an interval
+var ErrNoReportingInterval = errors.New("'interval' is required, the sensor has no reporting interval")
+
 // SensorData represents a recorded data point from a sensor
 type SensorData struct {
 	// UUID of the sensor that recorded this data
@@ -14,4 +99,121 @@ type SensorData struct {
 	Timestamp time.Time `json:"timestamp"`
 	// The measured value from the sensor
 	Value float64 `json:"value"`
+	// Whether the value deviates from the sensor's baseline (set by the anomaly detection)
+	Anomaly bool `json:"anomaly"`
+	// Quality of the reading: ok, estimated, suspect or missing
+	Quality string `json:"quality"`
+}
+
+// SensorDataCursor marks the position of the last reading returned in a page of sensor data
+type SensorDataCursor struct {
+	// Timestamp of the last reading returned
+	Timestamp time.Time `json:"t"`
+	// Position of the last reading among the readings sharing the same timestamp (tiebreaker)
+	Rank int64 `json:"r"`
+}
+
+// SensorDataGap is a time range without readings for longer than the sensor's expected interval
+type SensorDataGap struct {
+	// Timestamp of the last reading before the gap, or start of the time range
+	Start time.Time `json:"start"`
+	// Timestamp of the first reading after the gap, or end of the time range
+	End time.Time `json:"end"`
+}
+
+// SensorDataCompleteness reports the gaps of a sensor's data over a time range
+type SensorDataCompleteness struct {
+	// Expected interval between two readings
+	Interval time.Duration `json:"-"`
+	// Ranges without readings, oldest first
+	Gaps []SensorDataGap `json:"gaps"`
+	// Fraction of the time range not covered by gaps, from 0 to 1
+	Coverage float64 `json:"coverage"`
+}
+
+// SensorMetadata describes the sensor that recorded a series of data
+type SensorMetadata struct {
+	// UUID of the sensor
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Name of the sensor
+	Name string `json:"name"`
+	// Category of the sensor
+	Category int `json:"category"`
+	// Unit of the recorded values
+	Unit string `json:"unit"`
+	// Decimal places the values are displayed with
+	Precision int `json:"precision"`
+	// Expected number of seconds between two readings, 0 when not set
+	ReportingInterval int `json:"reportingInterval"`
+}
+
+// ValueRange is the physical range of the readings of a sensor's category
+type ValueRange struct {
+	// Lowest accepted value, nil when unbounded
+	Min *float64
+	// Highest accepted value, nil when unbounded
+	Max *float64
+}
+
+// Contains returns true if the value is within the range
+func (r ValueRange) Contains(value float64) bool {
+	return (r.Min == nil || value >= *r.Min) && (r.Max == nil || value <= *r.Max)
+}
+
+// ImportRowError describes a row of an imported file that was rejected
+type ImportRowError struct {
+	// Line of the file where the row starts
+	Line int `json:"line"`
+	// Reason why the row was rejected
+	Error string `json:"error"`
+}
+
+// ImportReport summarizes the result of importing sensor data from a file
+type ImportReport struct {
+	// Number of readings stored
+	Inserted int `json:"inserted"`
+	// Number of rows rejected
+	Rejected int `json:"rejected"`
+	// Errors found for each rejected row
+	Errors []ImportRowError `json:"errors"`
+}
+
+// SensorSeries holds the values of one sensor over a shared timestamp axis
+type SensorSeries struct {
+	// UUID of the sensor
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// One value per timestamp of the axis, nil when the sensor has no data for it
+	Values []*float64 `json:"values"`
+}
+
+// AlignedSensorData holds the series of several sensors aligned on a common timestamp axis
+type AlignedSensorData struct {
+	// Start of each bucket of the axis
+	Timestamps []time.Time `json:"timestamps"`
+	// Series of each sensor, in the order they were requested
+	Series []SensorSeries `json:"series"`
+}
+
+// SensorDataStats summarizes the readings of a sensor over a time range or one of its days or weeks.
+// The readings compacted into rollups are only kept summarized, so the standard deviation and
+// percentiles of a period with rollups are nil.
+type SensorDataStats struct {
+	// Start of the period (UTC day, or week starting on Monday), or of the time range when not grouped
+	Start time.Time `json:"start"`
+	// Number of readings
+	Count int64 `json:"count"`
+	// Lowest value, nil without readings
+	Min *float64 `json:"min"`
+	// Highest value, nil without readings
+	Max *float64 `json:"max"`
+	// Average value, nil without readings
+	Mean *float64 `json:"mean"`
+	// Sample standard deviation, nil with less than two readings
+	StdDev *float64 `json:"stddev"`
+	// Median, interpolated between the two nearest readings
+	P50 *float64 `json:"p50"`
+	// 95th percentile, interpolated between the two nearest readings
+	P95 *float64 `json:"p95"`
+	// 99th percentile, interpolated between the two nearest readings
+	P99 *float64 `json:"p99"`
 }
@@ -2,9 +2,18 @@ package handler
 
 import (
 	"api/internal/sensors_data/domain"
+	"api/internal/sensors_data/stream"
 	"api/internal/sensors_data/usecase"
+	"api/internal/units"
+	user_service "api/internal/users/usecase"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -15,20 +24,66 @@ import (
 type SensorDataHandler interface {
 	// Handles the HTTP request to add sensor data
 	AddSensorData(c *gin.Context)
+	// Handles the HTTP request to read the statistics of sensor data
+	ReadSensorDataStats(c *gin.Context)
 	// Handles the HTTP request to read sensor data
 	ReadSensorData(c *gin.Context)
+	// Handles the HTTP request to read the gaps of sensor data
+	ReadSensorDataGaps(c *gin.Context)
+	// Handles the HTTP request to export sensor data as CSV or NDJSON
+	ExportSensorData(c *gin.Context)
+	// Handles the HTTP request to import sensor data from a CSV file
+	ImportSensorData(c *gin.Context)
+	// Handles the HTTP request to read several sensors aligned on a common timestamp axis
+	ReadAlignedSensorData(c *gin.Context)
+	// Handles the HTTP request to read the most recent reading of one or many sensors
+	ReadLatestSensorData(c *gin.Context)
+	// Handles the HTTP request to receive the readings of a sensor in real time (Server-Sent Events)
+	SubscribeSensorData(c *gin.Context)
 }
 
-// Structure request to add sensor data
+// Timestamp-value pair recorded by a sensor
+type SensorReading struct {
+	// ISO 8601 format
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	// Optional quality of the reading: ok (default), estimated, suspect or missing
+	Quality string `json:"quality"`
+}
+
+// Group of readings recorded by the same sensor
+type SensorReadingsGroup struct {
+	// Uuid of the sensor that recorded the data
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Array of timestamp-value pairs
+	Readings []SensorReading `json:"readings"`
+}
+
+// Structure request to add sensor data, either for a single sensor (sensorUuid and readings)
+// or for several sensors at once (sensors)
 type SensorDataRequest struct {
 	// Uuid of the sensor that recorded the data
 	SensorUuid uuid.UUID `json:"sensorUuid"`
 	// Array of timestamp-value pairs
-	Readings []struct {
-		// ISO 8601 format
-		Timestamp time.Time `json:"timestamp"`
-		Value     float64   `json:"value"`
-	} `json:"readings"`
+	Readings []SensorReading `json:"readings"`
+	// Readings of several sensors, one group per sensor
+	Sensors []SensorReadingsGroup `json:"sensors"`
+	// What to do with readings that already exist for the same timestamp: reject (default), ignore or overwrite
+	OnConflict string `json:"onConflict"`
+}
+
+// Structure response with the outcome of the readings sent for one sensor
+type SensorDataGroupResult struct {
+	// Uuid of the sensor
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Number of readings stored
+	Accepted int `json:"accepted"`
+	// Number of readings that already existed and were left untouched (onConflict ignore)
+	Ignored int `json:"ignored"`
+	// Number of readings rejected
+	Rejected int `json:"rejected"`
+	// Reason why the readings were rejected
+	Error string `json:"error,omitempty"`
 }
 
 // Structure request to read sensor data
@@ -39,15 +94,170 @@ type SensorDataGetRequest struct {
 	From time.Time `json:"from"`
 	// End date of the time range
 	To time.Time `json:"to"`
+	// Optional bucket interval (e.g. 1m, 1h, 1d); when set, one aggregated value is returned per bucket
+	Interval string `json:"interval"`
+	// Aggregate function applied to each bucket: min, max, avg (default), sum, count, first or last
+	Aggregate string `json:"aggregate"`
+	// Optional maximum number of points; when set, raw data is downsampled with LTTB for chart rendering
+	MaxPoints int `json:"maxPoints"`
+	// Optional page size; when set, raw data is returned page by page
+	Limit int `json:"limit"`
+	// Opaque cursor returned as 'nextCursor' by the previous page
+	Cursor string `json:"cursor"`
+	// Optional unit the values are converted to from the unit of the sensor's category (e.g. °F, K, bar, psi)
+	Unit string `json:"unit"`
+}
+
+// Structure request to read the statistics of a sensor's data
+type SensorDataStatsRequest struct {
+	// Uuid for the sensor whose statistics are to be computed
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Start date of the time range
+	From time.Time `json:"from"`
+	// End date of the time range
+	To time.Time `json:"to"`
+	// Optional period the statistics are grouped by: day or week (starting on Monday), in UTC
+	GroupBy string `json:"groupBy"`
+	// Optional unit the statistics are converted to from the unit of the sensor's category
+	Unit string `json:"unit"`
+}
+
+// Structure request to read the gaps of a sensor's data
+type SensorDataGapsRequest struct {
+	// Uuid for the sensor whose data is to be checked
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Start date of the time range
+	From time.Time `json:"from"`
+	// End date of the time range
+	To time.Time `json:"to"`
+	// Expected interval between two readings (e.g. 30s, 5m, 1h), defaults to the sensor's reporting interval;
+	// longer stretches without readings are gaps
+	Interval string `json:"interval"`
 }
 
-// Process HTTP requests and interaction with the SensorDataService
+// Structure request to read several sensors aligned on a common timestamp axis
+type SensorDataAlignedRequest struct {
+	// Uuids of the sensors to compare
+	SensorUuids []uuid.UUID `json:"sensorUuids"`
+	// Start date of the time range
+	From time.Time `json:"from"`
+	// End date of the time range
+	To time.Time `json:"to"`
+	// Bucket interval of the axis (e.g. 1m, 1h, 1d)
+	Interval string `json:"interval"`
+	// Aggregate function applied to each bucket: min, max, avg (default), sum, count, first or last
+	Aggregate string `json:"aggregate"`
+}
+
+// Structure request to read the most recent reading of sensors
+type SensorDataLatestRequest struct {
+	// Uuids of the sensors
+	SensorUuids []uuid.UUID `json:"sensorUuids"`
+}
+
+// Structure request to export sensor data
+type SensorDataExportRequest struct {
+	// Uuid for the sensor whose data is to be exported
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// Start date of the time range
+	From time.Time `json:"from"`
+	// End date of the time range
+	To time.Time `json:"to"`
+	// Export format: csv or ndjson (when empty, it's chosen from the Accept header)
+	Format string `json:"format"`
+}
+
+// Number of rows written before flushing the response to the client
+const exportFlushEvery = 1000
+
+// Interval between keep-alive comments sent to real-time subscribers
+const subscribeHeartbeat = 15 * time.Second
+
+// Process HTTP requests and interaction with the SensorDataService/UserService
 type SensorDataHandlerImpl struct {
-	Service usecase.SensorDataService
+	Service     usecase.SensorDataService
+	UserService user_service.UserService
+	Hub         *stream.Hub
 }
 
-func NewSensorDataHandler(service usecase.SensorDataService) SensorDataHandler {
-	return &SensorDataHandlerImpl{Service: service}
+func NewSensorDataHandler(service usecase.SensorDataService, userService user_service.UserService, hub *stream.Hub) SensorDataHandler {
+	return &SensorDataHandlerImpl{
+		Service:     service,
+		UserService: userService,
+		Hub:         hub,
+	}
+}
+
+// Gets the uuid of the user from the token set by the auth middleware
+func (h *SensorDataHandlerImpl) getUserUuid(c *gin.Context) (uuid.UUID, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	return h.UserService.GetUserByToken(c.Request.Context(), str)
+}
+
+// Maps errors from the ownership check to the HTTP status to respond with
+func ownershipErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner), errors.Is(err, domain.ErrKeyNotForSensor):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// Maps errors from the sensor data reads to the HTTP status to respond with
+func readErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidInterval), errors.Is(err, domain.ErrInvalidAggregate),
+		errors.Is(err, domain.ErrInvalidMaxPoints), errors.Is(err, domain.ErrInvalidCursor),
+		errors.Is(err, domain.ErrInvalidSensorCount), errors.Is(err, domain.ErrTooManyBuckets),
+		errors.Is(err, domain.ErrUnitNotConvertible), errors.Is(err, units.ErrUnknownUnit),
+		errors.Is(err, units.ErrIncompatibleUnits):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// Returns the check that the request can write the data of a sensor. Requests made with a device API key can
+// only write the data of the key's sensor, those made with a token the data of the user's sensors.
+func (h *SensorDataHandlerImpl) writeAuthorizer(c *gin.Context) (func(sensorUuid uuid.UUID) error, error) {
+	if keySensor, ok := c.Get("apiKeySensor"); ok {
+		var allowed, _ = keySensor.(uuid.UUID)
+		return func(sensorUuid uuid.UUID) error {
+			if sensorUuid != allowed {
+				return domain.ErrKeyNotForSensor
+			}
+			return nil
+		}, nil
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		return nil, err
+	}
+	return func(sensorUuid uuid.UUID) error {
+		return h.Service.CheckSensorOwner(c.Request.Context(), sensorUuid, userUuid)
+	}, nil
+}
+
+// Converts readings of a sensor to sensor data
+func toSensorData(sensorUuid uuid.UUID, readings []SensorReading) []*domain.SensorData {
+	var sensorDataList []*domain.SensorData
+	for _, reading := range readings {
+		sensorDataList = append(sensorDataList, &domain.SensorData{
+			SensorUuid: sensorUuid,
+			Timestamp:  reading.Timestamp,
+			Value:      reading.Value,
+			Quality:    reading.Quality,
+		})
+	}
+	return sensorDataList
 }
 
 func (h *SensorDataHandlerImpl) AddSensorData(c *gin.Context) {
@@ -58,34 +268,97 @@ func (h *SensorDataHandlerImpl) AddSensorData(c *gin.Context) {
 		return
 	}
 
-	if len(req.Readings) == 0 {
+	if len(req.Sensors) > 0 && (len(req.Readings) > 0 || req.SensorUuid != uuid.NilUUID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'sensors' cannot be combined with 'sensorUuid' and 'readings'"})
+		return
+	}
+
+	if len(req.Sensors) == 0 && len(req.Readings) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one sensor data is required"})
 		return
 	}
 
-	var sensorDataList []*domain.SensorData
-	var responseData [][]float64
+	authorize, err := h.writeAuthorizer(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
 
-	for _, reading := range req.Readings {
-		sensorData := &domain.SensorData{
-			SensorUuid: req.SensorUuid,
-			Timestamp:  reading.Timestamp,
-			Value:      reading.Value,
-		}
+	if len(req.Sensors) > 0 {
+		h.addSensorDataGroups(c, req, authorize)
+		return
+	}
+
+	if err = authorize(req.SensorUuid); err != nil {
+		c.JSON(ownershipErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
 
-		sensorDataList = append(sensorDataList, sensorData)
+	var sensorDataList = toSensorData(req.SensorUuid, req.Readings)
+	var responseData [][]float64
+	for _, reading := range req.Readings {
 		responseData = append(responseData, []float64{float64(reading.Timestamp.Unix()), reading.Value})
 	}
 
-	if err := h.Service.AddSensorData(c.Request.Context(), sensorDataList); err != nil {
-		fmt.Print(err)
+	accepted, err := h.Service.AddSensorData(c.Request.Context(), sensorDataList, req.OnConflict)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicateSensorData) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, domain.ErrValueOutOfRange) || errors.Is(err, domain.ErrInvalidConflictPolicy) ||
+			errors.Is(err, domain.ErrInvalidQuality) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("Failed to add sensor data for %s: %v", req.SensorUuid.String(), err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"uuid": req.SensorUuid,
-		"data": responseData,
+		"uuid":     req.SensorUuid,
+		"data":     responseData,
+		"accepted": accepted,
+		"ignored":  len(sensorDataList) - accepted,
+	})
+}
+
+// Stores the readings of each sensor group independently, so one rejected sensor doesn't discard the others
+func (h *SensorDataHandlerImpl) addSensorDataGroups(c *gin.Context, req SensorDataRequest, authorize func(sensorUuid uuid.UUID) error) {
+	var results []SensorDataGroupResult
+	var accepted, ignored, rejected int
+
+	for _, group := range req.Sensors {
+		var result = SensorDataGroupResult{SensorUuid: group.SensorUuid}
+
+		var stored int
+		var err error
+		if len(group.Readings) == 0 {
+			err = errors.New("at least one sensor data is required")
+		} else if err = authorize(group.SensorUuid); err == nil {
+			stored, err = h.Service.AddSensorData(c.Request.Context(), toSensorData(group.SensorUuid, group.Readings), req.OnConflict)
+		}
+
+		if err != nil {
+			result.Rejected = len(group.Readings)
+			result.Error = err.Error()
+		} else {
+			result.Accepted = stored
+			result.Ignored = len(group.Readings) - stored
+		}
+
+		accepted += result.Accepted
+		ignored += result.Ignored
+		rejected += result.Rejected
+		results = append(results, result)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accepted": accepted,
+		"ignored":  ignored,
+		"rejected": rejected,
+		"sensors":  results,
 	})
 }
 
@@ -102,12 +375,238 @@ func (h *SensorDataHandlerImpl) ReadSensorData(c *gin.Context) {
 		return
 	}
 
-	sensorData, err := h.Service.GetSensorData(c.Request.Context(), req.SensorUuid, req.From, req.To)
+	if req.Interval != "" && req.MaxPoints > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'interval' and 'maxPoints' cannot be used together"})
+		return
+	}
+
+	if (req.Limit > 0 || req.Cursor != "") && (req.Interval != "" || req.MaxPoints > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'limit' and 'cursor' can only be used on raw data reads"})
+		return
+	}
+
+	if req.Cursor != "" && req.Limit <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'limit' is required when 'cursor' is given"})
+		return
+	}
+
+	if req.Limit > usecase.MaxPageLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("'limit' cannot be greater than %d", usecase.MaxPageLimit)})
+		return
+	}
+
+	var conversion *units.Conversion
+	if req.Unit != "" {
+		// The aggregate only applies to bucketed reads
+		var aggregate string
+		if req.Interval != "" {
+			aggregate = req.Aggregate
+		}
+
+		var err error
+		if conversion, err = h.Service.GetUnitConversion(c.Request.Context(), req.SensorUuid, req.Unit, aggregate); err != nil {
+			c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// Converts the values and reports the unit applied, if one was requested
+	var respond = func(response gin.H, sensorData []domain.SensorData) {
+		if conversion != nil {
+			usecase.ConvertSensorData(sensorData, conversion)
+			response["unit"] = conversion.To
+		}
+		response["data"] = toDataPairs(sensorData)
+		// Buckets aggregate several readings, only raw readings carry their anomaly and quality flags
+		if req.Interval == "" {
+			response["anomalies"] = anomalyTimestamps(sensorData)
+			response["quality"] = qualityTimestamps(sensorData)
+		}
+		c.JSON(http.StatusOK, response)
+	}
+
+	if req.Limit > 0 {
+		sensorData, nextCursor, err := h.Service.GetSensorDataPage(c.Request.Context(), req.SensorUuid, req.From, req.To, req.Cursor, req.Limit)
+		if err != nil {
+			c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
+		var response = gin.H{"nextCursor": nil}
+		if nextCursor != "" {
+			response["nextCursor"] = nextCursor
+		}
+
+		respond(response, sensorData)
+		return
+	}
+
+	var sensorData []domain.SensorData
+	var err error
+	if req.MaxPoints > 0 {
+		sensorData, err = h.Service.GetDownsampledSensorData(c.Request.Context(), req.SensorUuid, req.From, req.To, req.MaxPoints)
+	} else if req.Interval != "" {
+		if _, err = usecase.ParseInterval(req.Interval); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sensorData, err = h.Service.GetAggregatedSensorData(c.Request.Context(), req.SensorUuid, req.From, req.To, req.Interval, req.Aggregate)
+	} else {
+		sensorData, err = h.Service.GetSensorData(c.Request.Context(), req.SensorUuid, req.From, req.To)
+	}
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	respond(gin.H{}, sensorData)
+}
+
+func (h *SensorDataHandlerImpl) ReadSensorDataStats(c *gin.Context) {
+	var req SensorDataStatsRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.From.After(req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' timestamp must be before 'to' timestamp"})
+		return
+	}
+
+	groupBy, err := usecase.ParseGroupBy(req.GroupBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	if err = h.Service.CheckSensorsVisible(c.Request.Context(), userUuid, req.SensorUuid); err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var conversion *units.Conversion
+	if req.Unit != "" {
+		if conversion, err = h.Service.GetUnitConversion(c.Request.Context(), req.SensorUuid, req.Unit, ""); err != nil {
+			c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	stats, err := h.Service.GetSensorDataStats(c.Request.Context(), req.SensorUuid, req.From, req.To, groupBy)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
+	var response = gin.H{}
+	if conversion != nil {
+		usecase.ConvertSensorDataStats(stats, conversion)
+		response["unit"] = conversion.To
+	}
+
+	if groupBy == "" {
+		response["stats"] = stats[0]
+	} else {
+		if stats == nil {
+			stats = []domain.SensorDataStats{}
+		}
+		response["stats"] = stats
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Lists the timestamps, in unix seconds, of the readings flagged as anomalies
+func anomalyTimestamps(sensorData []domain.SensorData) []int64 {
+	var timestamps = []int64{}
+	for _, data := range sensorData {
+		if data.Anomaly {
+			timestamps = append(timestamps, data.Timestamp.Unix())
+		}
+	}
+	return timestamps
+}
+
+// Lists the timestamps, in unix seconds, of the readings of each quality other than ok
+func qualityTimestamps(sensorData []domain.SensorData) map[string][]int64 {
+	var timestamps = map[string][]int64{}
+	for _, data := range sensorData {
+		if data.Quality != domain.SENSOR_DATA_QUALITY_OK {
+			timestamps[data.Quality] = append(timestamps[data.Quality], data.Timestamp.Unix())
+		}
+	}
+	return timestamps
+}
+
+func (h *SensorDataHandlerImpl) ReadSensorDataGaps(c *gin.Context) {
+	var req SensorDataGapsRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.From.After(req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' timestamp must be before 'to' timestamp"})
+		return
+	}
+
+	if req.Interval != "" {
+		if _, err := usecase.ParseInterval(req.Interval); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	if err = h.Service.CheckSensorsVisible(c.Request.Context(), userUuid, req.SensorUuid); err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	completeness, err := h.Service.GetSensorDataGaps(c.Request.Context(), req.SensorUuid, req.From, req.To, req.Interval)
+	if err != nil {
+		var status = http.StatusInternalServerError
+		if errors.Is(err, domain.ErrSensorNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, domain.ErrNoReportingInterval) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	var gaps = []gin.H{}
+	for _, gap := range completeness.Gaps {
+		gaps = append(gaps, gin.H{
+			"start":    gap.Start,
+			"end":      gap.End,
+			"duration": int64(gap.End.Sub(gap.Start) / time.Second),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"interval": int64(completeness.Interval / time.Second),
+		"gaps":     gaps,
+		"coverage": completeness.Coverage,
+	})
+}
+
+// Converts sensor data to [timestamp, value] pairs, with the timestamp in unix seconds
+func toDataPairs(sensorData []domain.SensorData) [][]float64 {
 	var responseData [][]float64
 	for _, data := range sensorData {
 		responseData = append(responseData, []float64{
@@ -115,6 +614,327 @@ func (h *SensorDataHandlerImpl) ReadSensorData(c *gin.Context) {
 			data.Value,
 		})
 	}
+	return responseData
+}
+
+func (h *SensorDataHandlerImpl) ExportSensorData(c *gin.Context) {
+	var req SensorDataExportRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.From.After(req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' timestamp must be before 'to' timestamp"})
+		return
+	}
+
+	var format = strings.ToLower(req.Format)
+	if format == "" {
+		// Choose the format from the Accept header, CSV by default
+		if strings.Contains(c.GetHeader("Accept"), "ndjson") {
+			format = domain.SENSOR_DATA_EXPORT_NDJSON
+		} else {
+			format = domain.SENSOR_DATA_EXPORT_CSV
+		}
+	}
+
+	if format != domain.SENSOR_DATA_EXPORT_CSV && format != domain.SENSOR_DATA_EXPORT_NDJSON {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	if err = h.Service.CheckSensorsVisible(c.Request.Context(), userUuid, req.SensorUuid); err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	metadata, err := h.Service.GetSensorMetadata(c.Request.Context(), req.SensorUuid)
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var filename = fmt.Sprintf("sensor-%s.%s", req.SensorUuid.String(), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	// From here on the response is streamed, so errors can only be logged
+	if format == domain.SENSOR_DATA_EXPORT_CSV {
+		err = h.exportCSV(c, req, metadata)
+	} else {
+		err = h.exportNDJSON(c, req, metadata)
+	}
+	if err != nil {
+		log.Printf("failed to export sensor data for %s: %v", req.SensorUuid.String(), err)
+	}
+}
+
+// Streams the sensor data as CSV, preceded by a row with the sensor metadata
+func (h *SensorDataHandlerImpl) exportCSV(c *gin.Context, req SensorDataExportRequest, metadata *domain.SensorMetadata) error {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	var writer = csv.NewWriter(c.Writer)
+
+	var header = [][]string{
+		{"#sensor", metadata.SensorUuid.String(), metadata.Name, strconv.Itoa(metadata.Category), metadata.Unit},
+		{"timestamp", "value", "quality"},
+	}
+	if err := writer.WriteAll(header); err != nil {
+		return err
+	}
+
+	var count = 0
+	var err = h.Service.ExportSensorData(c.Request.Context(), req.SensorUuid, req.From, req.To, func(data domain.SensorData) error {
+		var record = []string{
+			data.Timestamp.UTC().Format(time.RFC3339Nano),
+			strconv.FormatFloat(data.Value, 'f', -1, 64),
+			data.Quality,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+		return writer.Error()
+	})
+
+	writer.Flush()
+	if err != nil {
+		return err
+	}
+	return writer.Error()
+}
+
+// Streams the sensor data as newline-delimited JSON, with the sensor metadata in the first line
+func (h *SensorDataHandlerImpl) exportNDJSON(c *gin.Context, req SensorDataExportRequest, metadata *domain.SensorMetadata) error {
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+
+	var encoder = json.NewEncoder(c.Writer)
+
+	if err := encoder.Encode(gin.H{"sensor": metadata}); err != nil {
+		return err
+	}
+
+	var count = 0
+	return h.Service.ExportSensorData(c.Request.Context(), req.SensorUuid, req.From, req.To, func(data domain.SensorData) error {
+		var line = gin.H{
+			"timestamp": data.Timestamp.UTC().Format(time.RFC3339Nano),
+			"value":     data.Value,
+			"quality":   data.Quality,
+		}
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+}
+
+func (h *SensorDataHandlerImpl) ImportSensorData(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a CSV file is required in the 'file' field"})
+		return
+	}
+
+	// Sensor of every row, unless the file has a sensor column
+	var sensorUuid = uuid.NilUUID
+	if sensor := c.PostForm("sensorUuid"); sensor != "" {
+		if sensorUuid, err = uuid.FromString(sensor); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sensorUuid"})
+			return
+		}
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unable to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	report, err := h.Service.ImportSensorData(c.Request.Context(), file, sensorUuid, userUuid, c.PostForm("onConflict"))
+	if err != nil {
+		var status = http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidConflictPolicy) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *SensorDataHandlerImpl) ReadAlignedSensorData(c *gin.Context) {
+	var req SensorDataAlignedRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.From.After(req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'from' timestamp must be before 'to' timestamp"})
+		return
+	}
+
+	if req.Interval == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "'interval' is required"})
+		return
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	if err = h.Service.CheckSensorsVisible(c.Request.Context(), userUuid, req.SensorUuids...); err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	aligned, err := h.Service.GetAlignedSensorData(c.Request.Context(), req.SensorUuids, req.From, req.To, req.Interval, req.Aggregate)
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var timestamps = make([]int64, len(aligned.Timestamps))
+	for i, timestamp := range aligned.Timestamps {
+		timestamps[i] = timestamp.Unix()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timestamps": timestamps,
+		"series":     aligned.Series,
+	})
+}
+
+func (h *SensorDataHandlerImpl) ReadLatestSensorData(c *gin.Context) {
+	var req SensorDataLatestRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.SensorUuids) == 0 || len(req.SensorUuids) > usecase.MaxLatestSensors {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("between 1 and %d sensors are required", usecase.MaxLatestSensors)})
+		return
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	if err = h.Service.CheckSensorsVisible(c.Request.Context(), userUuid, req.SensorUuids...); err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	sensorData, err := h.Service.GetLatestSensorData(c.Request.Context(), req.SensorUuids)
+	if err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var response = []gin.H{}
+	for _, data := range sensorData {
+		response = append(response, gin.H{
+			"sensorUuid": data.SensorUuid,
+			"timestamp":  data.Timestamp,
+			"value":      data.Value,
+			"quality":    data.Quality,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+func (h *SensorDataHandlerImpl) SubscribeSensorData(c *gin.Context) {
+	sensorUuid, err := uuid.FromString(c.Query("sensorUuid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sensorUuid"})
+		return
+	}
+
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"data": responseData})
+	if err = h.Service.CheckSensorsVisible(c.Request.Context(), userUuid, sensorUuid); err != nil {
+		c.JSON(readErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	var subscriber = h.Hub.Subscribe(sensorUuid)
+	defer h.Hub.Unsubscribe(subscriber)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	var heartbeat = time.NewTicker(subscribeHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+
+		case data, ok := <-subscriber.C:
+			if !ok {
+				// Dropped by the hub for not keeping up with the readings
+				if subscriber.Dropped() {
+					c.SSEvent("error", gin.H{"error": "subscriber too slow, reconnect to resume"})
+					c.Writer.Flush()
+				}
+				return
+			}
+
+			c.SSEvent("reading", gin.H{
+				"sensorUuid": data.SensorUuid,
+				"timestamp":  data.Timestamp.Unix(),
+				"value":      data.Value,
+				"quality":    data.Quality,
+			})
+			c.Writer.Flush()
+		}
+	}
 }
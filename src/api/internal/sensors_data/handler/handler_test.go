@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"api/internal/sensors_data/domain"
+	"api/internal/sensors_data/usecase"
+	user_service "api/internal/users/usecase"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Service serving the sensors visible to one user and recording the reads that reach it
+type fakeService struct {
+	usecase.SensorDataService
+	visible map[uuid.UUID]bool
+	reads   int
+}
+
+func (s *fakeService) CheckSensorsVisible(ctx context.Context, userUuid uuid.UUID, sensorUuids ...uuid.UUID) error {
+	for _, sensorUuid := range sensorUuids {
+		if !s.visible[sensorUuid] {
+			return fmt.Errorf("%w: %s", domain.ErrSensorNotFound, sensorUuid.String())
+		}
+	}
+	return nil
+}
+
+func (s *fakeService) GetAlignedSensorData(ctx context.Context, sensorUuids []uuid.UUID, from, to time.Time, interval string, aggregate string) (*domain.AlignedSensorData, error) {
+	s.reads++
+	return &domain.AlignedSensorData{}, nil
+}
+
+func (s *fakeService) GetSensorDataStats(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, groupBy string) ([]domain.SensorDataStats, error) {
+	s.reads++
+	return []domain.SensorDataStats{{}}, nil
+}
+
+func (s *fakeService) GetSensorDataGaps(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval string) (*domain.SensorDataCompleteness, error) {
+	s.reads++
+	return &domain.SensorDataCompleteness{}, nil
+}
+
+// User service resolving every token to the same user
+type fakeUserService struct {
+	user_service.UserService
+	userUuid uuid.UUID
+}
+
+func (s *fakeUserService) GetUserByToken(ctx context.Context, tokenStr string) (uuid.UUID, error) {
+	return s.userUuid, nil
+}
+
+// Sends the JSON body to the handler as an authenticated user
+func serve(handler gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	var recorder = httptest.NewRecorder()
+	var c, _ = gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("token", "token")
+	handler(c)
+	return recorder
+}
+
+func newTestHandler(visible ...uuid.UUID) (*SensorDataHandlerImpl, *fakeService) {
+	var service = &fakeService{visible: map[uuid.UUID]bool{}}
+	for _, sensorUuid := range visible {
+		service.visible[sensorUuid] = true
+	}
+	return &SensorDataHandlerImpl{Service: service, UserService: &fakeUserService{userUuid: uuid.NewV4()}}, service
+}
+
+func TestReadAlignedSensorDataVisibility(t *testing.T) {
+	own, hidden := uuid.NewV4(), uuid.NewV4()
+	h, service := newTestHandler(own)
+	var request = func(sensors ...uuid.UUID) string {
+		var quoted []string
+		for _, sensorUuid := range sensors {
+			quoted = append(quoted, `"`+sensorUuid.String()+`"`)
+		}
+		return fmt.Sprintf(`{"sensorUuids": [%s], "from": "2025-01-01T00:00:00Z", "to": "2025-01-02T00:00:00Z", "interval": "1h"}`,
+			strings.Join(quoted, ","))
+	}
+
+	// A private sensor of another user can't be read next to a visible one
+	if recorder := serve(h.ReadAlignedSensorData, request(own, hidden)); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+	}
+	if service.reads != 0 {
+		t.Fatal("expected the data not to be read")
+	}
+
+	if recorder := serve(h.ReadAlignedSensorData, request(own)); recorder.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestReadSensorDataStatsVisibility(t *testing.T) {
+	own, hidden := uuid.NewV4(), uuid.NewV4()
+	h, service := newTestHandler(own)
+	var request = func(sensorUuid uuid.UUID) string {
+		return fmt.Sprintf(`{"sensorUuid": "%s", "from": "2025-01-01T00:00:00Z", "to": "2025-01-02T00:00:00Z"}`, sensorUuid.String())
+	}
+
+	if recorder := serve(h.ReadSensorDataStats, request(hidden)); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+	}
+	if service.reads != 0 {
+		t.Fatal("expected the data not to be read")
+	}
+
+	if recorder := serve(h.ReadSensorDataStats, request(own)); recorder.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestReadSensorDataGapsVisibility(t *testing.T) {
+	own, hidden := uuid.NewV4(), uuid.NewV4()
+	h, service := newTestHandler(own)
+	var request = func(sensorUuid uuid.UUID) string {
+		return fmt.Sprintf(`{"sensorUuid": "%s", "from": "2025-01-01T00:00:00Z", "to": "2025-01-02T00:00:00Z", "interval": "5m"}`, sensorUuid.String())
+	}
+
+	if recorder := serve(h.ReadSensorDataGaps, request(hidden)); recorder.Code != http.StatusNotFound {
+		t.Fatalf("expected %d, got %d: %s", http.StatusNotFound, recorder.Code, recorder.Body.String())
+	}
+	if service.reads != 0 {
+		t.Fatal("expected the data not to be read")
+	}
+
+	if recorder := serve(h.ReadSensorDataGaps, request(own)); recorder.Code != http.StatusOK {
+		t.Fatalf("expected %d, got %d: %s", http.StatusOK, recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,210 @@
+package mqtt
+
+import (
+	"api/internal/sensors_data/domain"
+	"api/internal/sensors_data/usecase"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Maximum time spent storing the readings of a single message
+const messageTimeout = 30 * time.Second
+
+// Handles a message received on a topic
+type MessageHandler func(topic string, payload []byte)
+
+// Subset of an MQTT client used by the bridge, so it can run against any broker implementation
+type Client interface {
+	// Connects to the broker
+	Connect() error
+	// Subscribes to a topic filter, calling handler for every message received
+	Subscribe(topic string, qos byte, handler MessageHandler) error
+	// Disconnects from the broker
+	Disconnect()
+}
+
+// Reading sent in a message payload
+type reading struct {
+	// ISO 8601 format or unix seconds, defaults to the time the message is received
+	Timestamp json.RawMessage `json:"timestamp"`
+	Value     *float64        `json:"value"`
+	// Optional quality of the reading: ok (default), estimated, suspect or missing
+	Quality string `json:"quality"`
+}
+
+// Message payload, either a single reading or a list of readings
+type payload struct {
+	reading
+	Readings []reading `json:"readings"`
+	// What to do with readings that already exist for the same timestamp: reject (default), ignore or overwrite
+	OnConflict string `json:"onConflict"`
+}
+
+// Bridge subscribes to sensor topics on an MQTT broker and stores the readings received
+// through the SensorDataService, with the same validation as the HTTP ingest
+type Bridge struct {
+	Client  Client
+	Service usecase.SensorDataService
+	// Topic filters, where the first single-level wildcard (+) stands for the sensor uuid
+	Topics []string
+	QoS    byte
+	// Returns the current time, used for readings without timestamp
+	Now func() time.Time
+}
+
+func NewBridge(client Client, service usecase.SensorDataService, topics []string, qos byte) *Bridge {
+	return &Bridge{
+		Client:  client,
+		Service: service,
+		Topics:  topics,
+		QoS:     qos,
+		Now:     func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Connects to the broker and subscribes to every topic
+func (b *Bridge) Start() error {
+	if len(b.Topics) == 0 {
+		return errors.New("at least one topic is required")
+	}
+
+	for _, topic := range b.Topics {
+		if sensorLevel(topic) == -1 {
+			return fmt.Errorf("topic %q has no + wildcard for the sensor uuid", topic)
+		}
+	}
+
+	if err := b.Client.Connect(); err != nil {
+		return fmt.Errorf("failed to connect to broker: %v", err)
+	}
+
+	for _, topic := range b.Topics {
+		var filter = topic
+		var err = b.Client.Subscribe(filter, b.QoS, func(topic string, payload []byte) {
+			if err := b.HandleMessage(filter, topic, payload); err != nil {
+				log.Printf("mqtt: rejected message on %s: %v", topic, err)
+			}
+		})
+		if err != nil {
+			b.Client.Disconnect()
+			return fmt.Errorf("failed to subscribe to %s: %v", filter, err)
+		}
+	}
+
+	return nil
+}
+
+// Disconnects from the broker
+func (b *Bridge) Stop() {
+	b.Client.Disconnect()
+}
+
+// Decodes a message received on topic (matching the subscribed filter) and stores its readings
+func (b *Bridge) HandleMessage(filter string, topic string, message []byte) error {
+	sensorUuid, err := sensorFromTopic(filter, topic)
+	if err != nil {
+		return err
+	}
+
+	sensorData, onConflict, err := b.decode(sensorUuid, message)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), messageTimeout)
+	defer cancel()
+
+	if _, err = b.Service.GetSensorMetadata(ctx, sensorUuid); err != nil {
+		return err
+	}
+
+	_, err = b.Service.AddSensorData(ctx, sensorData, onConflict)
+	return err
+}
+
+// Position of the first single-level wildcard in the topic filter, or -1 if there is none
+func sensorLevel(filter string) int {
+	for i, level := range strings.Split(filter, "/") {
+		if level == "+" {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reads the sensor uuid from the topic level matched by the filter's wildcard
+func sensorFromTopic(filter string, topic string) (uuid.UUID, error) {
+	var levels = strings.Split(topic, "/")
+	var position = sensorLevel(filter)
+	if position == -1 || position >= len(levels) {
+		return uuid.NilUUID, fmt.Errorf("no sensor uuid in topic %q", topic)
+	}
+
+	sensorUuid, err := uuid.FromString(levels[position])
+	if err != nil {
+		return uuid.NilUUID, fmt.Errorf("invalid sensor uuid in topic %q", topic)
+	}
+	return sensorUuid, nil
+}
+
+// Decodes the payload, which can be a plain number, a single reading or a list of readings
+func (b *Bridge) decode(sensorUuid uuid.UUID, message []byte) ([]*domain.SensorData, string, error) {
+	var text = strings.TrimSpace(string(message))
+
+	// Plain number: a single reading taken now
+	if value, err := strconv.ParseFloat(text, 64); err == nil {
+		return []*domain.SensorData{{SensorUuid: sensorUuid, Timestamp: b.Now(), Value: value}}, "", nil
+	}
+
+	var decoded payload
+	if err := json.Unmarshal([]byte(text), &decoded); err != nil {
+		return nil, "", fmt.Errorf("invalid payload: %v", err)
+	}
+
+	var readings = decoded.Readings
+	if len(readings) == 0 {
+		readings = []reading{decoded.reading}
+	}
+
+	var sensorData []*domain.SensorData
+	for _, reading := range readings {
+		if reading.Value == nil {
+			return nil, "", errors.New("value is required")
+		}
+
+		timestamp, err := b.parseTimestamp(reading.Timestamp)
+		if err != nil {
+			return nil, "", err
+		}
+
+		sensorData = append(sensorData, &domain.SensorData{SensorUuid: sensorUuid, Timestamp: timestamp, Value: *reading.Value, Quality: reading.Quality})
+	}
+
+	return sensorData, decoded.OnConflict, nil
+}
+
+// Parses an ISO 8601 or unix seconds timestamp, defaulting to now when absent
+func (b *Bridge) parseTimestamp(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return b.Now(), nil
+	}
+
+	var seconds float64
+	if err := json.Unmarshal(raw, &seconds); err == nil {
+		return time.Unix(0, int64(seconds*1e9)).UTC(), nil
+	}
+
+	var timestamp time.Time
+	if err := json.Unmarshal(raw, &timestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %s", raw)
+	}
+	return timestamp, nil
+}
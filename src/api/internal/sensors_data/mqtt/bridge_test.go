@@ -0,0 +1,160 @@
+package mqtt
+
+import (
+	"api/internal/sensors_data/domain"
+	"api/internal/sensors_data/usecase"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// In-memory broker that delivers published messages to the matching subscriptions synchronously
+type memoryBroker struct {
+	mu            sync.Mutex
+	connected     bool
+	subscriptions map[string]MessageHandler
+}
+
+func newMemoryBroker() *memoryBroker {
+	return &memoryBroker{subscriptions: map[string]MessageHandler{}}
+}
+
+func (b *memoryBroker) Connect() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.connected = true
+	return nil
+}
+
+func (b *memoryBroker) Subscribe(topic string, qos byte, handler MessageHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.connected {
+		return errors.New("not connected")
+	}
+	b.subscriptions[topic] = handler
+	return nil
+}
+
+func (b *memoryBroker) Disconnect() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.connected = false
+	b.subscriptions = map[string]MessageHandler{}
+}
+
+// Delivers the payload to every subscription whose filter matches the topic
+func (b *memoryBroker) Publish(topic string, payload string) {
+	b.mu.Lock()
+	var handlers []MessageHandler
+	for filter, handler := range b.subscriptions {
+		if matches(filter, topic) {
+			handlers = append(handlers, handler)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(topic, []byte(payload))
+	}
+}
+
+// Checks a topic against a filter with + and # wildcards
+func matches(filter string, topic string) bool {
+	var filterLevels, topicLevels = strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, level := range filterLevels {
+		if level == "#" {
+			return true
+		}
+		if i >= len(topicLevels) || (level != "+" && level != topicLevels[i]) {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
+
+// Service that records the readings added, for the sensors it knows
+type fakeService struct {
+	usecase.SensorDataService
+	sensors map[uuid.UUID]bool
+	added   []*domain.SensorData
+}
+
+func (s *fakeService) GetSensorMetadata(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorMetadata, error) {
+	if !s.sensors[sensorUuid] {
+		return nil, domain.ErrSensorNotFound
+	}
+	return &domain.SensorMetadata{SensorUuid: sensorUuid}, nil
+}
+
+func (s *fakeService) AddSensorData(ctx context.Context, sensorData []*domain.SensorData, onConflict string) (int, error) {
+	s.added = append(s.added, sensorData...)
+	return len(sensorData), nil
+}
+
+// Test that messages published on sensor topics are decoded and stored
+func TestBridgeIngestsMessages(t *testing.T) {
+	var sensor = uuid.NewV4()
+	var unknown = uuid.NewV4()
+	var now = time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		topic     string
+		payload   string
+		expectLen int
+		expectAt  time.Time
+	}{
+		{"plain number", "uno/" + sensor.String() + "/data", "21.5", 1, now},
+		{"single reading", "uno/" + sensor.String() + "/data", `{"timestamp": "2025-03-01T10:00:00Z", "value": 20}`, 1, now.Add(-2 * time.Hour)},
+		{"unix timestamp", "uno/" + sensor.String() + "/data", `{"timestamp": 1740823200, "value": 20}`, 1, now.Add(-2 * time.Hour)},
+		{"list of readings", "uno/" + sensor.String() + "/data", `{"readings": [{"timestamp": "2025-03-01T10:00:00Z", "value": 1}, {"timestamp": "2025-03-01T10:01:00Z", "value": 2}]}`, 2, now.Add(-2 * time.Hour)},
+		{"unknown sensor", "uno/" + unknown.String() + "/data", "1", 0, time.Time{}},
+		{"invalid uuid", "uno/not-a-uuid/data", "1", 0, time.Time{}},
+		{"invalid payload", "uno/" + sensor.String() + "/data", "{not json", 0, time.Time{}},
+		{"missing value", "uno/" + sensor.String() + "/data", `{"timestamp": "2025-03-01T10:00:00Z"}`, 0, time.Time{}},
+		{"other topic", "uno/" + sensor.String() + "/status", "1", 0, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var broker = newMemoryBroker()
+			var service = &fakeService{sensors: map[uuid.UUID]bool{sensor: true}}
+
+			var bridge = NewBridge(broker, service, []string{"uno/+/data"}, 1)
+			bridge.Now = func() time.Time { return now }
+
+			if err := bridge.Start(); err != nil {
+				t.Fatalf("failed to start bridge: %v", err)
+			}
+			defer bridge.Stop()
+
+			broker.Publish(tt.topic, tt.payload)
+
+			if len(service.added) != tt.expectLen {
+				t.Fatalf("expected %d readings, got %d", tt.expectLen, len(service.added))
+			}
+			if tt.expectLen > 0 {
+				if service.added[0].SensorUuid != sensor {
+					t.Errorf("expected sensor %s, got %s", sensor.String(), service.added[0].SensorUuid.String())
+				}
+				if !service.added[0].Timestamp.Equal(tt.expectAt) {
+					t.Errorf("expected timestamp %v, got %v", tt.expectAt, service.added[0].Timestamp)
+				}
+			}
+		})
+	}
+}
+
+// Test that topics without a sensor wildcard are refused
+func TestBridgeRequiresSensorWildcard(t *testing.T) {
+	var bridge = NewBridge(newMemoryBroker(), &fakeService{}, []string{"uno/data"}, 0)
+	if err := bridge.Start(); err == nil {
+		t.Errorf("expected an error for a topic without wildcard")
+	}
+}
@@ -0,0 +1,52 @@
+package mqtt
+
+import (
+	"fmt"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Time allowed for connecting and subscribing to the broker
+const brokerTimeout = 10 * time.Second
+
+// Client backed by the Eclipse Paho MQTT library
+type PahoClient struct {
+	client paho.Client
+}
+
+func NewPahoClient(broker, clientID, username, password string) *PahoClient {
+	var options = paho.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(clientID).
+		SetUsername(username).
+		SetPassword(password).
+		SetAutoReconnect(true).
+		// Subscriptions are kept by the broker across reconnections
+		SetCleanSession(false).
+		SetOrderMatters(false)
+
+	return &PahoClient{client: paho.NewClient(options)}
+}
+
+func (c *PahoClient) Connect() error {
+	var token = c.client.Connect()
+	if !token.WaitTimeout(brokerTimeout) {
+		return fmt.Errorf("timed out connecting to broker")
+	}
+	return token.Error()
+}
+
+func (c *PahoClient) Subscribe(topic string, qos byte, handler MessageHandler) error {
+	var token = c.client.Subscribe(topic, qos, func(_ paho.Client, message paho.Message) {
+		handler(message.Topic(), message.Payload())
+	})
+	if !token.WaitTimeout(brokerTimeout) {
+		return fmt.Errorf("timed out subscribing to %s", topic)
+	}
+	return token.Error()
+}
+
+func (c *PahoClient) Disconnect() {
+	c.client.Disconnect(250)
+}
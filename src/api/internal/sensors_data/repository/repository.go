@@ -5,10 +5,13 @@ import (
 	"api/internal/sensors_data/domain"
 	"context"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"database/sql"
 
+	mssql "github.com/denisenkom/go-mssqldb"
 	uuid "github.com/tentone/mssql-uuid"
 )
 
@@ -16,8 +19,64 @@ import (
 type SensorDataRepository interface {
 	// Retrieves sensor data within a specific time interval.
 	GetSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time) ([]domain.SensorData, error)
-	// Add sensor data
-	AddSensorData(ctx context.Context, sensorData []*domain.SensorData) error
+	// Retrieves sensor data grouped in buckets of the given size, one aggregated value per bucket
+	GetAggregatedSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, bucket time.Duration, aggregate string) ([]domain.SensorData, error)
+	// Retrieves the statistics of the readings within a time interval, as a single period starting at from
+	// or grouped by day or week (periods without readings are left out)
+	GetSensorDataStats(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, groupBy string) ([]domain.SensorDataStats, error)
+	// Retrieves up to limit readings after the cursor (or from the start when nil), and the cursor for the next page
+	GetSensorDataPage(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, cursor *domain.SensorDataCursor, limit int) ([]domain.SensorData, *domain.SensorDataCursor, error)
+	// Reads sensor data within a time interval row by row, calling fn for each reading without buffering them
+	StreamSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, fn func(data domain.SensorData) error) error
+	// Retrieves the ranges within a time interval without readings (other than missing ones) for longer than the interval
+	GetSensorDataGaps(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval time.Duration) ([]domain.SensorDataGap, error)
+	// Retrieves the name, category, unit, precision and reporting interval of a sensor
+	GetSensorMetadata(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorMetadata, error)
+	// Retrieves the value range of the category of each sensor (unknown sensors are left out)
+	GetSensorValueRanges(ctx context.Context, sensorUuids []uuid.UUID) (map[uuid.UUID]domain.ValueRange, error)
+	// Retrieves the most recent reading of each sensor, skipping missing placeholders (sensors without data are left out)
+	GetLatestSensorData(ctx context.Context, sensorUuids []uuid.UUID) ([]domain.SensorData, error)
+	// Retrieves which of the sensors the user can read, the public ones and their own (unknown sensors are left out)
+	GetVisibleSensors(ctx context.Context, sensorUuids []uuid.UUID, userUuid uuid.UUID) (map[uuid.UUID]bool, error)
+	// Retrieves the uuid of the user who owns the sensor
+	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error)
+	// Add sensor data, applying the conflict policy (reject, ignore or overwrite) to readings that already exist.
+	// Returns the readings actually inserted or overwritten, without the ignored ones.
+	AddSensorData(ctx context.Context, sensorData []*domain.SensorData, onConflict string) ([]*domain.SensorData, error)
+}
+
+// Raw readings together with the rollups of the readings compacted by the retention job, so that
+// reads older than the raw retention window transparently return one averaged value per bucket.
+// Aggregates combine the count, sum, min and max of each row, a raw reading counting as a rollup of itself.
+const sensorDataSource = `(
+		SELECT sensorUuid, timestamp, value, anomaly, quality,
+			1 AS valueCount, value AS valueSum, value AS minValue, value AS maxValue, CAST(0 AS BIT) AS rolledUp
+		FROM SensorData
+		UNION ALL
+		SELECT sensorUuid, bucketStart, valueSum / valueCount, CAST(0 AS BIT), CAST('ok' AS NVARCHAR(10)),
+			valueCount, valueSum, minValue, maxValue, CAST(1 AS BIT)
+		FROM SensorDataRollup
+	) AS SensorData`
+
+// Rollups hold the readings of a whole hour (see the retention job)
+const rollupBucketSeconds = 3600
+
+// SQL expression used by each aggregate function, computed over the "buckets" CTE.
+// Rollups are weighted by the readings they stand for, the first and last values of a rollup are its average.
+var aggregateExpressions = map[string]string{
+	domain.SENSOR_DATA_AGGREGATE_MIN:   "MIN(minValue)",
+	domain.SENSOR_DATA_AGGREGATE_MAX:   "MAX(maxValue)",
+	domain.SENSOR_DATA_AGGREGATE_AVG:   "SUM(valueSum) / SUM(valueCount)",
+	domain.SENSOR_DATA_AGGREGATE_SUM:   "SUM(valueSum)",
+	domain.SENSOR_DATA_AGGREGATE_COUNT: "CAST(SUM(valueCount) AS FLOAT)",
+	domain.SENSOR_DATA_AGGREGATE_FIRST: "MIN(firstValue)",
+	domain.SENSOR_DATA_AGGREGATE_LAST:  "MIN(lastValue)",
+}
+
+// SQL expression of the date the given number of seconds after the unix epoch. DATEADD only takes an INT,
+// which overflows for seconds past 2038, so whole days are added first and the remaining seconds after.
+func epochSecondsToDatetime(seconds string) string {
+	return fmt.Sprintf("DATEADD(SECOND, CAST(%[1]s %% 86400 AS INT), DATEADD(DAY, CAST(%[1]s / 86400 AS INT), CAST('1970-01-01' AS DATETIME2)))", seconds)
 }
 
 // Performs sensors's data operations using database/sql to interact with the database
@@ -34,53 +93,164 @@ func NewSensorDataRepository() (SensorDataRepository, error) {
 	return &SensorDataRepositoryImpl{DB: db}, nil
 }
 
-func (r *SensorDataRepositoryImpl) AddSensorData(ctx context.Context, sensorData []*domain.SensorData) error {
+func (r *SensorDataRepositoryImpl) AddSensorData(ctx context.Context, sensorData []*domain.SensorData, onConflict string) ([]*domain.SensorData, error) {
+
+	if len(sensorData) == 0 {
+		return nil, nil
+	}
 
 	// Start a transaction
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %v", err)
+		return nil, fmt.Errorf("failed to begin transaction: %v", err)
 	}
+	defer tx.Rollback()
 
+	// Readings are bulk copied to a staging table and then merged into SensorData.
+	// The staging table belongs to the transaction's session and is dropped before committing
+	// (a rollback also discards it).
 	query := `
-		INSERT INTO SensorData (sensorUuid, timestamp, value)
-		VALUES (@sensorUuid, @timestamp, @value)
+		DROP TABLE IF EXISTS #SensorDataStaging;
+		SELECT TOP 0 sensorUuid, timestamp, value, anomaly, quality, CAST(0 AS INT) AS ordinal
+		INTO #SensorDataStaging
+		FROM SensorData;
 	`
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return nil, fmt.Errorf("failed to create staging table: %v", err)
+	}
 
-	stmt, err := tx.PrepareContext(ctx, query)
+	// Readings are sent with the bulk copy protocol instead of one INSERT per reading
+	stmt, err := tx.PrepareContext(ctx, mssql.CopyIn("#SensorDataStaging", mssql.BulkOptions{}, "sensorUuid", "timestamp", "value", "anomaly", "quality", "ordinal"))
 	if err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to prepare statement: %v", err)
+		return nil, fmt.Errorf("failed to prepare bulk copy: %v", err)
 	}
 	defer stmt.Close()
 
-	for _, sensorData := range sensorData {
+	for i, sensorData := range sensorData {
 		_, err := stmt.ExecContext(ctx,
-			sql.Named("sensorUuid", sensorData.SensorUuid),
-			sql.Named("timestamp", sensorData.Timestamp),
-			sql.Named("value", sensorData.Value),
+			// The bulk copy needs the uuid in SQL Server's byte order
+			mssql.UniqueIdentifier(sensorData.SensorUuid),
+			sensorData.Timestamp,
+			sensorData.Value,
+			sensorData.Anomaly,
+			sensorData.Quality,
+			i,
 		)
 		if err != nil {
-			tx.Rollback()
-			return fmt.Errorf("failed to insert sensor data: %v", err)
+			return nil, fmt.Errorf("failed to queue sensor data: %v", err)
+		}
+	}
+
+	// Flushes the queued rows to the server
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to insert sensor data: %v", err)
+	}
+
+	// Readings repeated within the request conflict with each other too
+	if onConflict == domain.SENSOR_DATA_CONFLICT_REJECT {
+		query = `
+			SELECT
+				(SELECT COUNT(*)
+				FROM #SensorDataStaging AS source
+				INNER JOIN SensorData AS target
+				ON target.sensorUuid = source.sensorUuid AND target.timestamp = source.timestamp)
+				+
+				(SELECT COUNT(*)
+				FROM (
+					SELECT sensorUuid
+					FROM #SensorDataStaging
+					GROUP BY sensorUuid, timestamp
+					HAVING COUNT(*) > 1
+				) AS repeated)
+		`
+
+		var conflicts int
+		if err = tx.QueryRowContext(ctx, query).Scan(&conflicts); err != nil {
+			return nil, fmt.Errorf("failed to check existing sensor data: %v", err)
+		}
+		if conflicts > 0 {
+			return nil, fmt.Errorf("%w (%d conflicting readings)", domain.ErrDuplicateSensorData, conflicts)
 		}
 	}
 
+	// Only overwrite updates existing readings; reject has already failed and ignore leaves them untouched
+	var whenMatched string
+	if onConflict == domain.SENSOR_DATA_CONFLICT_OVERWRITE {
+		whenMatched = "WHEN MATCHED THEN UPDATE SET target.value = source.value, target.anomaly = source.anomaly, target.quality = source.quality"
+	}
+
+	// Readings repeated within the same request keep the last one sent, reject has already failed on them.
+	// The position of each reading inserted or overwritten is returned, so that the ignored ones can be told apart.
+	query = fmt.Sprintf(`
+		MERGE SensorData AS target
+		USING (
+			SELECT sensorUuid, timestamp, value, anomaly, quality, ordinal
+			FROM (
+				SELECT
+					sensorUuid,
+					timestamp,
+					value,
+					anomaly,
+					quality,
+					ordinal,
+					ROW_NUMBER() OVER (PARTITION BY sensorUuid, timestamp ORDER BY ordinal DESC) AS occurrence
+				FROM #SensorDataStaging
+			) AS staged
+			WHERE occurrence = 1
+		) AS source
+		ON target.sensorUuid = source.sensorUuid AND target.timestamp = source.timestamp
+		%s
+		WHEN NOT MATCHED BY TARGET THEN
+			INSERT (sensorUuid, timestamp, value, anomaly, quality)
+			VALUES (source.sensorUuid, source.timestamp, source.value, source.anomaly, source.quality)
+		OUTPUT source.ordinal;
+	`, whenMatched)
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge sensor data: %v", err)
+	}
+
+	var ordinals []int
+	for rows.Next() {
+		var ordinal int
+		if err := rows.Scan(&ordinal); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan merged sensor data: %v", err)
+		}
+		ordinals = append(ordinals, ordinal)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to merge sensor data: %v", err)
+	}
+
+	// The output comes in no particular order, the readings are returned in the order they were sent
+	sort.Ints(ordinals)
+	var stored = make([]*domain.SensorData, len(ordinals))
+	for i, ordinal := range ordinals {
+		stored[i] = sensorData[ordinal]
+	}
+
+	if _, err = tx.ExecContext(ctx, `DROP TABLE #SensorDataStaging`); err != nil {
+		return nil, fmt.Errorf("failed to drop staging table: %v", err)
+	}
+
 	// Commit transaction
 	err = tx.Commit()
 	if err != nil {
-		return fmt.Errorf("failed to commit transaction: %v", err)
+		return nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
-	return nil
+	return stored, nil
 
 }
 
 func (s *SensorDataRepositoryImpl) GetSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time) ([]domain.SensorData, error) {
 
 	query := `
-		SELECT timestamp, value
-		FROM SensorData
+		SELECT timestamp, value, anomaly, quality
+		FROM ` + sensorDataSource + `
 		WHERE sensorUuid = @sensorUuid
 		AND timestamp BETWEEN @from AND @to
 		ORDER BY timestamp
@@ -95,7 +265,521 @@ func (s *SensorDataRepositoryImpl) GetSensorData(ctx context.Context, sensorUuid
 	var sensorData []domain.SensorData
 	for rows.Next() {
 		var data domain.SensorData
+		if err := rows.Scan(&data.Timestamp, &data.Value, &data.Anomaly, &data.Quality); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor data: %v", err)
+		}
+		sensorData = append(sensorData, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return sensorData, nil
+}
+
+func (s *SensorDataRepositoryImpl) GetAggregatedSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, bucket time.Duration, aggregate string) ([]domain.SensorData, error) {
+
+	expression, ok := aggregateExpressions[aggregate]
+	if !ok {
+		return nil, fmt.Errorf("unsupported aggregate function: %s", aggregate)
+	}
+
+	bucketSeconds := int64(bucket / time.Second)
+	if bucketSeconds <= 0 {
+		return nil, fmt.Errorf("bucket size must be at least one second")
+	}
+
+	// Buckets are aligned to the unix epoch, so every bucket starts at a multiple of its size.
+	// Missing readings are placeholders and are left out of the aggregates.
+	query := fmt.Sprintf(`
+		WITH buckets AS (
+			SELECT
+				%s AS bucket,
+				timestamp,
+				value,
+				valueCount,
+				valueSum,
+				minValue,
+				maxValue
+			FROM %s
+			CROSS APPLY (SELECT DATEDIFF_BIG(SECOND, '1970-01-01', timestamp) / @bucket * @bucket AS bucketSeconds) AS aligned
+			WHERE sensorUuid = @sensorUuid
+			AND timestamp BETWEEN @from AND @to
+			AND quality <> 'missing'
+		),
+		ranked AS (
+			SELECT
+				bucket,
+				valueCount,
+				valueSum,
+				minValue,
+				maxValue,
+				FIRST_VALUE(value) OVER (PARTITION BY bucket ORDER BY timestamp ASC) AS firstValue,
+				FIRST_VALUE(value) OVER (PARTITION BY bucket ORDER BY timestamp DESC) AS lastValue
+			FROM buckets
+		)
+		SELECT bucket, %s
+		FROM ranked
+		GROUP BY bucket
+		ORDER BY bucket
+	`, epochSecondsToDatetime("bucketSeconds"), sensorDataSource, expression)
+
+	rows, err := s.DB.QueryContext(ctx, query,
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("from", from),
+		sql.Named("to", to),
+		sql.Named("bucket", bucketSeconds),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch aggregated sensor data: %v", err)
+	}
+	defer rows.Close()
+
+	var sensorData []domain.SensorData
+	for rows.Next() {
+		var data = domain.SensorData{SensorUuid: sensorUuid}
 		if err := rows.Scan(&data.Timestamp, &data.Value); err != nil {
+			return nil, fmt.Errorf("failed to scan aggregated sensor data: %v", err)
+		}
+		sensorData = append(sensorData, data)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return sensorData, nil
+}
+
+// SQL expression of the period each reading belongs to when grouping statistics.
+// The 1st of January 1900 was a Monday, so whole weeks counted from it start on Mondays.
+var statsGroupExpressions = map[string]string{
+	"":                            "CAST(@from AS DATETIME2)",
+	domain.SENSOR_DATA_GROUP_DAY:  "DATEADD(DAY, DATEDIFF(DAY, '1900-01-01', timestamp), CAST('1900-01-01' AS DATETIME2))",
+	domain.SENSOR_DATA_GROUP_WEEK: "DATEADD(DAY, DATEDIFF(DAY, '1900-01-01', timestamp) / 7 * 7, CAST('1900-01-01' AS DATETIME2))",
+}
+
+func (s *SensorDataRepositoryImpl) GetSensorDataStats(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, groupBy string) ([]domain.SensorDataStats, error) {
+
+	expression, ok := statsGroupExpressions[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported stats grouping: %s", groupBy)
+	}
+
+	// PERCENTILE_CONT is only available as a window function, so the percentiles of each
+	// period are computed apart and joined to the aggregates. Missing readings are left out.
+	// The count, min, max and mean combine the rollups, but their readings are gone, so the
+	// standard deviation and percentiles of a period with rollups can't be computed and are NULL.
+	query := fmt.Sprintf(`
+		WITH readings AS (
+			SELECT %s AS period, value, valueCount, valueSum, minValue, maxValue, rolledUp
+			FROM %s
+			WHERE sensorUuid = @sensorUuid
+			AND timestamp BETWEEN @from AND @to
+			AND quality <> 'missing'
+		),
+		percentiles AS (
+			SELECT DISTINCT
+				period,
+				PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY value) OVER (PARTITION BY period) AS p50,
+				PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY value) OVER (PARTITION BY period) AS p95,
+				PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY value) OVER (PARTITION BY period) AS p99
+			FROM readings
+		)
+		SELECT
+			readings.period,
+			SUM(CAST(readings.valueCount AS BIGINT)),
+			MIN(readings.minValue),
+			MAX(readings.maxValue),
+			SUM(readings.valueSum) / SUM(readings.valueCount),
+			CASE WHEN MAX(CAST(readings.rolledUp AS INT)) = 0 THEN STDEV(readings.value) END,
+			CASE WHEN MAX(CAST(readings.rolledUp AS INT)) = 0 THEN percentiles.p50 END,
+			CASE WHEN MAX(CAST(readings.rolledUp AS INT)) = 0 THEN percentiles.p95 END,
+			CASE WHEN MAX(CAST(readings.rolledUp AS INT)) = 0 THEN percentiles.p99 END
+		FROM readings
+		INNER JOIN percentiles ON percentiles.period = readings.period
+		GROUP BY readings.period, percentiles.p50, percentiles.p95, percentiles.p99
+		ORDER BY readings.period
+	`, expression, sensorDataSource)
+
+	rows, err := s.DB.QueryContext(ctx, query,
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("from", from),
+		sql.Named("to", to),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sensor data stats: %v", err)
+	}
+	defer rows.Close()
+
+	var stats []domain.SensorDataStats
+	for rows.Next() {
+		var period domain.SensorDataStats
+		var minValue, maxValue, mean, stdDev, p50, p95, p99 sql.NullFloat64
+		if err := rows.Scan(&period.Start, &period.Count, &minValue, &maxValue, &mean, &stdDev, &p50, &p95, &p99); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor data stats: %v", err)
+		}
+
+		period.Min = nullableFloat(minValue)
+		period.Max = nullableFloat(maxValue)
+		period.Mean = nullableFloat(mean)
+		period.StdDev = nullableFloat(stdDev)
+		period.P50 = nullableFloat(p50)
+		period.P95 = nullableFloat(p95)
+		period.P99 = nullableFloat(p99)
+		stats = append(stats, period)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return stats, nil
+}
+
+// Returns a pointer to the value, or nil when it's NULL
+func nullableFloat(value sql.NullFloat64) *float64 {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Float64
+}
+
+func (s *SensorDataRepositoryImpl) GetSensorDataPage(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, cursor *domain.SensorDataCursor, limit int) ([]domain.SensorData, *domain.SensorDataCursor, error) {
+
+	var cursorTimestamp = from
+	var cursorRank int64
+	if cursor != nil {
+		cursorTimestamp = cursor.Timestamp
+		cursorRank = cursor.Rank
+	}
+
+	// Readings sharing a timestamp are ranked by value, so (timestamp, rank) identifies a position in the series.
+	// One extra row is fetched to know whether there is a next page.
+	query := `
+		SELECT TOP (@limit) timestamp, value, anomaly, quality, tieRank
+		FROM (
+			SELECT
+				timestamp,
+				value,
+				anomaly,
+				quality,
+				ROW_NUMBER() OVER (PARTITION BY timestamp ORDER BY value) AS tieRank
+			FROM ` + sensorDataSource + `
+			WHERE sensorUuid = @sensorUuid
+			AND timestamp BETWEEN @from AND @to
+			AND timestamp >= @cursorTimestamp
+		) AS ranked
+		WHERE timestamp > @cursorTimestamp OR tieRank > @cursorRank
+		ORDER BY timestamp, tieRank
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query,
+		sql.Named("limit", limit+1),
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("from", from),
+		sql.Named("to", to),
+		sql.Named("cursorTimestamp", cursorTimestamp),
+		sql.Named("cursorRank", cursorRank),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch sensor data page: %v", err)
+	}
+	defer rows.Close()
+
+	var sensorData []domain.SensorData
+	var ranks []int64
+	for rows.Next() {
+		var data = domain.SensorData{SensorUuid: sensorUuid}
+		var rank int64
+		if err := rows.Scan(&data.Timestamp, &data.Value, &data.Anomaly, &data.Quality, &rank); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan sensor data: %v", err)
+		}
+		sensorData = append(sensorData, data)
+		ranks = append(ranks, rank)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	// Last page
+	if len(sensorData) <= limit {
+		return sensorData, nil, nil
+	}
+
+	var next = &domain.SensorDataCursor{
+		Timestamp: sensorData[limit-1].Timestamp,
+		Rank:      ranks[limit-1],
+	}
+
+	return sensorData[:limit], next, nil
+}
+
+func (s *SensorDataRepositoryImpl) StreamSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, fn func(data domain.SensorData) error) error {
+
+	query := `
+		SELECT timestamp, value, quality
+		FROM ` + sensorDataSource + `
+		WHERE sensorUuid = @sensorUuid
+		AND timestamp BETWEEN @from AND @to
+		ORDER BY timestamp
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query, sql.Named("sensorUuid", sensorUuid), sql.Named("from", from), sql.Named("to", to))
+	if err != nil {
+		return fmt.Errorf("failed to fetch sensor data: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data = domain.SensorData{SensorUuid: sensorUuid}
+		if err := rows.Scan(&data.Timestamp, &data.Value, &data.Quality); err != nil {
+			return fmt.Errorf("failed to scan sensor data: %v", err)
+		}
+		if err := fn(data); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return nil
+}
+
+func (s *SensorDataRepositoryImpl) GetSensorDataGaps(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval time.Duration) ([]domain.SensorDataGap, error) {
+
+	// The bounds of the time range are points too, so that a range starting or ending without data
+	// has a gap. A rollup stands for readings over its whole hour, so it covers both ends of the hour.
+	query := `
+		WITH points AS (
+			SELECT timestamp
+			FROM SensorData
+			WHERE sensorUuid = @sensorUuid
+			AND timestamp BETWEEN @from AND @to
+			AND quality <> 'missing'
+			UNION ALL
+			SELECT bucketEdge
+			FROM SensorDataRollup
+			CROSS APPLY (VALUES (bucketStart), (DATEADD(SECOND, @rollupBucket, bucketStart))) AS edges(bucketEdge)
+			WHERE sensorUuid = @sensorUuid
+			AND bucketEdge BETWEEN @from AND @to
+			UNION ALL
+			SELECT CAST(@from AS DATETIME2)
+			UNION ALL
+			SELECT CAST(@to AS DATETIME2)
+		),
+		steps AS (
+			SELECT LAG(timestamp) OVER (ORDER BY timestamp) AS previous, timestamp
+			FROM points
+		)
+		SELECT previous, timestamp
+		FROM steps
+		WHERE previous IS NOT NULL
+		AND DATEDIFF_BIG(MILLISECOND, previous, timestamp) > @interval
+		ORDER BY previous
+	`
+
+	rows, err := s.DB.QueryContext(ctx, query,
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("from", from),
+		sql.Named("to", to),
+		sql.Named("rollupBucket", rollupBucketSeconds),
+		sql.Named("interval", interval.Milliseconds()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sensor data gaps: %v", err)
+	}
+	defer rows.Close()
+
+	var gaps []domain.SensorDataGap
+	for rows.Next() {
+		var gap domain.SensorDataGap
+		if err := rows.Scan(&gap.Start, &gap.End); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor data gap: %v", err)
+		}
+		gaps = append(gaps, gap)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return gaps, nil
+}
+
+func (s *SensorDataRepositoryImpl) GetSensorMetadata(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorMetadata, error) {
+
+	query := `
+		SELECT Sensors.name, Sensors.category, sensor_categories.unit, sensor_categories.decimalPrecision, Sensors.reportingInterval
+		FROM Sensors
+		INNER JOIN sensor_categories ON sensor_categories.id = Sensors.category
+		WHERE Sensors.uuid = @sensorUuid
+	`
+
+	var metadata = domain.SensorMetadata{SensorUuid: sensorUuid}
+	err := s.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&metadata.Name, &metadata.Category, &metadata.Unit, &metadata.Precision, &metadata.ReportingInterval)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, domain.ErrSensorNotFound
+		}
+		return nil, fmt.Errorf("failed to retrieve sensor: %v", err)
+	}
+
+	return &metadata, nil
+}
+
+func (s *SensorDataRepositoryImpl) GetSensorValueRanges(ctx context.Context, sensorUuids []uuid.UUID) (map[uuid.UUID]domain.ValueRange, error) {
+
+	var ranges = make(map[uuid.UUID]domain.ValueRange)
+	if len(sensorUuids) == 0 {
+		return ranges, nil
+	}
+
+	// One named parameter per sensor
+	var names []string
+	var args []any
+	for i, sensorUuid := range sensorUuids {
+		var name = fmt.Sprintf("sensor%d", i)
+		names = append(names, "@"+name)
+		args = append(args, sql.Named(name, sensorUuid))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT Sensors.uuid, sensor_categories.minValue, sensor_categories.maxValue
+		FROM Sensors
+		INNER JOIN sensor_categories ON sensor_categories.id = Sensors.category
+		WHERE Sensors.uuid IN (%s)
+	`, strings.Join(names, ", "))
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sensor value ranges: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sensorUuid uuid.UUID
+		var minValue, maxValue sql.NullFloat64
+		if err := rows.Scan(&sensorUuid, &minValue, &maxValue); err != nil {
+			return nil, fmt.Errorf("failed to scan sensor value range: %v", err)
+		}
+
+		ranges[sensorUuid] = domain.ValueRange{Min: nullableFloat(minValue), Max: nullableFloat(maxValue)}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return ranges, nil
+}
+
+func (s *SensorDataRepositoryImpl) GetVisibleSensors(ctx context.Context, sensorUuids []uuid.UUID, userUuid uuid.UUID) (map[uuid.UUID]bool, error) {
+
+	var visible = make(map[uuid.UUID]bool)
+	if len(sensorUuids) == 0 {
+		return visible, nil
+	}
+
+	// One named parameter per sensor
+	var names []string
+	var args = []any{sql.Named("userUuid", userUuid)}
+	for i, sensorUuid := range sensorUuids {
+		var name = fmt.Sprintf("sensor%d", i)
+		names = append(names, "@"+name)
+		args = append(args, sql.Named(name, sensorUuid))
+	}
+
+	// Same visibility rule as the sensor list and details
+	query := fmt.Sprintf(`
+		SELECT uuid
+		FROM Sensors
+		WHERE uuid IN (%s)
+		AND (visibility = 1 OR sensorOwnerUuid = @userUuid)
+	`, strings.Join(names, ", "))
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch visible sensors: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sensorUuid uuid.UUID
+		if err := rows.Scan(&sensorUuid); err != nil {
+			return nil, fmt.Errorf("failed to scan visible sensor: %v", err)
+		}
+		visible[sensorUuid] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error while iterating over rows: %v", err)
+	}
+
+	return visible, nil
+}
+
+func (s *SensorDataRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+
+	query := `
+		SELECT sensorOwnerUuid
+		FROM Sensors
+		WHERE uuid = @sensorUuid
+	`
+
+	var ownerUuid uuid.UUID
+	err := s.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&ownerUuid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.NilUUID, domain.ErrSensorNotFound
+		}
+		return uuid.NilUUID, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+
+	return ownerUuid, nil
+}
+
+func (s *SensorDataRepositoryImpl) GetLatestSensorData(ctx context.Context, sensorUuids []uuid.UUID) ([]domain.SensorData, error) {
+
+	if len(sensorUuids) == 0 {
+		return nil, nil
+	}
+
+	// One named parameter per sensor
+	var values []string
+	var args []any
+	for i, sensorUuid := range sensorUuids {
+		var name = fmt.Sprintf("sensor%d", i)
+		values = append(values, fmt.Sprintf("(CAST(@%s AS UNIQUEIDENTIFIER))", name))
+		args = append(args, sql.Named(name, sensorUuid))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT sensors.sensorUuid, latest.timestamp, latest.value, latest.anomaly, latest.quality
+		FROM (VALUES %s) AS sensors(sensorUuid)
+		CROSS APPLY (
+			SELECT TOP 1 timestamp, value, anomaly, quality
+			FROM SensorData
+			WHERE SensorData.sensorUuid = sensors.sensorUuid
+			AND quality <> 'missing'
+			ORDER BY timestamp DESC
+		) AS latest
+	`, strings.Join(values, ", "))
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch latest sensor data: %v", err)
+	}
+	defer rows.Close()
+
+	var sensorData []domain.SensorData
+	for rows.Next() {
+		var data domain.SensorData
+		if err := rows.Scan(&data.SensorUuid, &data.Timestamp, &data.Value, &data.Anomaly, &data.Quality); err != nil {
 			return nil, fmt.Errorf("failed to scan sensor data: %v", err)
 		}
 		sensorData = append(sensorData, data)
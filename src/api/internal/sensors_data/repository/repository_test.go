@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"api/internal/sensors_data/domain"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Connects to the database given by SENSOR_DATA_BENCH_DSN, skipping the test or benchmark when it's not set.
+// SENSOR_DATA_BENCH_SENSOR can point to an existing sensor if SensorData has a foreign key to Sensors.
+func openBenchmarkDB(tb testing.TB) (*sql.DB, uuid.UUID) {
+	var dsn = os.Getenv("SENSOR_DATA_BENCH_DSN")
+	if dsn == "" {
+		tb.Skip("SENSOR_DATA_BENCH_DSN not set")
+	}
+
+	db, err := sql.Open("sqlserver", dsn)
+	if err != nil {
+		tb.Fatalf("failed to open database: %v", err)
+	}
+
+	var sensorUuid = uuid.NewV4()
+	if sensor := os.Getenv("SENSOR_DATA_BENCH_SENSOR"); sensor != "" {
+		if sensorUuid, err = uuid.FromString(sensor); err != nil {
+			tb.Fatalf("invalid SENSOR_DATA_BENCH_SENSOR: %v", err)
+		}
+	}
+
+	tb.Cleanup(func() {
+		db.Exec("DELETE FROM SensorData WHERE sensorUuid = @sensorUuid", sql.Named("sensorUuid", sensorUuid))
+		db.Exec("DELETE FROM SensorDataRollup WHERE sensorUuid = @sensorUuid", sql.Named("sensorUuid", sensorUuid))
+		db.Close()
+	})
+
+	return db, sensorUuid
+}
+
+// Timestamp of the next benchmark reading, so that no two readings share a timestamp
+var nextBenchmarkTimestamp = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Builds n readings one second apart, following the ones built before
+func benchmarkReadings(sensorUuid uuid.UUID, n int) []*domain.SensorData {
+	var readings = make([]*domain.SensorData, n)
+	for i := range readings {
+		readings[i] = &domain.SensorData{
+			SensorUuid: sensorUuid,
+			Timestamp:  nextBenchmarkTimestamp,
+			Value:      float64(i),
+		}
+		nextBenchmarkTimestamp = nextBenchmarkTimestamp.Add(time.Second)
+	}
+	return readings
+}
+
+// Previous insert path, one prepared INSERT per reading, kept as the baseline for the benchmark
+func addSensorDataPerRow(ctx context.Context, db *sql.DB, sensorData []*domain.SensorData) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO SensorData (sensorUuid, timestamp, value)
+		VALUES (@sensorUuid, @timestamp, @value)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, data := range sensorData {
+		_, err := stmt.ExecContext(ctx,
+			sql.Named("sensorUuid", data.SensorUuid),
+			sql.Named("timestamp", data.Timestamp),
+			sql.Named("value", data.Value),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func BenchmarkAddSensorData(b *testing.B) {
+	var db, sensorUuid = openBenchmarkDB(b)
+	var repo = &SensorDataRepositoryImpl{DB: db}
+	var ctx = context.Background()
+
+	for _, size := range []int{100, 1000, 5000} {
+		b.Run(fmt.Sprintf("PerRow/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				var readings = benchmarkReadings(sensorUuid, size)
+				b.StartTimer()
+				if err := addSensorDataPerRow(ctx, db, readings); err != nil {
+					b.Fatalf("per-row insert failed: %v", err)
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("BulkCopy/%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				var readings = benchmarkReadings(sensorUuid, size)
+				b.StartTimer()
+				if _, err := repo.AddSensorData(ctx, readings, domain.SENSOR_DATA_CONFLICT_REJECT); err != nil {
+					b.Fatalf("bulk insert failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregatesCombineRollups(t *testing.T) {
+	var db, sensorUuid = openBenchmarkDB(t)
+	var repo = &SensorDataRepositoryImpl{DB: db}
+	var ctx = context.Background()
+	var day = time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// The first hour was compacted: 4 readings between 1 and 3 adding up to 8
+	_, err := db.Exec(`
+		INSERT INTO SensorDataRollup (sensorUuid, bucketStart, valueCount, valueSum, minValue, maxValue)
+		VALUES (@sensorUuid, @bucketStart, 4, 8, 1, 3)
+	`, sql.Named("sensorUuid", sensorUuid), sql.Named("bucketStart", day))
+	if err != nil {
+		t.Fatalf("failed to insert rollup: %v", err)
+	}
+	_, err = repo.AddSensorData(ctx, []*domain.SensorData{
+		{SensorUuid: sensorUuid, Timestamp: day.Add(time.Hour), Value: 10},
+		{SensorUuid: sensorUuid, Timestamp: day.Add(90 * time.Minute), Value: 20},
+	}, domain.SENSOR_DATA_CONFLICT_REJECT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var to = day.Add(24*time.Hour - time.Second)
+	want := map[string]float64{
+		domain.SENSOR_DATA_AGGREGATE_COUNT: 6,
+		domain.SENSOR_DATA_AGGREGATE_SUM:   38,
+		domain.SENSOR_DATA_AGGREGATE_AVG:   38.0 / 6,
+		domain.SENSOR_DATA_AGGREGATE_MIN:   1,
+		domain.SENSOR_DATA_AGGREGATE_MAX:   20,
+	}
+	for aggregate, value := range want {
+		data, err := repo.GetAggregatedSensorData(ctx, sensorUuid, day, to, 24*time.Hour, aggregate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 1 || data[0].Value != value {
+			t.Errorf("%s: expected %g, got %+v", aggregate, value, data)
+		}
+	}
+
+	stats, err := repo.GetSensorDataStats(ctx, sensorUuid, day, to, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("expected one period, got %+v", stats)
+	}
+	var period = stats[0]
+	if period.Count != 6 || *period.Min != 1 || *period.Max != 20 || *period.Mean != 38.0/6 {
+		t.Errorf("expected the rollup to be combined, got %+v", period)
+	}
+	// The readings of the rollup are gone, so their spread is unknown
+	if period.StdDev != nil || period.P50 != nil {
+		t.Errorf("expected no standard deviation nor percentiles over rolled up readings, got %+v", period)
+	}
+}
+
+func TestAddSensorDataRejectsRepeatedTimestamp(t *testing.T) {
+	var db, sensorUuid = openBenchmarkDB(t)
+	var repo = &SensorDataRepositoryImpl{DB: db}
+	var timestamp = time.Date(1990, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := repo.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: sensorUuid, Timestamp: timestamp, Value: 20, Quality: domain.SENSOR_DATA_QUALITY_OK},
+		{SensorUuid: sensorUuid, Timestamp: timestamp, Value: 21, Quality: domain.SENSOR_DATA_QUALITY_OK},
+	}, domain.SENSOR_DATA_CONFLICT_REJECT)
+	if !errors.Is(err, domain.ErrDuplicateSensorData) {
+		t.Fatalf("expected %v, got %v", domain.ErrDuplicateSensorData, err)
+	}
+}
+
+func TestGetLatestSensorDataSkipsMissing(t *testing.T) {
+	var db, sensorUuid = openBenchmarkDB(t)
+	var repo = &SensorDataRepositoryImpl{DB: db}
+	var timestamp = time.Date(1990, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := repo.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: sensorUuid, Timestamp: timestamp, Value: 20, Quality: domain.SENSOR_DATA_QUALITY_OK},
+		{SensorUuid: sensorUuid, Timestamp: timestamp.Add(time.Minute), Value: 0, Quality: domain.SENSOR_DATA_QUALITY_MISSING},
+	}, domain.SENSOR_DATA_CONFLICT_REJECT)
+	if err != nil {
+		t.Fatalf("failed to add sensor data: %v", err)
+	}
+
+	latest, err := repo.GetLatestSensorData(context.Background(), []uuid.UUID{sensorUuid})
+	if err != nil {
+		t.Fatalf("failed to get latest sensor data: %v", err)
+	}
+	if len(latest) != 1 || !latest[0].Timestamp.Equal(timestamp) || latest[0].Quality != domain.SENSOR_DATA_QUALITY_OK {
+		t.Fatalf("expected the reading at %s, got %+v", timestamp, latest)
+	}
+}
@@ -1,13 +1,21 @@
 package sensors_data
 
 import (
+	alert_service "api/internal/alerts/usecase"
+	anomaly_repository "api/internal/anomalies/repository"
+	anomaly_service "api/internal/anomalies/usecase"
+	apikey_repository "api/internal/apikeys/repository"
+	apikey_service "api/internal/apikeys/usecase"
 	auth_repository "api/internal/auth/repository"
 	auth_service "api/internal/auth/usecase"
 	"api/internal/sensors_data/handler"
 	sensor_repository "api/internal/sensors_data/repository"
+	"api/internal/sensors_data/stream"
 	sensor_service "api/internal/sensors_data/usecase"
 	user_repository "api/internal/users/repository"
 	user_service "api/internal/users/usecase"
+	webhook_repository "api/internal/webhooks/repository"
+	webhook_service "api/internal/webhooks/usecase"
 	middleware "api/utils"
 
 	"log"
@@ -16,7 +24,9 @@ import (
 )
 
 // RegisterSensorRoutes declares the routes that can be accessed for sensor management.
-func RegisterSensordataRoutes(router *gin.Engine) {
+// The stored readings are evaluated by the given alert service, shared with the alert routes.
+// It returns the sensor data service, for the other ways readings are ingested (e.g. MQTT).
+func RegisterSensordataRoutes(router *gin.Engine, alertService alert_service.AlertService) sensor_service.SensorDataService {
 
 	sensorDataRepo, err := sensor_repository.NewSensorDataRepository()
 	if err != nil {
@@ -33,19 +43,62 @@ func RegisterSensordataRoutes(router *gin.Engine) {
 		log.Fatalf("Failed to create auth repository: %v", err)
 	}
 
-	_ = user_service.NewUserService(usersRepos, authRepo)
-	sensorDataService := sensor_service.NewSensorDataService(sensorDataRepo)
+	webhookRepo, err := webhook_repository.NewWebhookRepository()
+	if err != nil {
+		log.Fatalf("Failed to create webhook repository: %v", err)
+	}
+
+	anomalyRepo, err := anomaly_repository.NewAnomalyRepository()
+	if err != nil {
+		log.Fatalf("Failed to create anomaly repository: %v", err)
+	}
+
+	apiKeyRepo, err := apikey_repository.NewAPIKeyRepository()
+	if err != nil {
+		log.Fatalf("Failed to create api key repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	apiKeyService := apikey_service.NewAPIKeyService(apiKeyRepo)
+	webhookService := webhook_service.NewWebhookService(webhookRepo)
+	// Readings are checked for anomalies before being stored. Stored readings are pushed to real-time subscribers,
+	// evaluated against the alert rules of their sensor, sent to the webhook subscriptions and their anomalies notified
+	hub := stream.NewHub(stream.DefaultBufferSize)
+	anomalyService := anomaly_service.NewAnomalyService(anomalyRepo, middleware.CreateEmail, webhookService)
+	sensorDataService := sensor_service.NewSensorDataService(sensorDataRepo, anomalyService, hub, alertService, webhookService, anomalyService)
 	authService := auth_service.NewAuthService(authRepo, usersRepos)
 
-	h := handler.NewSensorDataHandler(sensorDataService)
+	h := handler.NewSensorDataHandler(sensorDataService, userService, hub)
+
+	// Sensor's data ingestion, devices can authenticate with an API key of their sensor instead of a token
+	ingest := router.Group("/v1/sensor/data/")
+	ingest.Use(middleware.DeviceOrUserAuthMiddleware(authService, apiKeyService))
+	{
+		// Add sensor's data
+		ingest.POST("add", h.AddSensorData)
+	}
 
 	// Sensor's data routes
 	api := router.Group("/v1/sensor/data/")
 	api.Use(middleware.AuthMiddleware(authService))
 	{
-		// Add sensor's data
-		api.POST("add", h.AddSensorData)
 		// Read sensor data
 		api.POST("get", h.ReadSensorData)
+		// Read the statistics (count, min, max, mean, stddev and percentiles) of sensor data
+		api.POST("stats", h.ReadSensorDataStats)
+		// Read the ranges without readings for longer than the expected interval, and the coverage of the time range
+		api.POST("gaps", h.ReadSensorDataGaps)
+		// Read several sensors aligned on a common timestamp axis
+		api.POST("aligned", h.ReadAlignedSensorData)
+		// Read the most recent reading of one or many sensors
+		api.POST("latest", h.ReadLatestSensorData)
+		// Receive the readings of a sensor in real time (Server-Sent Events)
+		api.GET("subscribe", h.SubscribeSensorData)
+		// Export sensor data as CSV or NDJSON
+		api.POST("export", h.ExportSensorData)
+		// Import sensor data from a CSV file (multipart upload)
+		api.POST("import", h.ImportSensorData)
 	}
+
+	return sensorDataService
 }
@@ -0,0 +1,116 @@
+package stream
+
+import (
+	"api/internal/sensors_data/domain"
+	"context"
+	"sync"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Default number of readings buffered for each subscriber before it's considered too slow
+const DefaultBufferSize = 256
+
+// Subscriber receives the readings published for one sensor
+type Subscriber struct {
+	// UUID of the sensor the subscriber listens to
+	SensorUuid uuid.UUID
+	// Readings published for the sensor, closed when the subscriber is removed from the hub
+	C <-chan domain.SensorData
+
+	channel chan domain.SensorData
+	// Set when the subscriber was removed for not keeping up with the readings
+	dropped bool
+}
+
+// Dropped reports whether the subscriber was removed for being too slow (only meaningful once C is closed)
+func (s *Subscriber) Dropped() bool {
+	return s.dropped
+}
+
+// Hub is an in-process pub/sub of sensor readings, keyed by sensor.
+// Each subscriber has a bounded buffer; a subscriber whose buffer is full is dropped instead of blocking publishers.
+type Hub struct {
+	mu          sync.Mutex
+	bufferSize  int
+	subscribers map[uuid.UUID]map[*Subscriber]struct{}
+}
+
+func NewHub(bufferSize int) *Hub {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	return &Hub{
+		bufferSize:  bufferSize,
+		subscribers: make(map[uuid.UUID]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber for the readings of the sensor
+func (h *Hub) Subscribe(sensorUuid uuid.UUID) *Subscriber {
+	var channel = make(chan domain.SensorData, h.bufferSize)
+	var subscriber = &Subscriber{SensorUuid: sensorUuid, C: channel, channel: channel}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers[sensorUuid] == nil {
+		h.subscribers[sensorUuid] = make(map[*Subscriber]struct{})
+	}
+	h.subscribers[sensorUuid][subscriber] = struct{}{}
+
+	return subscriber
+}
+
+// Unsubscribe removes the subscriber from the hub and closes its channel (no-op if already removed)
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(subscriber)
+}
+
+// Removes the subscriber, must be called with the lock held
+func (h *Hub) remove(subscriber *Subscriber) {
+	var subscribers = h.subscribers[subscriber.SensorUuid]
+	if _, ok := subscribers[subscriber]; !ok {
+		return
+	}
+
+	delete(subscribers, subscriber)
+	if len(subscribers) == 0 {
+		delete(h.subscribers, subscriber.SensorUuid)
+	}
+	close(subscriber.channel)
+}
+
+// Publish sends the readings to the subscribers of their sensors, dropping subscribers that can't keep up
+func (h *Hub) Publish(sensorData []*domain.SensorData) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, data := range sensorData {
+		for subscriber := range h.subscribers[data.SensorUuid] {
+			select {
+			case subscriber.channel <- *data:
+			default:
+				subscriber.dropped = true
+				h.remove(subscriber)
+			}
+		}
+	}
+}
+
+// OnSensorData publishes the readings stored by the sensor data service
+func (h *Hub) OnSensorData(ctx context.Context, sensorData []*domain.SensorData) {
+	h.Publish(sensorData)
+}
+
+// Subscribers returns the number of subscribers of the sensor
+func (h *Hub) Subscribers(sensorUuid uuid.UUID) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscribers[sensorUuid])
+}
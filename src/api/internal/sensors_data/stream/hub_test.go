@@ -0,0 +1,82 @@
+package stream
+
+import (
+	"api/internal/sensors_data/domain"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Builds n readings of the sensor
+func readings(sensorUuid uuid.UUID, n int) []*domain.SensorData {
+	var sensorData = make([]*domain.SensorData, n)
+	for i := range sensorData {
+		sensorData[i] = &domain.SensorData{SensorUuid: sensorUuid, Timestamp: time.Unix(int64(i), 0), Value: float64(i)}
+	}
+	return sensorData
+}
+
+// Test that subscribers only receive the readings of their sensor
+func TestHubPublish(t *testing.T) {
+	var hub = NewHub(10)
+	var sensorA, sensorB = uuid.NewV4(), uuid.NewV4()
+
+	var subscriberA = hub.Subscribe(sensorA)
+	var subscriberB = hub.Subscribe(sensorB)
+
+	hub.Publish(readings(sensorA, 3))
+
+	if len(subscriberA.C) != 3 {
+		t.Errorf("expected 3 readings for sensor A, got %d", len(subscriberA.C))
+	}
+	if len(subscriberB.C) != 0 {
+		t.Errorf("expected no readings for sensor B, got %d", len(subscriberB.C))
+	}
+
+	hub.Unsubscribe(subscriberA)
+	hub.Unsubscribe(subscriberA)
+
+	if hub.Subscribers(sensorA) != 0 {
+		t.Errorf("expected no subscribers left for sensor A")
+	}
+	if subscriberA.Dropped() {
+		t.Errorf("expected unsubscribed subscriber not to be marked as dropped")
+	}
+}
+
+// Test that a subscriber that doesn't keep up is dropped without blocking the others
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	var hub = NewHub(2)
+	var sensor = uuid.NewV4()
+
+	var slow = hub.Subscribe(sensor)
+	var fast = hub.Subscribe(sensor)
+
+	var received = 0
+	for _, data := range readings(sensor, 5) {
+		hub.Publish([]*domain.SensorData{data})
+		// Only the fast subscriber drains its buffer
+		for len(fast.C) > 0 {
+			<-fast.C
+			received++
+		}
+	}
+
+	if received != 5 {
+		t.Errorf("expected fast subscriber to receive 5 readings, got %d", received)
+	}
+
+	// The slow subscriber keeps its buffered readings and then sees its channel closed
+	var buffered = 0
+	for range slow.C {
+		buffered++
+	}
+	if buffered != 2 || !slow.Dropped() {
+		t.Errorf("expected slow subscriber to be dropped after 2 readings, got %d (dropped: %v)", buffered, slow.Dropped())
+	}
+
+	if hub.Subscribers(sensor) != 1 {
+		t.Errorf("expected only the fast subscriber to remain, got %d", hub.Subscribers(sensor))
+	}
+}
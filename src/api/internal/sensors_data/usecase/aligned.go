@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"api/internal/sensors_data/domain"
+	"context"
+	"fmt"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Maximum number of sensors compared in a single aligned read
+const MaxAlignedSensors = 20
+
+// Maximum number of buckets on the axis of an aligned read
+const MaxAlignedBuckets = 10000
+
+// Builds the timestamp axis from the bucket containing 'from' to the bucket containing 'to',
+// with buckets aligned to the unix epoch like the aggregated reads
+func buildAxis(from, to time.Time, bucket time.Duration) []time.Time {
+	var start = time.Unix(0, 0).UTC().Add(from.Sub(time.Unix(0, 0)) / bucket * bucket)
+	if start.After(from) {
+		start = start.Add(-bucket)
+	}
+
+	var axis []time.Time
+	for timestamp := start; !timestamp.After(to); timestamp = timestamp.Add(bucket) {
+		axis = append(axis, timestamp)
+	}
+	return axis
+}
+
+// Places each value on the axis position of its bucket, leaving nil where the sensor has no data
+func alignSeries(axis []time.Time, sensorData []domain.SensorData) []*float64 {
+	var positions = make(map[int64]int, len(axis))
+	for i, timestamp := range axis {
+		positions[timestamp.Unix()] = i
+	}
+
+	var values = make([]*float64, len(axis))
+	for _, data := range sensorData {
+		if i, ok := positions[data.Timestamp.Unix()]; ok {
+			var value = data.Value
+			values[i] = &value
+		}
+	}
+	return values
+}
+
+func (s *SensorDataServiceImpl) GetAlignedSensorData(ctx context.Context, sensorUuids []uuid.UUID, from, to time.Time, interval string, aggregate string) (*domain.AlignedSensorData, error) {
+	if len(sensorUuids) == 0 || len(sensorUuids) > MaxAlignedSensors {
+		return nil, fmt.Errorf("%w: between 1 and %d sensors are required", domain.ErrInvalidSensorCount, MaxAlignedSensors)
+	}
+
+	var bucket, err = ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	if to.Sub(from)/bucket >= MaxAlignedBuckets {
+		return nil, domain.ErrTooManyBuckets
+	}
+
+	var aligned = &domain.AlignedSensorData{Timestamps: buildAxis(from, to, bucket)}
+
+	for _, sensorUuid := range sensorUuids {
+		var sensorData, err = s.GetAggregatedSensorData(ctx, sensorUuid, from, to, interval, aggregate)
+		if err != nil {
+			return nil, err
+		}
+
+		aligned.Series = append(aligned.Series, domain.SensorSeries{
+			SensorUuid: sensorUuid,
+			Values:     alignSeries(aligned.Timestamps, sensorData),
+		})
+	}
+
+	return aligned, nil
+}
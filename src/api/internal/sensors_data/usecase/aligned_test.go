@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"api/internal/sensors_data/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Test for the alignment of sensor series on a shared axis
+func TestAlignSeries(t *testing.T) {
+	var from = time.Date(2025, 1, 1, 10, 0, 30, 0, time.UTC)
+	var to = time.Date(2025, 1, 1, 10, 4, 0, 0, time.UTC)
+
+	var axis = buildAxis(from, to, time.Minute)
+
+	// Buckets containing 'from' and 'to' are both included
+	if len(axis) != 5 || !axis[0].Equal(time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)) || !axis[4].Equal(to) {
+		t.Fatalf("unexpected axis: %v", axis)
+	}
+
+	var sensorData = []domain.SensorData{
+		{Timestamp: axis[0], Value: 1},
+		{Timestamp: axis[3], Value: 4},
+	}
+
+	var values = alignSeries(axis, sensorData)
+
+	var expected = []*float64{&sensorData[0].Value, nil, nil, &sensorData[1].Value, nil}
+	for i := range expected {
+		if (values[i] == nil) != (expected[i] == nil) {
+			t.Fatalf("position %d: expected gap %v, got %v", i, expected[i] == nil, values[i] == nil)
+		}
+		if values[i] != nil && *values[i] != *expected[i] {
+			t.Errorf("position %d: expected %v, got %v", i, *expected[i], *values[i])
+		}
+	}
+}
+
+func TestGetAlignedSensorDataValidation(t *testing.T) {
+	service := &SensorDataServiceImpl{Repo: &fakeRepository{}}
+	ctx := context.Background()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tooMany := make([]uuid.UUID, MaxAlignedSensors+1)
+	for i := range tooMany {
+		tooMany[i] = uuid.NewV4()
+	}
+	for _, sensorUuids := range [][]uuid.UUID{nil, tooMany} {
+		if _, err := service.GetAlignedSensorData(ctx, sensorUuids, from, to, "1h", ""); !errors.Is(err, domain.ErrInvalidSensorCount) {
+			t.Errorf("%d sensors: expected %v, got %v", len(sensorUuids), domain.ErrInvalidSensorCount, err)
+		}
+	}
+
+	if _, err := service.GetAlignedSensorData(ctx, []uuid.UUID{uuid.NewV4()}, from, to, "1s", ""); !errors.Is(err, domain.ErrTooManyBuckets) {
+		t.Errorf("expected %v, got %v", domain.ErrTooManyBuckets, err)
+	}
+
+	aligned, err := service.GetAlignedSensorData(ctx, []uuid.UUID{uuid.NewV4(), uuid.NewV4()}, from, to, "1h", "")
+	if err != nil || len(aligned.Timestamps) != 25 || len(aligned.Series) != 2 {
+		t.Errorf("expected two series on 25 hourly buckets, got %+v, %v", aligned, err)
+	}
+}
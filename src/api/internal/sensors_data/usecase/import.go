@@ -0,0 +1,276 @@
+package usecase
+
+import (
+	"api/internal/sensors_data/domain"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Number of readings inserted at once while importing a file
+const ImportBatchSize = 1000
+
+// Timestamp layouts accepted on imported files, besides unix seconds
+var importTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// Position of each known column in the imported file (-1 when absent)
+type importColumns struct {
+	timestamp int
+	value     int
+	sensor    int
+	quality   int
+}
+
+// Reads the column positions from the header row, returning false if the row is not a header
+func parseImportHeader(record []string) (importColumns, bool) {
+	var columns = importColumns{timestamp: -1, value: -1, sensor: -1, quality: -1}
+
+	for i, name := range record {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "timestamp", "time", "date":
+			columns.timestamp = i
+		case "value":
+			columns.value = i
+		case "sensor", "sensoruuid", "sensor_uuid":
+			columns.sensor = i
+		case "quality":
+			columns.quality = i
+		}
+	}
+
+	if columns.timestamp == -1 || columns.value == -1 {
+		return importColumns{}, false
+	}
+	return columns, true
+}
+
+// Parses a timestamp in any of the accepted layouts (UTC when no zone is given) or in unix seconds
+func parseImportTimestamp(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	for _, layout := range importTimestampLayouts {
+		if timestamp, err := time.Parse(layout, value); err == nil {
+			return timestamp.UTC(), nil
+		}
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		var whole, fraction = math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*1e9)).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp: %q", value)
+}
+
+// Validates and converts a single row of the imported file
+func parseImportRow(record []string, columns importColumns, sensorUuid uuid.UUID) (*domain.SensorData, error) {
+	var field = func(index int) string {
+		if index < 0 || index >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[index])
+	}
+
+	var data = &domain.SensorData{SensorUuid: sensorUuid}
+
+	if sensor := field(columns.sensor); sensor != "" {
+		var err error
+		if data.SensorUuid, err = uuid.FromString(sensor); err != nil {
+			return nil, fmt.Errorf("invalid sensor uuid: %q", sensor)
+		}
+	}
+	if data.SensorUuid == uuid.NilUUID {
+		return nil, errors.New("sensor uuid is required")
+	}
+
+	var timestamp = field(columns.timestamp)
+	if timestamp == "" {
+		return nil, errors.New("timestamp is required")
+	}
+	var err error
+	if data.Timestamp, err = parseImportTimestamp(timestamp); err != nil {
+		return nil, err
+	}
+
+	var value = field(columns.value)
+	if value == "" {
+		return nil, errors.New("value is required")
+	}
+	if data.Value, err = strconv.ParseFloat(value, 64); err != nil || math.IsNaN(data.Value) || math.IsInf(data.Value, 0) {
+		return nil, fmt.Errorf("invalid value: %q", value)
+	}
+
+	if data.Quality, err = ParseQuality(field(columns.quality)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Error reported for a row that couldn't be stored. Database errors are only logged, the client gets a generic reason.
+func importStoreError(err error) error {
+	if errors.Is(err, domain.ErrDuplicateSensorData) {
+		return errors.New("sensor data already exists for the timestamp")
+	}
+	return errors.New("failed to store reading")
+}
+
+func (s *SensorDataServiceImpl) ImportSensorData(ctx context.Context, file io.Reader, sensorUuid uuid.UUID, userUuid uuid.UUID, onConflict string) (*domain.ImportReport, error) {
+	var err error
+	if onConflict, err = validateConflictPolicy(onConflict); err != nil {
+		return nil, err
+	}
+
+	var reader = csv.NewReader(file)
+	// Lines starting with # are skipped, e.g. the metadata row of exported files
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	var report = &domain.ImportReport{Errors: []domain.ImportRowError{}}
+	var reject = func(line int, err error) {
+		report.Rejected++
+		report.Errors = append(report.Errors, domain.ImportRowError{Line: line, Error: err.Error()})
+	}
+
+	// Ownership is checked once per sensor found in the file
+	var ownership = map[uuid.UUID]error{}
+	var checkOwner = func(sensor uuid.UUID) error {
+		if err, checked := ownership[sensor]; checked {
+			return err
+		}
+		ownership[sensor] = s.CheckSensorOwner(ctx, sensor, userUuid)
+		return ownership[sensor]
+	}
+
+	// The value range of the category is also fetched once per sensor
+	var ranges = map[uuid.UUID]domain.ValueRange{}
+	var checkRange = func(data *domain.SensorData) error {
+		valueRange, fetched := ranges[data.SensorUuid]
+		if !fetched {
+			fetchedRanges, err := s.Repo.GetSensorValueRanges(ctx, []uuid.UUID{data.SensorUuid})
+			if err != nil {
+				return fmt.Errorf("failed to check value range: %v", err)
+			}
+			valueRange = fetchedRanges[data.SensorUuid]
+			ranges[data.SensorUuid] = valueRange
+		}
+		return checkValueRange(data, valueRange)
+	}
+
+	// Without a header the columns are timestamp, value and optionally sensor
+	var columns = importColumns{timestamp: 0, value: 1, sensor: 2, quality: -1}
+	var first = true
+
+	var batch []*domain.SensorData
+	var batchLines []int
+	var flush = func() {
+		if len(batch) == 0 {
+			return
+		}
+		s.analyze(ctx, batch)
+		var stored []*domain.SensorData
+		var err error
+		// Repeated rows are stored one by one below, so that the later ones are rejected
+		if onConflict == domain.SENSOR_DATA_CONFLICT_REJECT {
+			err = checkRepeatedReadings(batch)
+		}
+		if err == nil {
+			stored, err = s.Repo.AddSensorData(ctx, batch, onConflict)
+		}
+		switch {
+		case err == nil:
+			report.Inserted += len(stored)
+			s.notifyImported(ctx, stored)
+		case errors.Is(err, domain.ErrDuplicateSensorData):
+			// The whole batch is rejected for a few existing or repeated readings, so its rows are stored
+			// one by one and only the conflicting ones are reported
+			for i, data := range batch {
+				stored, err := s.Repo.AddSensorData(ctx, []*domain.SensorData{data}, onConflict)
+				if err != nil {
+					if !errors.Is(err, domain.ErrDuplicateSensorData) {
+						log.Printf("Failed to import sensor data for %s: %v", sensorUuid.String(), err)
+					}
+					reject(batchLines[i], importStoreError(err))
+					continue
+				}
+				report.Inserted += len(stored)
+				s.notifyImported(ctx, stored)
+			}
+		default:
+			log.Printf("Failed to import sensor data for %s: %v", sensorUuid.String(), err)
+			for _, line := range batchLines {
+				reject(line, importStoreError(err))
+			}
+		}
+		batch = nil
+		batchLines = nil
+	}
+
+	for {
+		var record, err = reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				reject(parseErr.StartLine, parseErr.Err)
+				continue
+			}
+			return nil, fmt.Errorf("failed to read file: %v", err)
+		}
+
+		var line, _ = reader.FieldPos(0)
+
+		if first {
+			first = false
+			if header, ok := parseImportHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+
+		data, err := parseImportRow(record, columns, sensorUuid)
+		if err != nil {
+			reject(line, err)
+			continue
+		}
+
+		if err = checkOwner(data.SensorUuid); err != nil {
+			reject(line, err)
+			continue
+		}
+
+		if err = checkRange(data); err != nil {
+			reject(line, err)
+			continue
+		}
+
+		batch = append(batch, data)
+		batchLines = append(batchLines, line)
+		if len(batch) == ImportBatchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return report, nil
+}
@@ -0,0 +1,246 @@
+package usecase
+
+import (
+	alert_domain "api/internal/alerts/domain"
+	alert_repository "api/internal/alerts/repository"
+	alert_service "api/internal/alerts/usecase"
+	"api/internal/sensors_data/domain"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+func TestParseImportHeader(t *testing.T) {
+	columns, ok := parseImportHeader([]string{"Sensor_UUID", " time ", "quality", "Value"})
+	if !ok || columns.sensor != 0 || columns.timestamp != 1 || columns.quality != 2 || columns.value != 3 {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+
+	// A data row isn't a header
+	if _, ok = parseImportHeader([]string{"2025-01-01T00:00:00Z", "21.5"}); ok {
+		t.Error("expected a data row not to be read as a header")
+	}
+}
+
+func TestParseImportTimestamp(t *testing.T) {
+	want := time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)
+	for _, value := range []string{"2025-01-01T12:30:00Z", "2025-01-01T14:30:00+02:00", "2025-01-01 12:30", "1735734600"} {
+		if got, err := parseImportTimestamp(value); err != nil || !got.Equal(want) {
+			t.Errorf("%s: expected %v, got %v, %v", value, want, got, err)
+		}
+	}
+
+	if got, err := parseImportTimestamp("1735734600.5"); err != nil || got.Sub(want) != 500*time.Millisecond {
+		t.Errorf("expected fractional unix seconds to be kept, got %v, %v", got, err)
+	}
+	if _, err := parseImportTimestamp("yesterday"); err == nil {
+		t.Error("expected an unknown timestamp format to be rejected")
+	}
+}
+
+func TestParseImportRow(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	columns := importColumns{timestamp: 0, value: 1, sensor: 2, quality: 3}
+
+	data, err := parseImportRow([]string{"2025-01-01", "21.5", "", "Suspect"}, columns, sensorUuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.SensorUuid != sensorUuid || data.Value != 21.5 || data.Quality != domain.SENSOR_DATA_QUALITY_SUSPECT {
+		t.Errorf("unexpected reading %+v", data)
+	}
+
+	invalid := map[string][]string{
+		"missing timestamp":   {"", "21.5"},
+		"missing value":       {"2025-01-01", ""},
+		"non numeric value":   {"2025-01-01", "warm"},
+		"not a number":        {"2025-01-01", "NaN"},
+		"invalid sensor uuid": {"2025-01-01", "21.5", "probe-1"},
+		"unknown quality":     {"2025-01-01", "21.5", "", "bad"},
+	}
+	for name, record := range invalid {
+		if _, err := parseImportRow(record, columns, sensorUuid); err == nil {
+			t.Errorf("%s: expected the row to be rejected", name)
+		}
+	}
+
+	// Without a sensor column nor a default sensor, the row has no sensor
+	if _, err := parseImportRow([]string{"2025-01-01", "21.5"}, columns, uuid.NilUUID); err == nil {
+		t.Error("expected a row without sensor to be rejected")
+	}
+}
+
+func TestImportSensorDataReport(t *testing.T) {
+	user, own, other := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	zero, hundred := 0.0, 100.0
+	repo := &fakeRepository{
+		owners: map[uuid.UUID]uuid.UUID{own: user, other: uuid.NewV4()},
+		ranges: map[uuid.UUID]domain.ValueRange{own: {Min: &zero, Max: &hundred}},
+	}
+	service := &SensorDataServiceImpl{Repo: repo}
+
+	file := strings.Join([]string{
+		"#sensor,exported metadata",
+		"timestamp,value,sensor",
+		"2025-01-01T00:00:00Z,20,",
+		"2025-01-01T00:01:00Z,abc,",
+		"2025-01-01T00:02:00Z,21," + other.String(),
+		"2025-01-01T00:03:00Z,150,",
+		"2025-01-01T00:04:00Z,22,",
+	}, "\n")
+
+	report, err := service.ImportSensorData(context.Background(), strings.NewReader(file), own, user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Inserted != 2 || report.Rejected != 3 || len(repo.stored) != 2 {
+		t.Fatalf("expected 2 rows stored and 3 rejected, got %+v", report)
+	}
+	// Each rejected row is reported with its line in the file
+	wantLines := []int{4, 5, 6}
+	for i, rowErr := range report.Errors {
+		if rowErr.Line != wantLines[i] {
+			t.Errorf("error %d: expected line %d, got %d (%s)", i, wantLines[i], rowErr.Line, rowErr.Error)
+		}
+	}
+	if !strings.Contains(report.Errors[1].Error, domain.ErrNotSensorOwner.Error()) {
+		t.Errorf("expected the row of another user's sensor to be rejected as not owned, got %s", report.Errors[1].Error)
+	}
+
+	if _, err = service.ImportSensorData(context.Background(), strings.NewReader(file), own, user, "merge"); err == nil {
+		t.Error("expected an unknown conflict policy to be rejected")
+	}
+}
+
+func TestImportSensorDataDuplicates(t *testing.T) {
+	user, sensor := uuid.NewV4(), uuid.NewV4()
+	existing := time.Date(2025, 1, 1, 0, 1, 0, 0, time.UTC)
+	repo := &fakeRepository{
+		owners:   map[uuid.UUID]uuid.UUID{sensor: user},
+		existing: map[int64]bool{existing.Unix(): true},
+	}
+	service := &SensorDataServiceImpl{Repo: repo}
+
+	file := "2025-01-01T00:00:00Z,20\n2025-01-01T00:01:00Z,21\n2025-01-01T00:02:00Z,22\n"
+
+	// Only the row that already exists is rejected, not the rest of its batch
+	report, err := service.ImportSensorData(context.Background(), strings.NewReader(file), sensor, user, domain.SENSOR_DATA_CONFLICT_REJECT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 2 || report.Rejected != 1 || len(repo.stored) != 2 {
+		t.Fatalf("expected 2 rows stored and 1 rejected, got %+v", report)
+	}
+	if report.Errors[0].Line != 2 {
+		t.Errorf("expected the duplicate on line 2, got %+v", report.Errors[0])
+	}
+}
+
+func TestImportSensorDataRepeatedRows(t *testing.T) {
+	user, sensor := uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{owners: map[uuid.UUID]uuid.UUID{sensor: user}}
+	service := &SensorDataServiceImpl{Repo: repo}
+
+	file := "2025-01-01T00:00:00Z,20\n2025-01-01T00:01:00Z,21\n2025-01-01T00:01:00Z,22\n"
+	report, err := service.ImportSensorData(context.Background(), strings.NewReader(file), sensor, user, domain.SENSOR_DATA_CONFLICT_REJECT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every row is either inserted or reported
+	if report.Inserted != 2 || report.Rejected != 1 || len(report.Errors) != 1 || report.Errors[0].Line != 3 {
+		t.Fatalf("expected the repeated row on line 3 to be rejected, got %+v", report)
+	}
+}
+
+func TestImportSensorDataStoreFailure(t *testing.T) {
+	user, sensor := uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{
+		owners: map[uuid.UUID]uuid.UUID{sensor: user},
+		addErr: errors.New("mssql: Transaction (Process ID 53) was deadlocked on lock resources"),
+	}
+	service := &SensorDataServiceImpl{Repo: repo}
+
+	file := "2025-01-01T00:00:00Z,20\n2025-01-01T00:01:00Z,21\n"
+	report, err := service.ImportSensorData(context.Background(), strings.NewReader(file), sensor, user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 0 || report.Rejected != 2 {
+		t.Fatalf("expected both rows rejected, got %+v", report)
+	}
+	// The database error isn't exposed to the client
+	for _, rowErr := range report.Errors {
+		if rowErr.Error != "failed to store reading" {
+			t.Errorf("line %d: expected a generic reason, got %q", rowErr.Line, rowErr.Error)
+		}
+	}
+}
+
+// Alert repository holding one rule, counting the alerts fired
+type fakeAlertRepository struct {
+	alert_repository.AlertRepository
+	rule   alert_domain.AlertRule
+	alerts int
+}
+
+func (r *fakeAlertRepository) ListSensorRules(ctx context.Context, sensorUuid uuid.UUID) ([]alert_domain.AlertRule, error) {
+	return []alert_domain.AlertRule{r.rule}, nil
+}
+
+func (r *fakeAlertRepository) GetActiveAlert(ctx context.Context, ruleUuid uuid.UUID) (*alert_domain.Alert, error) {
+	return nil, nil
+}
+
+func (r *fakeAlertRepository) CreateAlert(ctx context.Context, alert *alert_domain.Alert) error {
+	r.alerts++
+	return nil
+}
+
+func (r *fakeAlertRepository) SetConditionSince(ctx context.Context, ruleUuid uuid.UUID, since *time.Time, evaluatedUntil time.Time) error {
+	r.rule.ConditionSince = since
+	return nil
+}
+
+func (r *fakeAlertRepository) GetSensorContact(ctx context.Context, sensorUuid uuid.UUID) (*alert_domain.SensorContact, error) {
+	return &alert_domain.SensorContact{}, nil
+}
+
+func TestImportSensorDataSkipsAlerts(t *testing.T) {
+	user, sensor := uuid.NewV4(), uuid.NewV4()
+	repo := &fakeRepository{owners: map[uuid.UUID]uuid.UUID{sensor: user}}
+	alertRepo := &fakeAlertRepository{rule: alert_domain.AlertRule{
+		ID:              uuid.NewV4(),
+		SensorUuid:      sensor,
+		Type:            alert_domain.ALERT_RULE_THRESHOLD,
+		Operator:        alert_domain.ALERT_OPERATOR_GREATER,
+		Threshold:       30,
+		DurationMinutes: 10,
+		Enabled:         true,
+	}}
+	alerts := alert_service.NewAlertService(alertRepo, func(to, subject, body string) error { return nil })
+	listener := &recordingListener{}
+	service := NewSensorDataService(repo, nil, alerts, listener)
+
+	// Back-dated readings breaching the threshold for an hour
+	file := "2024-01-01T00:00:00Z,40\n2024-01-01T00:30:00Z,41\n2024-01-01T01:00:00Z,42\n"
+	report, err := service.ImportSensorData(context.Background(), strings.NewReader(file), sensor, user, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Inserted != 3 {
+		t.Fatalf("expected 3 rows stored, got %+v", report)
+	}
+
+	if alertRepo.alerts != 0 || alertRepo.rule.ConditionSince != nil {
+		t.Errorf("expected imported readings not to be evaluated by the alert rules, got %d alerts", alertRepo.alerts)
+	}
+	// The other listeners are still notified
+	if len(listener.notified) != 3 {
+		t.Errorf("expected the imported readings to be notified, got %d", len(listener.notified))
+	}
+}
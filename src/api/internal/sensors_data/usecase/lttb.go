@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"api/internal/sensors_data/domain"
+	"math"
+)
+
+// Downsamples the series to at most threshold points using the Largest-Triangle-Three-Buckets algorithm.
+// The first and last points are always kept, and for every bucket in between the point forming the largest
+// triangle with the previously selected point and the average of the next bucket is chosen, which keeps peaks
+// and troughs visible in charts.
+func DownsampleLTTB(data []domain.SensorData, threshold int) []domain.SensorData {
+	if threshold >= len(data) || threshold <= 0 {
+		return data
+	}
+
+	// Not enough room for any bucket, so keep only the edges
+	if threshold < 3 {
+		if threshold == 1 {
+			return []domain.SensorData{data[0]}
+		}
+		return []domain.SensorData{data[0], data[len(data)-1]}
+	}
+
+	var sampled = make([]domain.SensorData, 0, threshold)
+	sampled = append(sampled, data[0])
+
+	// Size of each bucket, excluding the first and last points
+	var every = float64(len(data)-2) / float64(threshold-2)
+	var selected = 0
+
+	for i := 0; i < threshold-2; i++ {
+		// Average point of the next bucket, used as the third vertex of the triangle
+		var nextStart = int(math.Floor(float64(i+1)*every)) + 1
+		var nextEnd = int(math.Floor(float64(i+2)*every)) + 1
+		if nextEnd > len(data) {
+			nextEnd = len(data)
+		}
+
+		var avgX, avgY float64
+		for j := nextStart; j < nextEnd; j++ {
+			avgX += x(data[j])
+			avgY += data[j].Value
+		}
+		var count = float64(nextEnd - nextStart)
+		avgX /= count
+		avgY /= count
+
+		// Range of the current bucket
+		var start = int(math.Floor(float64(i)*every)) + 1
+		var end = int(math.Floor(float64(i+1)*every)) + 1
+
+		var pointX = x(data[selected])
+		var pointY = data[selected].Value
+
+		var maxArea = -1.0
+		var next = start
+		for j := start; j < end; j++ {
+			var area = math.Abs((pointX-avgX)*(data[j].Value-pointY) - (pointX-x(data[j]))*(avgY-pointY))
+			if area > maxArea {
+				maxArea = area
+				next = j
+			}
+		}
+
+		sampled = append(sampled, data[next])
+		selected = next
+	}
+
+	sampled = append(sampled, data[len(data)-1])
+
+	return sampled
+}
+
+// X coordinate of a point, in seconds since the unix epoch
+func x(data domain.SensorData) float64 {
+	return float64(data.Timestamp.UnixNano()) / float64(1e9)
+}
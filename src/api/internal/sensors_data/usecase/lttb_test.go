@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"api/internal/sensors_data/domain"
+	"math"
+	"testing"
+	"time"
+)
+
+// Builds a series with one point per second starting at a fixed date
+func series(values []float64) []domain.SensorData {
+	var start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var data = make([]domain.SensorData, len(values))
+	for i, value := range values {
+		data[i] = domain.SensorData{Timestamp: start.Add(time.Duration(i) * time.Second), Value: value}
+	}
+	return data
+}
+
+// Flat series with a single spike and a single dip at the given positions
+func spikes(length, peakAt, troughAt int) []float64 {
+	var values = make([]float64, length)
+	for i := range values {
+		values[i] = 10
+	}
+	values[peakAt] = 100
+	values[troughAt] = -100
+	return values
+}
+
+// Test for the Largest-Triangle-Three-Buckets downsampling
+func TestDownsampleLTTB(t *testing.T) {
+	var sine = make([]float64, 1000)
+	for i := range sine {
+		sine[i] = math.Sin(float64(i) / 50)
+	}
+
+	tests := []struct {
+		name      string
+		values    []float64
+		threshold int
+		expectLen int
+		// Maximum distance allowed between the real and the sampled extremes
+		tolerance float64
+	}{
+		{"threshold above length keeps series", []float64{1, 2, 3}, 10, 3, 0},
+		{"zero threshold keeps series", []float64{1, 2, 3}, 0, 3, 0},
+		{"threshold of two keeps edges", []float64{1, 5, 2, 8}, 2, 2, math.Inf(1)},
+		{"spike and dip are preserved", spikes(500, 123, 377), 20, 20, 0},
+		{"spike near the edges is preserved", spikes(500, 1, 498), 10, 10, 0},
+		{"sine extremes are preserved", sine, 100, 100, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data = series(tt.values)
+			var sampled = DownsampleLTTB(data, tt.threshold)
+
+			if len(sampled) != tt.expectLen {
+				t.Fatalf("expected %d points, got %d", tt.expectLen, len(sampled))
+			}
+
+			// Edges are always kept
+			if !sampled[0].Timestamp.Equal(data[0].Timestamp) || !sampled[len(sampled)-1].Timestamp.Equal(data[len(data)-1].Timestamp) {
+				t.Errorf("expected first and last points to be kept")
+			}
+
+			// Points must stay in chronological order
+			for i := 1; i < len(sampled); i++ {
+				if !sampled[i].Timestamp.After(sampled[i-1].Timestamp) {
+					t.Fatalf("points out of order at index %d", i)
+				}
+			}
+
+			// Global maximum and minimum must survive the downsampling
+			var maxValue, minValue = math.Inf(-1), math.Inf(1)
+			for _, value := range tt.values {
+				maxValue = math.Max(maxValue, value)
+				minValue = math.Min(minValue, value)
+			}
+
+			var sampledMax, sampledMin = math.Inf(-1), math.Inf(1)
+			for _, point := range sampled {
+				sampledMax = math.Max(sampledMax, point.Value)
+				sampledMin = math.Min(sampledMin, point.Value)
+			}
+			if maxValue-sampledMax > tt.tolerance || sampledMin-minValue > tt.tolerance {
+				t.Errorf("expected extremes %v and %v to be preserved, got %v and %v", maxValue, minValue, sampledMax, sampledMin)
+			}
+		})
+	}
+}
@@ -3,8 +3,16 @@ package usecase
 import (
 	"api/internal/sensors_data/domain"
 	"api/internal/sensors_data/repository"
+	"api/internal/units"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	uuid "github.com/tentone/mssql-uuid"
@@ -14,27 +22,273 @@ import (
 type SensorDataService interface {
 	// Retrieves sensor data within a specific time interval.
 	GetSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time) ([]domain.SensorData, error)
-	// Add sensor data
-	AddSensorData(ctx context.Context, sensorData []*domain.SensorData) error
+	// Retrieves sensor data grouped in buckets of the given interval (e.g. 1m, 1h, 1d) and aggregated with the given function
+	GetAggregatedSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval string, aggregate string) ([]domain.SensorData, error)
+	// Retrieves the count, min, max, mean, standard deviation and percentiles of sensor data, over the whole range or grouped by day or week
+	GetSensorDataStats(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, groupBy string) ([]domain.SensorDataStats, error)
+	// Retrieves the ranges without readings for longer than the expected interval (e.g. 5m, defaults to the sensor's
+	// reporting interval) and the coverage of the time range
+	GetSensorDataGaps(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval string) (*domain.SensorDataCompleteness, error)
+	// Retrieves sensor data downsampled with LTTB to at most maxPoints points
+	GetDownsampledSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, maxPoints int) ([]domain.SensorData, error)
+	// Retrieves one page of sensor data after the given opaque cursor, and the cursor for the next page (empty on the last page)
+	GetSensorDataPage(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, cursor string, limit int) ([]domain.SensorData, string, error)
+	// Streams sensor data within a time interval to fn, one reading at a time
+	ExportSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, fn func(data domain.SensorData) error) error
+	// Retrieves the name, category, unit, precision and reporting interval of a sensor
+	GetSensorMetadata(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorMetadata, error)
+	// Retrieves the conversion from the unit of the sensor's category to the given unit, for values read with the aggregate (empty for raw reads)
+	GetUnitConversion(ctx context.Context, sensorUuid uuid.UUID, unit string, aggregate string) (*units.Conversion, error)
+	// Imports readings from a CSV file, storing the valid rows in batches and reporting the invalid ones
+	ImportSensorData(ctx context.Context, file io.Reader, sensorUuid uuid.UUID, userUuid uuid.UUID, onConflict string) (*domain.ImportReport, error)
+	// Retrieves the aggregated data of several sensors aligned on a common timestamp axis, with nil values for gaps
+	GetAlignedSensorData(ctx context.Context, sensorUuids []uuid.UUID, from, to time.Time, interval string, aggregate string) (*domain.AlignedSensorData, error)
+	// Retrieves the most recent reading of each sensor
+	GetLatestSensorData(ctx context.Context, sensorUuids []uuid.UUID) ([]domain.SensorData, error)
+	// Checks that every sensor exists and is public or belongs to the user, hidden sensors are reported as not found
+	CheckSensorsVisible(ctx context.Context, userUuid uuid.UUID, sensorUuids ...uuid.UUID) error
+	// Checks that the sensor exists and belongs to the user
+	CheckSensorOwner(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) error
+	// Add sensor data, applying the conflict policy (reject, ignore or overwrite) to readings that already exist.
+	// Returns the number of readings stored, without the ones ignored.
+	AddSensorData(ctx context.Context, sensorData []*domain.SensorData, onConflict string) (int, error)
+}
+
+// Maximum number of readings returned in a single page
+const MaxPageLimit = 10000
+
+// Maximum number of sensors in a single latest read
+const MaxLatestSensors = 100
+
+// Units accepted for bucket intervals
+var intervalUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// Parses a bucket interval such as "30s", "1m", "1h" or "1d"
+func ParseInterval(interval string) (time.Duration, error) {
+	interval = strings.TrimSpace(interval)
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("%w: %q", domain.ErrInvalidInterval, interval)
+	}
+
+	unit, ok := intervalUnits[interval[len(interval)-1:]]
+	if !ok {
+		return 0, fmt.Errorf("%w unit: %q (must be s, m, h, d or w)", domain.ErrInvalidInterval, interval)
+	}
+
+	amount, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || amount <= 0 {
+		return 0, fmt.Errorf("%w amount: %q", domain.ErrInvalidInterval, interval)
+	}
+
+	return time.Duration(amount) * unit, nil
+}
+
+// Receives the readings after they're stored by the sensor data service
+type SensorDataListener interface {
+	// Called with the readings stored on each successful add
+	OnSensorData(ctx context.Context, sensorData []*domain.SensorData)
+}
+
+// Implemented by listeners that only react to readings as they're received (e.g. alerting),
+// they aren't notified of the readings imported from a file
+type LiveSensorDataListener interface {
+	SensorDataListener
+	// Reports whether imported readings are left out
+	SkipsImportedData() bool
+}
+
+// Inspects the readings before they're stored
+type SensorDataAnalyzer interface {
+	// Called with the validated readings of each add, it can flag them (e.g. as anomalies)
+	Analyze(ctx context.Context, sensorData []*domain.SensorData)
 }
 
 // Handles sensor's data logic and interaction with the repository
 type SensorDataServiceImpl struct {
-	Repo repository.SensorDataRepository
+	Repo      repository.SensorDataRepository
+	Analyzer  SensorDataAnalyzer
+	Listeners []SensorDataListener
+}
+
+// Creates the service, the analyzer is optional (nil to store the readings as received)
+func NewSensorDataService(repo repository.SensorDataRepository, analyzer SensorDataAnalyzer, listeners ...SensorDataListener) SensorDataService {
+	return &SensorDataServiceImpl{
+		Repo:      repo,
+		Analyzer:  analyzer,
+		Listeners: listeners,
+	}
+}
+
+// Runs the analyzer, if any, on the readings about to be stored
+func (s *SensorDataServiceImpl) analyze(ctx context.Context, sensorData []*domain.SensorData) {
+	if s.Analyzer != nil {
+		s.Analyzer.Analyze(ctx, sensorData)
+	}
+}
+
+// Notifies every listener of the stored readings, readings ignored as duplicates are left out beforehand
+func (s *SensorDataServiceImpl) notify(ctx context.Context, sensorData []*domain.SensorData) {
+	if len(sensorData) == 0 {
+		return
+	}
+	for _, listener := range s.Listeners {
+		listener.OnSensorData(ctx, sensorData)
+	}
+}
+
+// Notifies the listeners of the stored readings of an import, except those that only react to live readings
+func (s *SensorDataServiceImpl) notifyImported(ctx context.Context, sensorData []*domain.SensorData) {
+	if len(sensorData) == 0 {
+		return
+	}
+	for _, listener := range s.Listeners {
+		if live, ok := listener.(LiveSensorDataListener); ok && live.SkipsImportedData() {
+			continue
+		}
+		listener.OnSensorData(ctx, sensorData)
+	}
+}
+
+// Checks the conflict policy, defaulting to reject when empty
+func validateConflictPolicy(onConflict string) (string, error) {
+	switch onConflict {
+	case "":
+		return domain.SENSOR_DATA_CONFLICT_REJECT, nil
+	case domain.SENSOR_DATA_CONFLICT_REJECT, domain.SENSOR_DATA_CONFLICT_IGNORE, domain.SENSOR_DATA_CONFLICT_OVERWRITE:
+		return onConflict, nil
+	}
+	return "", domain.ErrInvalidConflictPolicy
+}
+
+// Checks the quality of a reading, returning it lowercased (ok when empty)
+func ParseQuality(quality string) (string, error) {
+	quality = strings.ToLower(strings.TrimSpace(quality))
+	switch quality {
+	case "":
+		return domain.SENSOR_DATA_QUALITY_OK, nil
+	case domain.SENSOR_DATA_QUALITY_OK, domain.SENSOR_DATA_QUALITY_ESTIMATED,
+		domain.SENSOR_DATA_QUALITY_SUSPECT, domain.SENSOR_DATA_QUALITY_MISSING:
+		return quality, nil
+	}
+	return "", fmt.Errorf("%w: %q (must be ok, estimated, suspect or missing)", domain.ErrInvalidQuality, quality)
+}
+
+// Checks the readings before storing them, whatever the way they were received
+func validateSensorData(sensorData []*domain.SensorData) error {
+	if len(sensorData) == 0 {
+		return errors.New("at least one sensor data is required")
+	}
+
+	for _, data := range sensorData {
+		if data.SensorUuid == uuid.NilUUID {
+			return errors.New("sensor uuid is required")
+		}
+		if data.Timestamp.IsZero() {
+			return errors.New("timestamp is required")
+		}
+		if math.IsNaN(data.Value) || math.IsInf(data.Value, 0) {
+			return errors.New("value must be a finite number")
+		}
+		var err error
+		if data.Quality, err = ParseQuality(data.Quality); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checks that no two readings are for the same sensor and timestamp, which the reject policy refuses
+// like a reading that already exists (the other policies keep the last one sent)
+func checkRepeatedReadings(sensorData []*domain.SensorData) error {
+	type readingKey struct {
+		sensorUuid uuid.UUID
+		timestamp  int64
+	}
+
+	var seen = make(map[readingKey]bool, len(sensorData))
+	for _, data := range sensorData {
+		var key = readingKey{data.SensorUuid, data.Timestamp.UnixNano()}
+		if seen[key] {
+			return fmt.Errorf("%w (repeated reading at %s)", domain.ErrDuplicateSensorData, data.Timestamp.Format(time.RFC3339Nano))
+		}
+		seen[key] = true
+	}
+	return nil
 }
 
-func NewSensorDataService(repo repository.SensorDataRepository) SensorDataService {
-	return &SensorDataServiceImpl{Repo: repo}
+// Checks that the reading is within the value range of its sensor's category.
+// Missing readings are placeholders, whatever value they hold.
+func checkValueRange(data *domain.SensorData, valueRange domain.ValueRange) error {
+	if data.Quality != domain.SENSOR_DATA_QUALITY_MISSING && !valueRange.Contains(data.Value) {
+		return fmt.Errorf("%w: %v at %s", domain.ErrValueOutOfRange, data.Value, data.Timestamp.Format(time.RFC3339))
+	}
+	return nil
 }
 
-func (s *SensorDataServiceImpl) AddSensorData(ctx context.Context, sensorData []*domain.SensorData) error {
-	var err = s.Repo.AddSensorData(ctx, sensorData)
+// Checks the readings against the value ranges of their sensors' categories
+func (s *SensorDataServiceImpl) checkValueRanges(ctx context.Context, sensorData []*domain.SensorData) error {
+	var sensorUuids []uuid.UUID
+	var seen = map[uuid.UUID]bool{}
+	for _, data := range sensorData {
+		if !seen[data.SensorUuid] {
+			seen[data.SensorUuid] = true
+			sensorUuids = append(sensorUuids, data.SensorUuid)
+		}
+	}
+
+	ranges, err := s.Repo.GetSensorValueRanges(ctx, sensorUuids)
 	if err != nil {
-		return fmt.Errorf("failed to add sensor data")
+		return fmt.Errorf("failed to check sensor data")
+	}
+
+	for _, data := range sensorData {
+		if err := checkValueRange(data, ranges[data.SensorUuid]); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+func (s *SensorDataServiceImpl) AddSensorData(ctx context.Context, sensorData []*domain.SensorData, onConflict string) (int, error) {
+	var err error
+	if onConflict, err = validateConflictPolicy(onConflict); err != nil {
+		return 0, err
+	}
+
+	if err = validateSensorData(sensorData); err != nil {
+		return 0, err
+	}
+
+	if err = s.checkValueRanges(ctx, sensorData); err != nil {
+		return 0, err
+	}
+
+	if onConflict == domain.SENSOR_DATA_CONFLICT_REJECT {
+		if err = checkRepeatedReadings(sensorData); err != nil {
+			return 0, err
+		}
+	}
+
+	s.analyze(ctx, sensorData)
+
+	stored, err := s.Repo.AddSensorData(ctx, sensorData, onConflict)
+	if err != nil {
+		if errors.Is(err, domain.ErrDuplicateSensorData) {
+			return 0, err
+		}
+		return 0, fmt.Errorf("failed to add sensor data")
+	}
+
+	s.notify(ctx, stored)
+	return len(stored), nil
+}
+
 func (s *SensorDataServiceImpl) GetSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time) ([]domain.SensorData, error) {
 	var sensorData, err = s.Repo.GetSensorData(ctx, sensorUuid, from, to)
 	if err != nil {
@@ -42,3 +296,293 @@ func (s *SensorDataServiceImpl) GetSensorData(ctx context.Context, sensorUuid uu
 	}
 	return sensorData, nil
 }
+
+func (s *SensorDataServiceImpl) GetAggregatedSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval string, aggregate string) ([]domain.SensorData, error) {
+	var bucket, err = ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	// Defaults to the average of each bucket
+	if aggregate == "" {
+		aggregate = domain.SENSOR_DATA_AGGREGATE_AVG
+	}
+
+	switch aggregate {
+	case domain.SENSOR_DATA_AGGREGATE_MIN, domain.SENSOR_DATA_AGGREGATE_MAX, domain.SENSOR_DATA_AGGREGATE_AVG,
+		domain.SENSOR_DATA_AGGREGATE_SUM, domain.SENSOR_DATA_AGGREGATE_COUNT,
+		domain.SENSOR_DATA_AGGREGATE_FIRST, domain.SENSOR_DATA_AGGREGATE_LAST:
+	default:
+		return nil, domain.ErrInvalidAggregate
+	}
+
+	sensorData, err := s.Repo.GetAggregatedSensorData(ctx, sensorUuid, from, to, bucket, aggregate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregated sensor data")
+	}
+	return sensorData, nil
+}
+
+func (s *SensorDataServiceImpl) GetSensorDataGaps(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval string) (*domain.SensorDataCompleteness, error) {
+	var expected time.Duration
+	var err error
+	if interval != "" {
+		if expected, err = ParseInterval(interval); err != nil {
+			return nil, err
+		}
+	} else {
+		metadata, err := s.Repo.GetSensorMetadata(ctx, sensorUuid)
+		if err != nil {
+			if errors.Is(err, domain.ErrSensorNotFound) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to read sensor")
+		}
+		if metadata.ReportingInterval <= 0 {
+			return nil, domain.ErrNoReportingInterval
+		}
+		expected = time.Duration(metadata.ReportingInterval) * time.Second
+	}
+
+	gaps, err := s.Repo.GetSensorDataGaps(ctx, sensorUuid, from, to, expected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sensor data gaps")
+	}
+
+	return &domain.SensorDataCompleteness{
+		Interval: expected,
+		Gaps:     gaps,
+		Coverage: coverage(from, to, gaps),
+	}, nil
+}
+
+// Fraction of the time range that isn't within a gap (1 for an empty range)
+func coverage(from, to time.Time, gaps []domain.SensorDataGap) float64 {
+	var total = to.Sub(from)
+	if total <= 0 {
+		return 1
+	}
+
+	var missing time.Duration
+	for _, gap := range gaps {
+		missing += gap.End.Sub(gap.Start)
+	}
+	return 1 - float64(missing)/float64(total)
+}
+
+func (s *SensorDataServiceImpl) GetDownsampledSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, maxPoints int) ([]domain.SensorData, error) {
+	if maxPoints < 3 {
+		return nil, domain.ErrInvalidMaxPoints
+	}
+
+	var sensorData, err = s.GetSensorData(ctx, sensorUuid, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	// LTTB keeps the extremes of the series, so placeholders of missing readings would be kept as peaks
+	var readings = make([]domain.SensorData, 0, len(sensorData))
+	for _, data := range sensorData {
+		if data.Quality != domain.SENSOR_DATA_QUALITY_MISSING {
+			readings = append(readings, data)
+		}
+	}
+
+	return DownsampleLTTB(readings, maxPoints), nil
+}
+
+// Encodes the cursor as an opaque URL-safe string
+func encodeCursor(cursor *domain.SensorDataCursor) (string, error) {
+	var data, err = json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decodes a cursor previously returned by encodeCursor
+func decodeCursor(cursor string) (*domain.SensorDataCursor, error) {
+	var data, err = base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, domain.ErrInvalidCursor
+	}
+
+	var decoded domain.SensorDataCursor
+	if err = json.Unmarshal(data, &decoded); err != nil || decoded.Timestamp.IsZero() {
+		return nil, domain.ErrInvalidCursor
+	}
+	return &decoded, nil
+}
+
+func (s *SensorDataServiceImpl) GetSensorDataPage(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, cursor string, limit int) ([]domain.SensorData, string, error) {
+	if limit <= 0 || limit > MaxPageLimit {
+		return nil, "", fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+
+	var position *domain.SensorDataCursor
+	var err error
+	if cursor != "" {
+		if position, err = decodeCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	sensorData, next, err := s.Repo.GetSensorDataPage(ctx, sensorUuid, from, to, position, limit)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read sensor data")
+	}
+
+	if next == nil {
+		return sensorData, "", nil
+	}
+
+	nextCursor, err := encodeCursor(next)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode cursor: %v", err)
+	}
+
+	return sensorData, nextCursor, nil
+}
+
+func (s *SensorDataServiceImpl) ExportSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, fn func(data domain.SensorData) error) error {
+	var err = s.Repo.StreamSensorData(ctx, sensorUuid, from, to, fn)
+	if err != nil {
+		return fmt.Errorf("failed to export sensor data: %v", err)
+	}
+	return nil
+}
+
+func (s *SensorDataServiceImpl) GetSensorMetadata(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorMetadata, error) {
+	var metadata, err = s.Repo.GetSensorMetadata(ctx, sensorUuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrSensorNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read sensor")
+	}
+	return metadata, nil
+}
+
+func (s *SensorDataServiceImpl) GetUnitConversion(ctx context.Context, sensorUuid uuid.UUID, unit string, aggregate string) (*units.Conversion, error) {
+	// Counts have no unit, and sums can't be shifted to another zero point without knowing how many values were added
+	if aggregate == domain.SENSOR_DATA_AGGREGATE_COUNT {
+		return nil, fmt.Errorf("%w: 'unit' cannot be used with the count aggregate", domain.ErrUnitNotConvertible)
+	}
+
+	var metadata, err = s.Repo.GetSensorMetadata(ctx, sensorUuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrSensorNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to read sensor")
+	}
+
+	conversion, err := units.NewConversion(metadata.Unit, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	if aggregate == domain.SENSOR_DATA_AGGREGATE_SUM && !conversion.Scales() {
+		return nil, fmt.Errorf("%w: sums cannot be converted from %s to %s", domain.ErrUnitNotConvertible, conversion.From, conversion.To)
+	}
+
+	return &conversion, nil
+}
+
+// Checks the period statistics are grouped by, returning it lowercased (empty when not grouped)
+func ParseGroupBy(groupBy string) (string, error) {
+	groupBy = strings.ToLower(strings.TrimSpace(groupBy))
+	switch groupBy {
+	case "", domain.SENSOR_DATA_GROUP_DAY, domain.SENSOR_DATA_GROUP_WEEK:
+		return groupBy, nil
+	}
+	return "", errors.New("invalid groupBy: must be day or week")
+}
+
+func (s *SensorDataServiceImpl) GetSensorDataStats(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, groupBy string) ([]domain.SensorDataStats, error) {
+	var err error
+	if groupBy, err = ParseGroupBy(groupBy); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.Repo.GetSensorDataStats(ctx, sensorUuid, from, to, groupBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sensor data stats")
+	}
+
+	// The whole range always has its stats, even without readings
+	if groupBy == "" && len(stats) == 0 {
+		stats = []domain.SensorDataStats{{Start: from}}
+	}
+	return stats, nil
+}
+
+// Converts the values of the statistics in place
+func ConvertSensorDataStats(stats []domain.SensorDataStats, conversion *units.Conversion) {
+	var apply = func(value *float64, convert func(float64) float64) *float64 {
+		if value == nil {
+			return nil
+		}
+		var converted = convert(*value)
+		return &converted
+	}
+
+	for i := range stats {
+		stats[i].Min = apply(stats[i].Min, conversion.Apply)
+		stats[i].Max = apply(stats[i].Max, conversion.Apply)
+		stats[i].Mean = apply(stats[i].Mean, conversion.Apply)
+		stats[i].StdDev = apply(stats[i].StdDev, conversion.ApplyDelta)
+		stats[i].P50 = apply(stats[i].P50, conversion.Apply)
+		stats[i].P95 = apply(stats[i].P95, conversion.Apply)
+		stats[i].P99 = apply(stats[i].P99, conversion.Apply)
+	}
+}
+
+// Converts the values of the readings in place
+func ConvertSensorData(sensorData []domain.SensorData, conversion *units.Conversion) {
+	for i := range sensorData {
+		sensorData[i].Value = conversion.Apply(sensorData[i].Value)
+	}
+}
+
+func (s *SensorDataServiceImpl) CheckSensorsVisible(ctx context.Context, userUuid uuid.UUID, sensorUuids ...uuid.UUID) error {
+	var visible, err = s.Repo.GetVisibleSensors(ctx, sensorUuids, userUuid)
+	if err != nil {
+		return fmt.Errorf("failed to check sensor visibility")
+	}
+
+	// Private sensors of other users aren't disclosed, they're not found like unknown ones
+	for _, sensorUuid := range sensorUuids {
+		if !visible[sensorUuid] {
+			return fmt.Errorf("%w: %s", domain.ErrSensorNotFound, sensorUuid.String())
+		}
+	}
+	return nil
+}
+
+func (s *SensorDataServiceImpl) CheckSensorOwner(ctx context.Context, sensorUuid uuid.UUID, userUuid uuid.UUID) error {
+	var ownerUuid, err = s.Repo.GetSensorOwner(ctx, sensorUuid)
+	if err != nil {
+		if errors.Is(err, domain.ErrSensorNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to check sensor owner")
+	}
+
+	if ownerUuid != userUuid {
+		return domain.ErrNotSensorOwner
+	}
+	return nil
+}
+
+func (s *SensorDataServiceImpl) GetLatestSensorData(ctx context.Context, sensorUuids []uuid.UUID) ([]domain.SensorData, error) {
+	if len(sensorUuids) == 0 || len(sensorUuids) > MaxLatestSensors {
+		return nil, fmt.Errorf("%w: between 1 and %d sensors are required", domain.ErrInvalidSensorCount, MaxLatestSensors)
+	}
+
+	var sensorData, err = s.Repo.GetLatestSensorData(ctx, sensorUuids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latest sensor data")
+	}
+	return sensorData, nil
+}
@@ -0,0 +1,527 @@
+package usecase
+
+import (
+	"api/internal/sensors_data/domain"
+	"api/internal/sensors_data/repository"
+	"api/internal/units"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Repository returning fixed value ranges and recording the stored readings
+type fakeRepository struct {
+	repository.SensorDataRepository
+	ranges map[uuid.UUID]domain.ValueRange
+	stored []*domain.SensorData
+	unit   string
+	gaps   []domain.SensorDataGap
+	// Reporting interval of every sensor, in seconds
+	reportingInterval int
+	// Bucket and aggregate of the last aggregated read
+	bucket    time.Duration
+	aggregate string
+	// Unix timestamps already stored, rejected or ignored depending on the conflict policy
+	existing map[int64]bool
+	// Owner of each sensor
+	owners map[uuid.UUID]uuid.UUID
+	// Sensors the user can read
+	visible map[uuid.UUID]bool
+	// Error returned by the metadata read
+	metadataErr error
+	// Cursor given to the last page read, and the one it returns
+	cursor *domain.SensorDataCursor
+	next   *domain.SensorDataCursor
+	// Readings returned by raw reads
+	readings []domain.SensorData
+	// Error returned by the adds
+	addErr error
+}
+
+func (r *fakeRepository) GetSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time) ([]domain.SensorData, error) {
+	return r.readings, nil
+}
+
+func (r *fakeRepository) GetSensorDataPage(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, cursor *domain.SensorDataCursor, limit int) ([]domain.SensorData, *domain.SensorDataCursor, error) {
+	r.cursor = cursor
+	return nil, r.next, nil
+}
+
+func (r *fakeRepository) GetAggregatedSensorData(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, bucket time.Duration, aggregate string) ([]domain.SensorData, error) {
+	r.bucket, r.aggregate = bucket, aggregate
+	return nil, nil
+}
+
+func (r *fakeRepository) GetSensorDataStats(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, groupBy string) ([]domain.SensorDataStats, error) {
+	return nil, nil
+}
+
+func (r *fakeRepository) GetSensorDataGaps(ctx context.Context, sensorUuid uuid.UUID, from, to time.Time, interval time.Duration) ([]domain.SensorDataGap, error) {
+	return r.gaps, nil
+}
+
+func (r *fakeRepository) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	if owner, ok := r.owners[sensorUuid]; ok {
+		return owner, nil
+	}
+	return uuid.NilUUID, domain.ErrSensorNotFound
+}
+
+func (r *fakeRepository) GetVisibleSensors(ctx context.Context, sensorUuids []uuid.UUID, userUuid uuid.UUID) (map[uuid.UUID]bool, error) {
+	return r.visible, nil
+}
+
+func (r *fakeRepository) GetSensorMetadata(ctx context.Context, sensorUuid uuid.UUID) (*domain.SensorMetadata, error) {
+	if r.metadataErr != nil {
+		return nil, r.metadataErr
+	}
+	return &domain.SensorMetadata{SensorUuid: sensorUuid, Unit: r.unit, ReportingInterval: r.reportingInterval}, nil
+}
+
+func (r *fakeRepository) GetSensorValueRanges(ctx context.Context, sensorUuids []uuid.UUID) (map[uuid.UUID]domain.ValueRange, error) {
+	return r.ranges, nil
+}
+
+func (r *fakeRepository) AddSensorData(ctx context.Context, sensorData []*domain.SensorData, onConflict string) ([]*domain.SensorData, error) {
+	if r.addErr != nil {
+		return nil, r.addErr
+	}
+	var stored []*domain.SensorData
+	for _, data := range sensorData {
+		if !r.existing[data.Timestamp.Unix()] || onConflict == domain.SENSOR_DATA_CONFLICT_OVERWRITE {
+			stored = append(stored, data)
+		} else if onConflict == domain.SENSOR_DATA_CONFLICT_REJECT {
+			return nil, domain.ErrDuplicateSensorData
+		}
+	}
+	if r.existing == nil {
+		r.existing = make(map[int64]bool)
+	}
+	for _, data := range stored {
+		r.existing[data.Timestamp.Unix()] = true
+	}
+	r.stored = append(r.stored, stored...)
+	return stored, nil
+}
+
+func TestParseInterval(t *testing.T) {
+	valid := map[string]time.Duration{
+		"30s": 30 * time.Second,
+		"1m":  time.Minute,
+		"6h":  6 * time.Hour,
+		"1d":  24 * time.Hour,
+		"2w":  14 * 24 * time.Hour,
+	}
+	for interval, want := range valid {
+		if got, err := ParseInterval(interval); err != nil || got != want {
+			t.Errorf("%s: expected %v, got %v, %v", interval, want, got, err)
+		}
+	}
+
+	for _, interval := range []string{"", "m", "5", "1y", "0h", "-1m", "1.5h"} {
+		if _, err := ParseInterval(interval); !errors.Is(err, domain.ErrInvalidInterval) {
+			t.Errorf("%q: expected %v, got %v", interval, domain.ErrInvalidInterval, err)
+		}
+	}
+}
+
+func TestGetAggregatedSensorData(t *testing.T) {
+	repo := &fakeRepository{}
+	service := &SensorDataServiceImpl{Repo: repo}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	// The average is used when no aggregate is given
+	if _, err := service.GetAggregatedSensorData(context.Background(), uuid.NewV4(), from, to, "15m", ""); err != nil {
+		t.Fatal(err)
+	}
+	if repo.bucket != 15*time.Minute || repo.aggregate != domain.SENSOR_DATA_AGGREGATE_AVG {
+		t.Errorf("expected 15m buckets averaged, got %v %s", repo.bucket, repo.aggregate)
+	}
+
+	if _, err := service.GetAggregatedSensorData(context.Background(), uuid.NewV4(), from, to, "1h", "median"); !errors.Is(err, domain.ErrInvalidAggregate) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidAggregate, err)
+	}
+	if _, err := service.GetAggregatedSensorData(context.Background(), uuid.NewV4(), from, to, "1x", "max"); !errors.Is(err, domain.ErrInvalidInterval) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidInterval, err)
+	}
+}
+
+func TestAddSensorDataValueRange(t *testing.T) {
+	humidity, power := uuid.NewV4(), uuid.NewV4()
+	zero, hundred := 0.0, 100.0
+	repo := &fakeRepository{ranges: map[uuid.UUID]domain.ValueRange{
+		humidity: {Min: &zero, Max: &hundred},
+		power:    {},
+	}}
+	service := &SensorDataServiceImpl{Repo: repo}
+	now := time.Now().UTC()
+
+	_, err := service.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: humidity, Timestamp: now, Value: 100},
+		{SensorUuid: power, Timestamp: now, Value: 1e6},
+	}, "")
+	if err != nil {
+		t.Fatalf("expected readings within range to be stored, got %v", err)
+	}
+
+	_, err = service.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: power, Timestamp: now, Value: 5},
+		{SensorUuid: humidity, Timestamp: now, Value: 104.5},
+	}, "")
+	if !errors.Is(err, domain.ErrValueOutOfRange) {
+		t.Fatalf("expected %v, got %v", domain.ErrValueOutOfRange, err)
+	}
+	if len(repo.stored) != 2 {
+		t.Errorf("expected the rejected batch not to be stored, got %d readings", len(repo.stored))
+	}
+}
+
+func TestAddSensorDataQuality(t *testing.T) {
+	humidity := uuid.NewV4()
+	zero, hundred := 0.0, 100.0
+	repo := &fakeRepository{ranges: map[uuid.UUID]domain.ValueRange{humidity: {Min: &zero, Max: &hundred}}}
+	service := &SensorDataServiceImpl{Repo: repo}
+	now := time.Now().UTC()
+
+	// Missing readings are placeholders, their value isn't checked against the range
+	_, err := service.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: humidity, Timestamp: now, Value: 40},
+		{SensorUuid: humidity, Timestamp: now.Add(time.Minute), Value: 40, Quality: "Estimated"},
+		{SensorUuid: humidity, Timestamp: now.Add(2 * time.Minute), Value: -1, Quality: domain.SENSOR_DATA_QUALITY_MISSING},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{domain.SENSOR_DATA_QUALITY_OK, domain.SENSOR_DATA_QUALITY_ESTIMATED, domain.SENSOR_DATA_QUALITY_MISSING}
+	for i, data := range repo.stored {
+		if data.Quality != want[i] {
+			t.Errorf("reading %d: expected quality %s, got %s", i, want[i], data.Quality)
+		}
+	}
+
+	_, err = service.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: humidity, Timestamp: now, Value: 40, Quality: "bad"},
+	}, "")
+	if !errors.Is(err, domain.ErrInvalidQuality) {
+		t.Errorf("expected an unknown quality to be rejected, got %v", err)
+	}
+}
+
+func TestGetSensorDataGaps(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(10 * time.Hour)
+	repo := &fakeRepository{gaps: []domain.SensorDataGap{
+		{Start: from, End: from.Add(time.Hour)},
+		{Start: from.Add(5 * time.Hour), End: from.Add(6 * time.Hour)},
+	}}
+	service := &SensorDataServiceImpl{Repo: repo}
+
+	completeness, err := service.GetSensorDataGaps(context.Background(), uuid.NewV4(), from, to, "5m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completeness.Interval != 5*time.Minute || len(completeness.Gaps) != 2 || completeness.Coverage != 0.8 {
+		t.Errorf("expected two gaps covering 80%% of the range, got %+v", completeness)
+	}
+
+	if _, err = service.GetSensorDataGaps(context.Background(), uuid.NewV4(), from, to, "5"); err == nil {
+		t.Error("expected an interval without unit to be rejected")
+	}
+
+	// Without interval, the sensor's reporting interval is used if it has one
+	if _, err = service.GetSensorDataGaps(context.Background(), uuid.NewV4(), from, to, ""); !errors.Is(err, domain.ErrNoReportingInterval) {
+		t.Errorf("expected %v, got %v", domain.ErrNoReportingInterval, err)
+	}
+	repo.reportingInterval = 60
+	completeness, err = service.GetSensorDataGaps(context.Background(), uuid.NewV4(), from, to, "")
+	if err != nil || completeness.Interval != time.Minute {
+		t.Errorf("expected the sensor's interval, got %+v, %v", completeness, err)
+	}
+}
+
+func TestGetUnitConversion(t *testing.T) {
+	service := &SensorDataServiceImpl{Repo: &fakeRepository{unit: "°C"}}
+	ctx := context.Background()
+	sensorUuid := uuid.NewV4()
+
+	conversion, err := service.GetUnitConversion(ctx, sensorUuid, "F", domain.SENSOR_DATA_AGGREGATE_MAX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []domain.SensorData{{Value: 0}, {Value: 100}}
+	ConvertSensorData(data, conversion)
+	if conversion.To != "°F" || data[0].Value != 32 || data[1].Value != 212 {
+		t.Errorf("unexpected conversion to %s: %+v", conversion.To, data)
+	}
+
+	// Sums of temperatures can't be shifted to another scale, counts have no unit
+	if _, err = service.GetUnitConversion(ctx, sensorUuid, "°F", domain.SENSOR_DATA_AGGREGATE_SUM); !errors.Is(err, domain.ErrUnitNotConvertible) {
+		t.Errorf("expected sums not to be converted between temperature scales, got %v", err)
+	}
+	if _, err = service.GetUnitConversion(ctx, sensorUuid, "°F", domain.SENSOR_DATA_AGGREGATE_COUNT); !errors.Is(err, domain.ErrUnitNotConvertible) {
+		t.Errorf("expected counts not to be converted, got %v", err)
+	}
+	if _, err = service.GetUnitConversion(ctx, sensorUuid, "hPa", ""); !errors.Is(err, units.ErrIncompatibleUnits) {
+		t.Errorf("expected temperatures not to be converted to a pressure, got %v", err)
+	}
+
+	// A failing database is neither a missing sensor nor an invalid request
+	service.Repo = &fakeRepository{metadataErr: errors.New("connection refused")}
+	_, err = service.GetUnitConversion(ctx, sensorUuid, "°F", "")
+	if err == nil || errors.Is(err, domain.ErrSensorNotFound) || errors.Is(err, domain.ErrUnitNotConvertible) {
+		t.Errorf("expected a read failure, got %v", err)
+	}
+}
+
+func TestGetSensorDataStats(t *testing.T) {
+	service := &SensorDataServiceImpl{Repo: &fakeRepository{}}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	// A range without readings still has its stats, a grouping has no periods
+	stats, err := service.GetSensorDataStats(context.Background(), uuid.NewV4(), from, to, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 || !stats[0].Start.Equal(from) || stats[0].Count != 0 || stats[0].Mean != nil {
+		t.Errorf("expected empty stats of the range, got %+v", stats)
+	}
+
+	stats, err = service.GetSensorDataStats(context.Background(), uuid.NewV4(), from, to, "Week")
+	if err != nil || len(stats) != 0 {
+		t.Errorf("expected no periods, got %+v, %v", stats, err)
+	}
+
+	if _, err = service.GetSensorDataStats(context.Background(), uuid.NewV4(), from, to, "month"); err == nil {
+		t.Error("expected grouping by month to be rejected")
+	}
+}
+
+func TestConvertSensorDataStats(t *testing.T) {
+	conversion, _ := units.NewConversion("°C", "°F")
+	mean, stdDev := 20.0, 5.0
+	stats := []domain.SensorDataStats{{Count: 3, Mean: &mean, StdDev: &stdDev}}
+
+	ConvertSensorDataStats(stats, &conversion)
+
+	// The standard deviation is a difference, it's scaled without the offset
+	if *stats[0].Mean != 68 || *stats[0].StdDev != 9 || stats[0].Min != nil {
+		t.Errorf("unexpected converted stats %+v", stats[0])
+	}
+	if mean != 20 {
+		t.Errorf("expected the original values to be left untouched, got %v", mean)
+	}
+}
+
+// Analyzer flagging every reading above a limit
+type limitAnalyzer struct {
+	limit float64
+}
+
+func (a *limitAnalyzer) Analyze(ctx context.Context, sensorData []*domain.SensorData) {
+	for _, data := range sensorData {
+		data.Anomaly = data.Value > a.limit
+	}
+}
+
+func TestAddSensorDataAnalyzesBeforeStoring(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	repo := &fakeRepository{ranges: map[uuid.UUID]domain.ValueRange{sensorUuid: {}}}
+	service := NewSensorDataService(repo, &limitAnalyzer{limit: 50})
+	now := time.Now().UTC()
+
+	_, err := service.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: sensorUuid, Timestamp: now, Value: 20},
+		{SensorUuid: sensorUuid, Timestamp: now.Add(time.Second), Value: 90},
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.stored) != 2 || repo.stored[0].Anomaly || !repo.stored[1].Anomaly {
+		t.Errorf("expected the readings to be stored with their anomaly flag, got %+v", repo.stored)
+	}
+}
+
+func TestGetDownsampledSensorDataMaxPoints(t *testing.T) {
+	service := &SensorDataServiceImpl{Repo: &fakeRepository{}}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := service.GetDownsampledSensorData(context.Background(), uuid.NewV4(), from, from.Add(time.Hour), 2); !errors.Is(err, domain.ErrInvalidMaxPoints) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidMaxPoints, err)
+	}
+}
+
+func TestGetDownsampledSensorDataSkipsMissing(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var readings []domain.SensorData
+	for i := 0; i < 10; i++ {
+		readings = append(readings, domain.SensorData{Timestamp: from.Add(time.Duration(i) * time.Minute), Value: 20, Quality: domain.SENSOR_DATA_QUALITY_OK})
+	}
+	// A placeholder far from the other values would be kept as a peak
+	readings[5].Value, readings[5].Quality = -1000, domain.SENSOR_DATA_QUALITY_MISSING
+	service := &SensorDataServiceImpl{Repo: &fakeRepository{readings: readings}}
+
+	sampled, err := service.GetDownsampledSensorData(context.Background(), uuid.NewV4(), from, from.Add(time.Hour), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range sampled {
+		if data.Quality == domain.SENSOR_DATA_QUALITY_MISSING {
+			t.Fatalf("expected missing readings to be left out, got %+v", sampled)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := &domain.SensorDataCursor{Timestamp: time.Date(2025, 1, 1, 12, 30, 0, 500, time.UTC), Rank: 3}
+
+	encoded, err := encodeCursor(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := decodeCursor(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Timestamp.Equal(cursor.Timestamp) || decoded.Rank != cursor.Rank {
+		t.Errorf("expected %+v, got %+v", cursor, decoded)
+	}
+
+	// Not base64, not JSON, and JSON without timestamp
+	for _, malformed := range []string{"not a cursor!", "bm90IGpzb24", "eyJyIjoxfQ"} {
+		if _, err := decodeCursor(malformed); !errors.Is(err, domain.ErrInvalidCursor) {
+			t.Errorf("%q: expected %v, got %v", malformed, domain.ErrInvalidCursor, err)
+		}
+	}
+}
+
+func TestGetSensorDataPage(t *testing.T) {
+	repo := &fakeRepository{next: &domain.SensorDataCursor{Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rank: 1}}
+	service := &SensorDataServiceImpl{Repo: repo}
+	ctx := context.Background()
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	// The first page starts without cursor, the cursor it returns resumes where it stopped
+	_, next, err := service.GetSensorDataPage(ctx, uuid.NewV4(), from, to, "", 100)
+	if err != nil || next == "" || repo.cursor != nil {
+		t.Fatalf("expected a next cursor, got %q, %v", next, err)
+	}
+	repo.next = nil
+	_, last, err := service.GetSensorDataPage(ctx, uuid.NewV4(), from, to, next, 100)
+	if err != nil || last != "" || repo.cursor == nil || repo.cursor.Rank != 1 {
+		t.Errorf("expected the last page to resume from the cursor, got %q, %+v, %v", last, repo.cursor, err)
+	}
+
+	if _, _, err = service.GetSensorDataPage(ctx, uuid.NewV4(), from, to, "garbage", 100); !errors.Is(err, domain.ErrInvalidCursor) {
+		t.Errorf("expected %v, got %v", domain.ErrInvalidCursor, err)
+	}
+	if _, _, err = service.GetSensorDataPage(ctx, uuid.NewV4(), from, to, "", MaxPageLimit+1); err == nil {
+		t.Error("expected a limit above the maximum to be rejected")
+	}
+}
+
+func TestCheckSensorsVisible(t *testing.T) {
+	public, hidden := uuid.NewV4(), uuid.NewV4()
+	service := &SensorDataServiceImpl{Repo: &fakeRepository{visible: map[uuid.UUID]bool{public: true}}}
+
+	if err := service.CheckSensorsVisible(context.Background(), uuid.NewV4(), public); err != nil {
+		t.Errorf("expected the visible sensor to be readable, got %v", err)
+	}
+	// A private sensor of another user is reported like an unknown one
+	if err := service.CheckSensorsVisible(context.Background(), uuid.NewV4(), public, hidden); !errors.Is(err, domain.ErrSensorNotFound) {
+		t.Errorf("expected %v, got %v", domain.ErrSensorNotFound, err)
+	}
+}
+
+func TestGetSensorMetadataErrors(t *testing.T) {
+	repo := &fakeRepository{metadataErr: domain.ErrSensorNotFound}
+	service := &SensorDataServiceImpl{Repo: repo}
+
+	if _, err := service.GetSensorMetadata(context.Background(), uuid.NewV4()); !errors.Is(err, domain.ErrSensorNotFound) {
+		t.Errorf("expected %v, got %v", domain.ErrSensorNotFound, err)
+	}
+
+	// A failing database isn't reported as a missing sensor
+	repo.metadataErr = errors.New("connection refused")
+	if _, err := service.GetSensorMetadata(context.Background(), uuid.NewV4()); err == nil || errors.Is(err, domain.ErrSensorNotFound) {
+		t.Errorf("expected a read failure, got %v", err)
+	}
+}
+
+// Listener recording the readings it's notified of
+type recordingListener struct {
+	notified []*domain.SensorData
+}
+
+func (l *recordingListener) OnSensorData(ctx context.Context, sensorData []*domain.SensorData) {
+	l.notified = append(l.notified, sensorData...)
+}
+
+func TestAddSensorDataNotifiesStoredReadings(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	now := time.Now().UTC().Truncate(time.Second)
+	repo := &fakeRepository{
+		ranges:   map[uuid.UUID]domain.ValueRange{sensorUuid: {}},
+		existing: map[int64]bool{now.Unix(): true},
+	}
+	listener := &recordingListener{}
+	service := NewSensorDataService(repo, nil, listener)
+
+	// The reading ignored as a duplicate isn't published nor counted as stored
+	stored, err := service.AddSensorData(context.Background(), []*domain.SensorData{
+		{SensorUuid: sensorUuid, Timestamp: now, Value: 20},
+		{SensorUuid: sensorUuid, Timestamp: now.Add(time.Second), Value: 21},
+	}, domain.SENSOR_DATA_CONFLICT_IGNORE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != 1 {
+		t.Errorf("expected 1 reading stored, got %d", stored)
+	}
+	if len(listener.notified) != 1 || listener.notified[0].Value != 21 {
+		t.Errorf("expected only the new reading to be notified, got %+v", listener.notified)
+	}
+
+	// Nothing is published when every reading is ignored
+	listener.notified = nil
+	if _, err = service.AddSensorData(context.Background(), []*domain.SensorData{{SensorUuid: sensorUuid, Timestamp: now, Value: 20}}, domain.SENSOR_DATA_CONFLICT_IGNORE); err != nil {
+		t.Fatal(err)
+	}
+	if len(listener.notified) != 0 {
+		t.Errorf("expected no notification, got %+v", listener.notified)
+	}
+}
+
+func TestAddSensorDataRepeatedTimestamp(t *testing.T) {
+	sensorUuid := uuid.NewV4()
+	now := time.Now().UTC().Truncate(time.Second)
+	repo := &fakeRepository{ranges: map[uuid.UUID]domain.ValueRange{sensorUuid: {}}}
+	service := NewSensorDataService(repo, nil)
+	repeated := func() []*domain.SensorData {
+		return []*domain.SensorData{
+			{SensorUuid: sensorUuid, Timestamp: now, Value: 20},
+			{SensorUuid: sensorUuid, Timestamp: now, Value: 21},
+		}
+	}
+
+	// Two readings at the same timestamp in one request conflict like an existing reading
+	_, err := service.AddSensorData(context.Background(), repeated(), domain.SENSOR_DATA_CONFLICT_REJECT)
+	if !errors.Is(err, domain.ErrDuplicateSensorData) {
+		t.Fatalf("expected %v, got %v", domain.ErrDuplicateSensorData, err)
+	}
+	if len(repo.stored) != 0 {
+		t.Fatalf("expected nothing stored, got %d readings", len(repo.stored))
+	}
+
+	// The other policies keep the last one sent
+	if _, err = service.AddSensorData(context.Background(), repeated(), domain.SENSOR_DATA_CONFLICT_OVERWRITE); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,182 @@
+// Package units converts sensor values between units of the same physical quantity.
+package units
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Significant digits kept in converted values, dropping the floating point noise of the factors
+const significantDigits = 12
+
+const (
+	// Physical quantities measured by the units
+	DIMENSION_TEMPERATURE   string = "temperature"
+	DIMENSION_PRESSURE      string = "pressure"
+	DIMENSION_RATIO         string = "ratio"
+	DIMENSION_CONCENTRATION string = "concentration"
+	DIMENSION_ILLUMINANCE   string = "illuminance"
+	DIMENSION_POWER         string = "power"
+	DIMENSION_ENERGY        string = "energy"
+	DIMENSION_FLOW          string = "flow"
+)
+
+var (
+	// Returned when a unit isn't known
+	ErrUnknownUnit = errors.New("unknown unit")
+	// Returned when converting between units of different quantities
+	ErrIncompatibleUnits = errors.New("units measure different quantities")
+)
+
+// Unit is a unit of measurement, defined by how its values map to the base unit of its dimension
+type Unit struct {
+	// Canonical symbol of the unit
+	Symbol string
+	// Physical quantity measured
+	Dimension string
+	// A value in this unit is value*factor + offset in the base unit
+	factor float64
+	offset float64
+}
+
+// Known units, the first of each dimension being its base unit
+var knownUnits = []Unit{
+	{Symbol: "K", Dimension: DIMENSION_TEMPERATURE, factor: 1},
+	{Symbol: "°C", Dimension: DIMENSION_TEMPERATURE, factor: 1, offset: 273.15},
+	{Symbol: "°F", Dimension: DIMENSION_TEMPERATURE, factor: 5.0 / 9, offset: 459.67 * 5 / 9},
+
+	{Symbol: "Pa", Dimension: DIMENSION_PRESSURE, factor: 1},
+	{Symbol: "hPa", Dimension: DIMENSION_PRESSURE, factor: 100},
+	{Symbol: "kPa", Dimension: DIMENSION_PRESSURE, factor: 1000},
+	{Symbol: "mbar", Dimension: DIMENSION_PRESSURE, factor: 100},
+	{Symbol: "bar", Dimension: DIMENSION_PRESSURE, factor: 100000},
+	{Symbol: "psi", Dimension: DIMENSION_PRESSURE, factor: 6894.757293168},
+	{Symbol: "atm", Dimension: DIMENSION_PRESSURE, factor: 101325},
+
+	{Symbol: "%", Dimension: DIMENSION_RATIO, factor: 1},
+
+	{Symbol: "ppm", Dimension: DIMENSION_CONCENTRATION, factor: 1},
+	{Symbol: "ppb", Dimension: DIMENSION_CONCENTRATION, factor: 0.001},
+
+	{Symbol: "lx", Dimension: DIMENSION_ILLUMINANCE, factor: 1},
+
+	{Symbol: "W", Dimension: DIMENSION_POWER, factor: 1},
+	{Symbol: "kW", Dimension: DIMENSION_POWER, factor: 1000},
+	{Symbol: "MW", Dimension: DIMENSION_POWER, factor: 1000000},
+
+	{Symbol: "Wh", Dimension: DIMENSION_ENERGY, factor: 1},
+	{Symbol: "kWh", Dimension: DIMENSION_ENERGY, factor: 1000},
+	{Symbol: "MWh", Dimension: DIMENSION_ENERGY, factor: 1000000},
+
+	{Symbol: "L/min", Dimension: DIMENSION_FLOW, factor: 1},
+	{Symbol: "L/s", Dimension: DIMENSION_FLOW, factor: 60},
+	{Symbol: "L/h", Dimension: DIMENSION_FLOW, factor: 1.0 / 60},
+	{Symbol: "m³/h", Dimension: DIMENSION_FLOW, factor: 1000.0 / 60},
+	{Symbol: "gal/min", Dimension: DIMENSION_FLOW, factor: 3.785411784},
+}
+
+// Other spellings accepted for the canonical symbols
+var aliases = map[string]string{
+	"c":          "°C",
+	"degc":       "°C",
+	"celsius":    "°C",
+	"f":          "°F",
+	"degf":       "°F",
+	"fahrenheit": "°F",
+	"kelvin":     "K",
+	"percent":    "%",
+	"lux":        "lx",
+	"m3/h":       "m³/h",
+	"gpm":        "gal/min",
+}
+
+// Units by lowercased symbol
+var unitsBySymbol = func() map[string]Unit {
+	var bySymbol = make(map[string]Unit, len(knownUnits))
+	for _, unit := range knownUnits {
+		bySymbol[strings.ToLower(unit.Symbol)] = unit
+	}
+	return bySymbol
+}()
+
+// Lookup finds a unit by its symbol or one of its aliases, ignoring case
+func Lookup(symbol string) (Unit, bool) {
+	var key = strings.ToLower(strings.TrimSpace(symbol))
+	if canonical, ok := aliases[key]; ok {
+		key = strings.ToLower(canonical)
+	}
+	unit, ok := unitsBySymbol[key]
+	return unit, ok
+}
+
+// Conversion converts values from a unit to another with value*Factor + Offset
+type Conversion struct {
+	// Canonical symbol of the source unit
+	From string
+	// Canonical symbol of the target unit
+	To string
+	// Multiplier applied to the values
+	Factor float64
+	// Added to the values after multiplying them, only set between temperature scales
+	Offset float64
+}
+
+// NewConversion returns the conversion between two units of the same dimension
+func NewConversion(from, to string) (Conversion, error) {
+	// Units that aren't known can still be "converted" to themselves
+	if strings.EqualFold(strings.TrimSpace(from), strings.TrimSpace(to)) {
+		if unit, ok := Lookup(from); ok {
+			return Conversion{From: unit.Symbol, To: unit.Symbol, Factor: 1}, nil
+		}
+		return Conversion{From: from, To: from, Factor: 1}, nil
+	}
+
+	source, ok := Lookup(from)
+	if !ok {
+		return Conversion{}, fmt.Errorf("%w: %q", ErrUnknownUnit, from)
+	}
+	target, ok := Lookup(to)
+	if !ok {
+		return Conversion{}, fmt.Errorf("%w: %q", ErrUnknownUnit, to)
+	}
+	if source.Dimension != target.Dimension {
+		return Conversion{}, fmt.Errorf("%w: cannot convert %s (%s) to %s (%s)", ErrIncompatibleUnits, source.Symbol, source.Dimension, target.Symbol, target.Dimension)
+	}
+
+	if source.Symbol == target.Symbol {
+		return Conversion{From: source.Symbol, To: target.Symbol, Factor: 1}, nil
+	}
+
+	return Conversion{
+		From:   source.Symbol,
+		To:     target.Symbol,
+		Factor: source.factor / target.factor,
+		Offset: (source.offset - target.offset) / target.factor,
+	}, nil
+}
+
+// Rounds the value to the significant digits kept in conversions
+func round(value float64) float64 {
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(value, 'g', significantDigits, 64), 64)
+	if err != nil {
+		return value
+	}
+	return rounded
+}
+
+// Apply converts a value
+func (c Conversion) Apply(value float64) float64 {
+	return round(value*c.Factor + c.Offset)
+}
+
+// ApplyDelta converts a difference between two values, such as a standard deviation, which the offset doesn't apply to
+func (c Conversion) ApplyDelta(delta float64) float64 {
+	return round(delta * c.Factor)
+}
+
+// Scales returns true if the conversion is a plain multiplication, so that sums of values can be converted too
+func (c Conversion) Scales() bool {
+	return c.Offset == 0
+}
@@ -0,0 +1,78 @@
+package units
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestConversion(t *testing.T) {
+	tests := []struct {
+		from  string
+		to    string
+		value float64
+		want  float64
+	}{
+		{"°C", "°F", 100, 212},
+		{"°C", "°F", -40, -40},
+		{"°F", "°C", 32, 0},
+		{"°C", "K", 0, 273.15},
+		{"K", "°F", 0, -459.67},
+		{"hPa", "Pa", 1013.25, 101325},
+		{"hPa", "bar", 1013.25, 1.01325},
+		{"bar", "psi", 1, 14.503773773},
+		{"ppb", "ppm", 400, 0.4},
+		{"kW", "W", 1.5, 1500},
+		{"m³/h", "L/min", 6, 100},
+		{"%", "%", 42.5, 42.5},
+		// Aliases and case
+		{"celsius", "degF", 20, 68},
+		{"HPA", "kpa", 1000, 100},
+		{"m3/h", "L/s", 3.6, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from+" to "+test.to, func(t *testing.T) {
+			conversion, err := NewConversion(test.from, test.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conversion.Apply(test.value); math.Abs(got-test.want) > 1e-6 {
+				t.Errorf("expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestConversionErrors(t *testing.T) {
+	if _, err := NewConversion("°C", "hPa"); !errors.Is(err, ErrIncompatibleUnits) {
+		t.Errorf("expected %v, got %v", ErrIncompatibleUnits, err)
+	}
+	if _, err := NewConversion("°C", "furlong"); !errors.Is(err, ErrUnknownUnit) {
+		t.Errorf("expected %v, got %v", ErrUnknownUnit, err)
+	}
+	if conversion, err := NewConversion("µg/m³", "µg/m³"); err != nil || conversion.Apply(12.5) != 12.5 {
+		t.Errorf("expected an unknown unit to be kept as is, got %+v, %v", conversion, err)
+	}
+}
+
+func TestConversionScales(t *testing.T) {
+	temperature, _ := NewConversion("°C", "°F")
+	if temperature.Scales() {
+		t.Error("expected a conversion between temperature scales to have an offset")
+	}
+
+	pressure, _ := NewConversion("hPa", "bar")
+	if !pressure.Scales() {
+		t.Error("expected a conversion between pressure units to be a plain multiplication")
+	}
+
+	if delta := temperature.ApplyDelta(10); delta != 18 {
+		t.Errorf("expected a difference of 10 °C to be 18 °F, got %v", delta)
+	}
+
+	interval, _ := NewConversion("°C", "K")
+	if interval.Factor != 1 || interval.Offset != 273.15 {
+		t.Errorf("unexpected conversion %+v", interval)
+	}
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+const (
+	// Event types
+	// Readings were added to a sensor
+	WEBHOOK_EVENT_DATA_ADDED string = "data.added"
+	// A sensor was created
+	WEBHOOK_EVENT_SENSOR_CREATED string = "sensor.created"
+	// A sensor was updated
+	WEBHOOK_EVENT_SENSOR_UPDATED string = "sensor.updated"
+	// An alert rule fired
+	WEBHOOK_EVENT_ALERT_FIRED string = "alert.fired"
+	// Readings added to a sensor were flagged as anomalies
+	WEBHOOK_EVENT_ANOMALY_DETECTED string = "anomaly.detected"
+	// A monitored sensor went online, stale or offline
+	WEBHOOK_EVENT_SENSOR_STATUS_CHANGED string = "sensor.status_changed"
+)
+
+// Every event type a subscription can receive
+var WebhookEvents = []string{
+	WEBHOOK_EVENT_DATA_ADDED,
+	WEBHOOK_EVENT_SENSOR_CREATED,
+	WEBHOOK_EVENT_SENSOR_UPDATED,
+	WEBHOOK_EVENT_ALERT_FIRED,
+	WEBHOOK_EVENT_ANOMALY_DETECTED,
+	WEBHOOK_EVENT_SENSOR_STATUS_CHANGED,
+}
+
+const (
+	// Headers sent with every delivery
+	// HMAC-SHA256 of the body with the subscription's secret, as "sha256=<hex>"
+	WEBHOOK_HEADER_SIGNATURE string = "X-Webhook-Signature"
+	// Event type of the payload
+	WEBHOOK_HEADER_EVENT string = "X-Webhook-Event"
+	// Unique identifier of the delivery, the same across retries
+	WEBHOOK_HEADER_DELIVERY string = "X-Webhook-Delivery"
+)
+
+var (
+	// Returned when the subscription doesn't exist or belongs to another user
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	// Returned when the sensor of a subscription doesn't exist
+	ErrSensorNotFound = errors.New("sensor not found")
+	// Returned when the user subscribes to a sensor they don't own
+	ErrNotSensorOwner = errors.New("user is not the owner of the sensor")
+)
+
+// Subscription is an URL that receives the events of one sensor or of every sensor of a user
+type Subscription struct {
+	// Unique identifier for the subscription
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the user who owns the subscription
+	OwnerUuid uuid.UUID `json:"ownerUuid"`
+	// UUID of the sensor whose events are sent, nil for every sensor of the owner
+	SensorUuid *uuid.UUID `json:"sensorUuid"`
+	// URL the events are posted to
+	URL string `json:"url"`
+	// Secret used to sign the payloads
+	Secret string `json:"secret,omitempty"`
+	// Event types sent to the URL
+	Events []string `json:"events"`
+	// Whether events are sent
+	Enabled bool `json:"enabled"`
+	// When the subscription was created
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Receives reports whether the subscription wants the event type
+func (s *Subscription) Receives(eventType string) bool {
+	for _, event := range s.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is something that happened on a sensor, sent as the JSON body of the deliveries
+type Event struct {
+	// Unique identifier for the event
+	ID uuid.UUID `json:"id"`
+	// Event type
+	Type string `json:"type"`
+	// UUID of the sensor the event happened on
+	SensorUuid uuid.UUID `json:"sensorUuid"`
+	// When the event happened
+	OccurredAt time.Time `json:"occurredAt"`
+	// Details of the event, depending on its type
+	Data interface{} `json:"data"`
+}
+
+// Delivery is a pending post of an event payload to a subscription
+type Delivery struct {
+	// Unique identifier for the delivery
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the subscription receiving the event
+	SubscriptionUuid uuid.UUID `json:"subscriptionUuid"`
+	// Event type
+	Event string `json:"event"`
+	// JSON body posted to the subscription
+	Payload []byte `json:"-"`
+	// Number of failed attempts so far
+	Attempts int `json:"attempts"`
+	// When the next attempt is due
+	NextAttemptAt time.Time `json:"nextAttemptAt"`
+	// Error of the last failed attempt
+	LastError string `json:"lastError"`
+	// URL of the subscription (read only)
+	URL string `json:"-"`
+	// Secret of the subscription (read only)
+	Secret string `json:"-"`
+}
+
+// DeadLetter is a delivery that failed every attempt
+type DeadLetter struct {
+	// Unique identifier of the delivery
+	ID uuid.UUID `json:"uuid"`
+	// UUID of the subscription that didn't receive the event
+	SubscriptionUuid uuid.UUID `json:"subscriptionUuid"`
+	// Event type
+	Event string `json:"event"`
+	// JSON body that couldn't be delivered
+	Payload string `json:"payload"`
+	// Number of attempts made
+	Attempts int `json:"attempts"`
+	// Error of the last attempt
+	LastError string `json:"lastError"`
+	// When the delivery was given up
+	FailedAt time.Time `json:"failedAt"`
+}
@@ -0,0 +1,162 @@
+package handler
+
+import (
+	user_service "api/internal/users/usecase"
+	"api/internal/webhooks/domain"
+	webhook_service "api/internal/webhooks/usecase"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for handling HTTP requests related to webhooks
+type WebhookHandler interface {
+	// Handles the HTTP request to create a webhook subscription
+	CreateSubscription(c *gin.Context)
+	// Handles the HTTP request to delete a webhook subscription
+	DeleteSubscription(c *gin.Context)
+	// Handles the HTTP request to list the user's webhook subscriptions
+	ListSubscriptions(c *gin.Context)
+	// Handles the HTTP request to list the deliveries that failed every attempt
+	ListDeadLetters(c *gin.Context)
+}
+
+// Structure request to create a webhook subscription
+type WebhookSubscriptionRequest struct {
+	// Sensor UUID, omitted for every sensor of the user
+	SensorUuid *uuid.UUID `json:"sensorUuid"`
+	// URL the events are posted to
+	URL string `json:"url" binding:"required"`
+	// Secret used to sign the payloads, generated when omitted
+	Secret string `json:"secret"`
+	// Event types: data.added, sensor.created, sensor.updated, alert.fired, anomaly.detected and/or sensor.status_changed
+	Events []string `json:"events" binding:"required"`
+}
+
+// Structure request identifying a webhook subscription
+type WebhookUuidRequest struct {
+	// Subscription UUID
+	ID uuid.UUID `json:"uuid" binding:"required"`
+}
+
+// Process HTTP requests and interaction with WebhookService/UserService for webhook operations
+type WebhookHandlerImpl struct {
+	WebhookService webhook_service.WebhookService
+	UserService    user_service.UserService
+}
+
+func NewWebhookHandler(webhookService webhook_service.WebhookService, userService user_service.UserService) WebhookHandler {
+	return &WebhookHandlerImpl{
+		WebhookService: webhookService,
+		UserService:    userService,
+	}
+}
+
+// Gets the uuid of the user authenticated by the request's token
+func (h *WebhookHandlerImpl) getUserUuid(c *gin.Context) (uuid.UUID, error) {
+	var tokenAuth, _ = c.Get("token")
+	var str, _ = tokenAuth.(string)
+
+	return h.UserService.GetUserByToken(c.Request.Context(), str)
+}
+
+// Maps errors from the webhook service to the HTTP status to respond with
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrSubscriptionNotFound), errors.Is(err, domain.ErrSensorNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrNotSensorOwner):
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+func (h *WebhookHandlerImpl) CreateSubscription(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req WebhookSubscriptionRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	subscription := &domain.Subscription{
+		SensorUuid: req.SensorUuid,
+		URL:        req.URL,
+		Secret:     req.Secret,
+		Events:     req.Events,
+		Enabled:    true,
+	}
+	if err = h.WebhookService.CreateSubscription(c.Request.Context(), subscription, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// The secret is only returned here, the receiver needs it to verify the signatures
+	c.JSON(http.StatusOK, gin.H{"uuid": subscription.ID, "secret": subscription.Secret})
+}
+
+func (h *WebhookHandlerImpl) DeleteSubscription(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	var req WebhookUuidRequest
+	if err = c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if err = h.WebhookService.DeleteSubscription(c.Request.Context(), req.ID, userUuid); err != nil {
+		c.JSON(errorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+func (h *WebhookHandlerImpl) ListSubscriptions(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	subscriptions, err := h.WebhookService.ListSubscriptions(c.Request.Context(), userUuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if subscriptions == nil {
+		subscriptions = []domain.Subscription{}
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+func (h *WebhookHandlerImpl) ListDeadLetters(c *gin.Context) {
+	userUuid, err := h.getUserUuid(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+
+	deadLetters, err := h.WebhookService.ListDeadLetters(c.Request.Context(), userUuid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if deadLetters == nil {
+		deadLetters = []domain.DeadLetter{}
+	}
+	c.JSON(http.StatusOK, gin.H{"deadLetters": deadLetters})
+}
@@ -0,0 +1,367 @@
+package repository
+
+import (
+	config "api/configs"
+	"api/internal/webhooks/domain"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for webhook subscriptions and deliveries data operations
+type WebhookRepository interface {
+	// Stores a new subscription
+	CreateSubscription(ctx context.Context, subscription *domain.Subscription) error
+	// Deletes a subscription of the owner, its pending deliveries and dead letters
+	DeleteSubscription(ctx context.Context, subscriptionUuid uuid.UUID, ownerUuid uuid.UUID) error
+	// Retrieves the subscriptions of the owner
+	ListSubscriptions(ctx context.Context, ownerUuid uuid.UUID) ([]domain.Subscription, error)
+	// Retrieves the enabled subscriptions to the sensor, directly or through its owner
+	ListSensorSubscriptions(ctx context.Context, sensorUuid uuid.UUID) ([]domain.Subscription, error)
+	// Retrieves the uuid of the user who owns the sensor
+	GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error)
+	// Queues deliveries to be sent by the worker
+	EnqueueDeliveries(ctx context.Context, deliveries []domain.Delivery) error
+	// Retrieves up to limit deliveries due at the given time, with their subscription's URL and secret
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.Delivery, error)
+	// Removes a delivery that was sent
+	DeleteDelivery(ctx context.Context, deliveryUuid uuid.UUID) error
+	// Records a failed attempt and when to try again
+	ScheduleRetry(ctx context.Context, delivery *domain.Delivery) error
+	// Moves a delivery that failed every attempt to the dead-letter table
+	MoveToDeadLetter(ctx context.Context, delivery *domain.Delivery, failedAt time.Time) error
+	// Retrieves the dead letters of the owner's subscriptions
+	ListDeadLetters(ctx context.Context, ownerUuid uuid.UUID) ([]domain.DeadLetter, error)
+}
+
+// Performs webhook's data operations using database/sql to interact with the database
+type WebhookRepositoryImpl struct {
+	DB *sql.DB
+}
+
+func NewWebhookRepository() (WebhookRepository, error) {
+	db, err := config.ConnectDB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %v", err)
+	}
+
+	return &WebhookRepositoryImpl{DB: db}, nil
+}
+
+// Columns selected for subscriptions, in the order read by scanSubscriptions
+const subscriptionColumns = `uuid, ownerUuid, sensorUuid, url, secret, events, enabled, createdAt`
+
+func scanSubscriptions(rows *sql.Rows) ([]domain.Subscription, error) {
+	defer rows.Close()
+
+	var subscriptions []domain.Subscription
+	for rows.Next() {
+		var subscription domain.Subscription
+		var sensorUuid uuid.NullUUID
+		var events string
+		if err := rows.Scan(&subscription.ID, &subscription.OwnerUuid, &sensorUuid, &subscription.URL, &subscription.Secret,
+			&events, &subscription.Enabled, &subscription.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook subscription: %v", err)
+		}
+		if sensorUuid.Valid {
+			subscription.SensorUuid = &sensorUuid.UUID
+		}
+		// Events are stored comma separated
+		subscription.Events = strings.Split(events, ",")
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook subscriptions: %v", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepositoryImpl) CreateSubscription(ctx context.Context, subscription *domain.Subscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (uuid, ownerUuid, sensorUuid, url, secret, events, enabled, createdAt)
+		VALUES (@uuid, @ownerUuid, @sensorUuid, @url, @secret, @events, @enabled, @createdAt)
+	`
+
+	var sensorUuid uuid.NullUUID
+	if subscription.SensorUuid != nil {
+		sensorUuid = uuid.NullUUID{UUID: *subscription.SensorUuid, Valid: true}
+	}
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", subscription.ID),
+		sql.Named("ownerUuid", subscription.OwnerUuid),
+		sql.Named("sensorUuid", sensorUuid),
+		sql.Named("url", subscription.URL),
+		sql.Named("secret", subscription.Secret),
+		sql.Named("events", strings.Join(subscription.Events, ",")),
+		sql.Named("enabled", subscription.Enabled),
+		sql.Named("createdAt", subscription.CreatedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook subscription: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepositoryImpl) DeleteSubscription(ctx context.Context, subscriptionUuid uuid.UUID, ownerUuid uuid.UUID) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE webhook_deliveries
+		FROM webhook_deliveries
+		INNER JOIN webhook_subscriptions ON webhook_subscriptions.uuid = webhook_deliveries.subscriptionUuid
+		WHERE webhook_subscriptions.uuid = @uuid AND webhook_subscriptions.ownerUuid = @ownerUuid
+	`
+	if _, err = tx.ExecContext(ctx, query, sql.Named("uuid", subscriptionUuid), sql.Named("ownerUuid", ownerUuid)); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %v", err)
+	}
+
+	query = `
+		DELETE webhook_dead_letters
+		FROM webhook_dead_letters
+		INNER JOIN webhook_subscriptions ON webhook_subscriptions.uuid = webhook_dead_letters.subscriptionUuid
+		WHERE webhook_subscriptions.uuid = @uuid AND webhook_subscriptions.ownerUuid = @ownerUuid
+	`
+	if _, err = tx.ExecContext(ctx, query, sql.Named("uuid", subscriptionUuid), sql.Named("ownerUuid", ownerUuid)); err != nil {
+		return fmt.Errorf("failed to delete webhook dead letters: %v", err)
+	}
+
+	query = `DELETE FROM webhook_subscriptions WHERE uuid = @uuid AND ownerUuid = @ownerUuid`
+	result, err := tx.ExecContext(ctx, query, sql.Named("uuid", subscriptionUuid), sql.Named("ownerUuid", ownerUuid))
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %v", err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return domain.ErrSubscriptionNotFound
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepositoryImpl) ListSubscriptions(ctx context.Context, ownerUuid uuid.UUID) ([]domain.Subscription, error) {
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE ownerUuid = @ownerUuid
+		ORDER BY createdAt
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("ownerUuid", ownerUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %v", err)
+	}
+	return scanSubscriptions(rows)
+}
+
+func (r *WebhookRepositoryImpl) ListSensorSubscriptions(ctx context.Context, sensorUuid uuid.UUID) ([]domain.Subscription, error) {
+	// Subscriptions without sensor receive the events of every sensor of their owner
+	query := `
+		SELECT ` + subscriptionColumns + `
+		FROM webhook_subscriptions
+		WHERE enabled = 1
+		AND (
+			sensorUuid = @sensorUuid
+			OR (sensorUuid IS NULL AND ownerUuid = (SELECT sensorOwnerUuid FROM Sensors WHERE uuid = @sensorUuid))
+		)
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("sensorUuid", sensorUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook subscriptions: %v", err)
+	}
+	return scanSubscriptions(rows)
+}
+
+func (r *WebhookRepositoryImpl) GetSensorOwner(ctx context.Context, sensorUuid uuid.UUID) (uuid.UUID, error) {
+	query := `SELECT sensorOwnerUuid FROM Sensors WHERE uuid = @sensorUuid`
+
+	var ownerUuid uuid.UUID
+	err := r.DB.QueryRowContext(ctx, query, sql.Named("sensorUuid", sensorUuid)).Scan(&ownerUuid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.NilUUID, domain.ErrSensorNotFound
+		}
+		return uuid.NilUUID, fmt.Errorf("failed to retrieve sensor owner: %v", err)
+	}
+	return ownerUuid, nil
+}
+
+func (r *WebhookRepositoryImpl) EnqueueDeliveries(ctx context.Context, deliveries []domain.Delivery) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_deliveries (uuid, subscriptionUuid, event, payload, attempts, nextAttemptAt, lastError)
+		VALUES (@uuid, @subscriptionUuid, @event, @payload, @attempts, @nextAttemptAt, @lastError)
+	`
+
+	for _, delivery := range deliveries {
+		_, err = tx.ExecContext(ctx, query,
+			sql.Named("uuid", delivery.ID),
+			sql.Named("subscriptionUuid", delivery.SubscriptionUuid),
+			sql.Named("event", delivery.Event),
+			sql.Named("payload", string(delivery.Payload)),
+			sql.Named("attempts", delivery.Attempts),
+			sql.Named("nextAttemptAt", delivery.NextAttemptAt),
+			sql.Named("lastError", delivery.LastError),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue webhook delivery: %v", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepositoryImpl) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.Delivery, error) {
+	query := `
+		SELECT TOP (@limit)
+			webhook_deliveries.uuid, webhook_deliveries.subscriptionUuid, webhook_deliveries.event, webhook_deliveries.payload,
+			webhook_deliveries.attempts, webhook_deliveries.nextAttemptAt, webhook_deliveries.lastError,
+			webhook_subscriptions.url, webhook_subscriptions.secret
+		FROM webhook_deliveries
+		INNER JOIN webhook_subscriptions ON webhook_subscriptions.uuid = webhook_deliveries.subscriptionUuid
+		WHERE webhook_deliveries.nextAttemptAt <= @now
+		ORDER BY webhook_deliveries.nextAttemptAt
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("limit", limit), sql.Named("now", now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.Delivery
+	for rows.Next() {
+		var delivery domain.Delivery
+		var payload string
+		if err := rows.Scan(&delivery.ID, &delivery.SubscriptionUuid, &delivery.Event, &payload,
+			&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastError, &delivery.URL, &delivery.Secret); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %v", err)
+		}
+		delivery.Payload = []byte(payload)
+		deliveries = append(deliveries, delivery)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %v", err)
+	}
+
+	return deliveries, nil
+}
+
+func (r *WebhookRepositoryImpl) DeleteDelivery(ctx context.Context, deliveryUuid uuid.UUID) error {
+	query := `DELETE FROM webhook_deliveries WHERE uuid = @uuid`
+
+	if _, err := r.DB.ExecContext(ctx, query, sql.Named("uuid", deliveryUuid)); err != nil {
+		return fmt.Errorf("failed to delete webhook delivery: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepositoryImpl) ScheduleRetry(ctx context.Context, delivery *domain.Delivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET attempts = @attempts, nextAttemptAt = @nextAttemptAt, lastError = @lastError
+		WHERE uuid = @uuid
+	`
+
+	_, err := r.DB.ExecContext(ctx, query,
+		sql.Named("uuid", delivery.ID),
+		sql.Named("attempts", delivery.Attempts),
+		sql.Named("nextAttemptAt", delivery.NextAttemptAt),
+		sql.Named("lastError", delivery.LastError),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to schedule webhook delivery retry: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepositoryImpl) MoveToDeadLetter(ctx context.Context, delivery *domain.Delivery, failedAt time.Time) error {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO webhook_dead_letters (uuid, subscriptionUuid, event, payload, attempts, lastError, failedAt)
+		VALUES (@uuid, @subscriptionUuid, @event, @payload, @attempts, @lastError, @failedAt)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		sql.Named("uuid", delivery.ID),
+		sql.Named("subscriptionUuid", delivery.SubscriptionUuid),
+		sql.Named("event", delivery.Event),
+		sql.Named("payload", string(delivery.Payload)),
+		sql.Named("attempts", delivery.Attempts),
+		sql.Named("lastError", delivery.LastError),
+		sql.Named("failedAt", failedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store webhook dead letter: %v", err)
+	}
+
+	query = `DELETE FROM webhook_deliveries WHERE uuid = @uuid`
+	if _, err = tx.ExecContext(ctx, query, sql.Named("uuid", delivery.ID)); err != nil {
+		return fmt.Errorf("failed to delete webhook delivery: %v", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepositoryImpl) ListDeadLetters(ctx context.Context, ownerUuid uuid.UUID) ([]domain.DeadLetter, error) {
+	query := `
+		SELECT webhook_dead_letters.uuid, webhook_dead_letters.subscriptionUuid, webhook_dead_letters.event,
+			webhook_dead_letters.payload, webhook_dead_letters.attempts, webhook_dead_letters.lastError, webhook_dead_letters.failedAt
+		FROM webhook_dead_letters
+		INNER JOIN webhook_subscriptions ON webhook_subscriptions.uuid = webhook_dead_letters.subscriptionUuid
+		WHERE webhook_subscriptions.ownerUuid = @ownerUuid
+		ORDER BY webhook_dead_letters.failedAt DESC
+	`
+
+	rows, err := r.DB.QueryContext(ctx, query, sql.Named("ownerUuid", ownerUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook dead letters: %v", err)
+	}
+	defer rows.Close()
+
+	var deadLetters []domain.DeadLetter
+	for rows.Next() {
+		var deadLetter domain.DeadLetter
+		if err := rows.Scan(&deadLetter.ID, &deadLetter.SubscriptionUuid, &deadLetter.Event, &deadLetter.Payload,
+			&deadLetter.Attempts, &deadLetter.LastError, &deadLetter.FailedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook dead letter: %v", err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook dead letters: %v", err)
+	}
+
+	return deadLetters, nil
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	auth_repository "api/internal/auth/repository"
+	auth_service "api/internal/auth/usecase"
+	user_repository "api/internal/users/repository"
+	user_service "api/internal/users/usecase"
+	"api/internal/webhooks/handler"
+	webhook_repository "api/internal/webhooks/repository"
+	webhook_service "api/internal/webhooks/usecase"
+	middleware "api/utils"
+	"context"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterWebhookRoutes declares the routes that can be accessed for webhook management
+// and starts the worker delivering the events.
+func RegisterWebhookRoutes(router *gin.Engine) {
+
+	webhookRepo, err := webhook_repository.NewWebhookRepository()
+	if err != nil {
+		log.Fatalf("Failed to create webhook repository: %v", err)
+	}
+
+	usersRepos, err := user_repository.NewUserRepository()
+	if err != nil {
+		log.Fatalf("Failed to create repository: %v", err)
+	}
+
+	authRepo, err := auth_repository.NewAuthRepository()
+	if err != nil {
+		log.Fatalf("Failed to create auth repository: %v", err)
+	}
+
+	userService := user_service.NewUserService(usersRepos, authRepo)
+	webhookService := webhook_service.NewWebhookService(webhookRepo)
+	authService := auth_service.NewAuthService(authRepo, usersRepos)
+
+	h := handler.NewWebhookHandler(webhookService, userService)
+
+	// Queued events are posted in the background, failed ones are retried with backoff
+	go webhookService.Run(context.Background(), webhook_service.DefaultDeliveryInterval)
+
+	// Webhook routes
+	api := router.Group("/v1/webhooks/")
+	api.Use(middleware.AuthMiddleware(authService))
+	{
+		// Create webhook subscription
+		api.POST("create", h.CreateSubscription)
+		// Delete webhook subscription
+		api.POST("delete", h.DeleteSubscription)
+		// List webhook subscriptions
+		api.POST("list", h.ListSubscriptions)
+		// List deliveries that failed every attempt
+		api.POST("dead-letters", h.ListDeadLetters)
+	}
+}
@@ -0,0 +1,126 @@
+package usecase
+
+import (
+	"api/internal/webhooks/domain"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// Interval between runs of the delivery worker
+	DefaultDeliveryInterval = 10 * time.Second
+	// Deliveries sent per run of the worker
+	DeliveryBatchSize = 100
+	// Time allowed to the receiver to answer
+	DeliveryTimeout = 10 * time.Second
+	// Attempts made before a delivery is moved to the dead letters
+	MaxDeliveryAttempts = 8
+	// Delay before the first retry, doubled after each failed attempt
+	BaseRetryDelay = 30 * time.Second
+	// Longest delay between two attempts
+	MaxRetryDelay = 2 * time.Hour
+)
+
+// Sign returns the signature of the payload sent in the X-Webhook-Signature header,
+// "sha256=" followed by the hex encoded HMAC-SHA256 of the payload with the secret
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay returns the delay before the next attempt of a delivery that failed attempts times
+func RetryDelay(attempts int) time.Duration {
+	delay := BaseRetryDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= MaxRetryDelay {
+			return MaxRetryDelay
+		}
+	}
+	return delay
+}
+
+func (s *WebhookServiceImpl) DeliverDue(ctx context.Context, now time.Time) (int, error) {
+	deliveries, err := s.Repo.ListDueDeliveries(ctx, now, DeliveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		sendErr := s.send(ctx, delivery)
+		if sendErr == nil {
+			if err = s.Repo.DeleteDelivery(ctx, delivery.ID); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		delivery.LastError = sendErr.Error()
+		delivery.Attempts++
+		if delivery.Attempts >= MaxDeliveryAttempts {
+			err = s.Repo.MoveToDeadLetter(ctx, delivery, now)
+		} else {
+			delivery.NextAttemptAt = now.Add(RetryDelay(delivery.Attempts))
+			err = s.Repo.ScheduleRetry(ctx, delivery)
+		}
+		if err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, nil
+}
+
+// Posts the payload of the delivery to its subscription, any non 2xx answer is a failure
+func (s *WebhookServiceImpl) send(ctx context.Context, delivery *domain.Delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(domain.WEBHOOK_HEADER_EVENT, delivery.Event)
+	req.Header.Set(domain.WEBHOOK_HEADER_DELIVERY, delivery.ID.String())
+	req.Header.Set(domain.WEBHOOK_HEADER_SIGNATURE, Sign(delivery.Secret, delivery.Payload))
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *WebhookServiceImpl) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := s.DeliverDue(ctx, now.UTC()); err != nil {
+				log.Printf("Failed to deliver webhooks: %v", err)
+			}
+		}
+	}
+}
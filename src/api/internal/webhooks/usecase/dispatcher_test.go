@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"api/internal/webhooks/domain"
+	"api/internal/webhooks/repository"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// In-memory repository holding the subscriptions and the delivery queue
+type fakeRepository struct {
+	repository.WebhookRepository
+	subscriptions []domain.Subscription
+	deliveries    []domain.Delivery
+	deadLetters   []domain.Delivery
+}
+
+func (r *fakeRepository) ListSensorSubscriptions(ctx context.Context, sensorUuid uuid.UUID) ([]domain.Subscription, error) {
+	return r.subscriptions, nil
+}
+
+func (r *fakeRepository) EnqueueDeliveries(ctx context.Context, deliveries []domain.Delivery) error {
+	r.deliveries = append(r.deliveries, deliveries...)
+	return nil
+}
+
+func (r *fakeRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.Delivery, error) {
+	var due []domain.Delivery
+	for _, delivery := range r.deliveries {
+		if delivery.NextAttemptAt.After(now) {
+			continue
+		}
+		for _, subscription := range r.subscriptions {
+			if subscription.ID == delivery.SubscriptionUuid {
+				delivery.URL = subscription.URL
+				delivery.Secret = subscription.Secret
+			}
+		}
+		due = append(due, delivery)
+	}
+	return due, nil
+}
+
+func (r *fakeRepository) remove(deliveryUuid uuid.UUID) {
+	for i, delivery := range r.deliveries {
+		if delivery.ID == deliveryUuid {
+			r.deliveries = append(r.deliveries[:i], r.deliveries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *fakeRepository) DeleteDelivery(ctx context.Context, deliveryUuid uuid.UUID) error {
+	r.remove(deliveryUuid)
+	return nil
+}
+
+func (r *fakeRepository) ScheduleRetry(ctx context.Context, delivery *domain.Delivery) error {
+	for i := range r.deliveries {
+		if r.deliveries[i].ID == delivery.ID {
+			r.deliveries[i] = *delivery
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) MoveToDeadLetter(ctx context.Context, delivery *domain.Delivery, failedAt time.Time) error {
+	r.remove(delivery.ID)
+	r.deadLetters = append(r.deadLetters, *delivery)
+	return nil
+}
+
+// Receiver that records the requests and answers with the configured status
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	w.WriteHeader(rc.status)
+}
+
+func newTestService(t *testing.T, status int, events ...string) (*WebhookServiceImpl, *fakeRepository, *receiver) {
+	rc := &receiver{status: status}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	repo := &fakeRepository{subscriptions: []domain.Subscription{{
+		ID:      uuid.NewV4(),
+		URL:     server.URL,
+		Secret:  "s3cret",
+		Events:  events,
+		Enabled: true,
+	}}}
+	return &WebhookServiceImpl{Repo: repo, Client: server.Client()}, repo, rc
+}
+
+func TestDeliverySignedPayload(t *testing.T) {
+	service, repo, rc := newTestService(t, http.StatusNoContent, domain.WEBHOOK_EVENT_DATA_ADDED)
+	ctx := context.Background()
+	sensorUuid := uuid.NewV4()
+
+	if err := service.Publish(ctx, domain.WEBHOOK_EVENT_DATA_ADDED, sensorUuid, map[string]float64{"value": 21.5}); err != nil {
+		t.Fatal(err)
+	}
+	// Not subscribed to this event
+	if err := service.Publish(ctx, domain.WEBHOOK_EVENT_ALERT_FIRED, sensorUuid, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("expected 1 queued delivery, got %d", len(repo.deliveries))
+	}
+
+	delivered, err := service.DeliverDue(ctx, time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivered != 1 || len(repo.deliveries) != 0 {
+		t.Fatalf("expected the delivery to be sent and removed, delivered %d, queued %d", delivered, len(repo.deliveries))
+	}
+
+	req, body := rc.requests[0], rc.bodies[0]
+	if got := req.Header.Get(domain.WEBHOOK_HEADER_EVENT); got != domain.WEBHOOK_EVENT_DATA_ADDED {
+		t.Errorf("expected event header %s, got %s", domain.WEBHOOK_EVENT_DATA_ADDED, got)
+	}
+	if got, want := req.Header.Get(domain.WEBHOOK_HEADER_SIGNATURE), Sign("s3cret", body); got != want {
+		t.Errorf("expected signature %s, got %s", want, got)
+	}
+	if Sign("other", body) == Sign("s3cret", body) {
+		t.Error("expected the signature to depend on the secret")
+	}
+}
+
+func TestDeliveryRetriesWithBackoffThenDeadLetters(t *testing.T) {
+	service, repo, rc := newTestService(t, http.StatusInternalServerError, domain.WEBHOOK_EVENT_SENSOR_CREATED)
+	ctx := context.Background()
+
+	if err := service.Publish(ctx, domain.WEBHOOK_EVENT_SENSOR_CREATED, uuid.NewV4(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	for attempt := 1; attempt < MaxDeliveryAttempts; attempt++ {
+		if _, err := service.DeliverDue(ctx, now); err != nil {
+			t.Fatal(err)
+		}
+
+		delivery := repo.deliveries[0]
+		if delivery.Attempts != attempt {
+			t.Fatalf("expected %d attempts, got %d", attempt, delivery.Attempts)
+		}
+		if want := now.Add(RetryDelay(attempt)); !delivery.NextAttemptAt.Equal(want) {
+			t.Fatalf("attempt %d: expected next attempt at %v, got %v", attempt, want, delivery.NextAttemptAt)
+		}
+
+		// Nothing is sent before the delay elapses
+		if _, err := service.DeliverDue(ctx, delivery.NextAttemptAt.Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if len(rc.requests) != attempt {
+			t.Fatalf("expected %d requests, got %d", attempt, len(rc.requests))
+		}
+		now = delivery.NextAttemptAt
+	}
+
+	if _, err := service.DeliverDue(ctx, now); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.deliveries) != 0 || len(repo.deadLetters) != 1 {
+		t.Fatalf("expected the delivery to be dead-lettered, queued %d, dead letters %d", len(repo.deliveries), len(repo.deadLetters))
+	}
+	if repo.deadLetters[0].LastError == "" {
+		t.Error("expected the dead letter to keep the last error")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if RetryDelay(1) != BaseRetryDelay || RetryDelay(2) != 2*BaseRetryDelay || RetryDelay(3) != 4*BaseRetryDelay {
+		t.Errorf("expected the delay to double after each attempt, got %v %v %v", RetryDelay(1), RetryDelay(2), RetryDelay(3))
+	}
+	if RetryDelay(50) != MaxRetryDelay {
+		t.Errorf("expected the delay to be capped at %v, got %v", MaxRetryDelay, RetryDelay(50))
+	}
+}
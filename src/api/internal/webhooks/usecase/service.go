@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	alert_domain "api/internal/alerts/domain"
+	sensor_domain "api/internal/sensors/domain"
+	sensor_data_domain "api/internal/sensors_data/domain"
+	"api/internal/webhooks/domain"
+	"api/internal/webhooks/repository"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	uuid "github.com/tentone/mssql-uuid"
+)
+
+// Interface for webhook's services
+type WebhookService interface {
+	// Creates a subscription of the user, to one of their sensors or to all of them
+	CreateSubscription(ctx context.Context, subscription *domain.Subscription, userUuid uuid.UUID) error
+	// Deletes a subscription of the user
+	DeleteSubscription(ctx context.Context, subscriptionUuid uuid.UUID, userUuid uuid.UUID) error
+	// Lists the subscriptions of the user, without their secrets
+	ListSubscriptions(ctx context.Context, userUuid uuid.UUID) ([]domain.Subscription, error)
+	// Lists the deliveries to the user's subscriptions that failed every attempt
+	ListDeadLetters(ctx context.Context, userUuid uuid.UUID) ([]domain.DeadLetter, error)
+	// Queues a delivery of the event to every subscription that receives it
+	Publish(ctx context.Context, eventType string, sensorUuid uuid.UUID, data interface{}) error
+	// Sends the deliveries due at the given time, returns how many were delivered
+	DeliverDue(ctx context.Context, now time.Time) (int, error)
+	// Sends the due deliveries at each interval until the context is cancelled
+	Run(ctx context.Context, interval time.Duration)
+	// Publishes data.added events
+	OnSensorData(ctx context.Context, sensorData []*sensor_data_domain.SensorData)
+	// Publishes sensor.created events
+	OnSensorCreated(ctx context.Context, sensor *sensor_domain.Sensor)
+	// Publishes sensor.updated events
+	OnSensorUpdated(ctx context.Context, sensor *sensor_domain.Sensor)
+	// Publishes alert.fired events
+	OnAlertFired(ctx context.Context, rule *alert_domain.AlertRule, alert *alert_domain.Alert)
+	// Publishes anomaly.detected events
+	OnAnomalies(ctx context.Context, sensorUuid uuid.UUID, anomalies []*sensor_data_domain.SensorData)
+	// Publishes sensor.status_changed events
+	OnSensorStatusChanged(ctx context.Context, change *sensor_domain.SensorStatusChange)
+}
+
+// Handles webhook's logic, interaction with the repository and delivery of the events
+type WebhookServiceImpl struct {
+	Repo repository.WebhookRepository
+	// HTTP client used to post the events
+	Client *http.Client
+}
+
+func NewWebhookService(repo repository.WebhookRepository) WebhookService {
+	return &WebhookServiceImpl{
+		Repo:   repo,
+		Client: &http.Client{Timeout: DeliveryTimeout},
+	}
+}
+
+// Generates a random secret for subscriptions created without one
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Checks the URL and event types of the subscription
+func validateSubscription(subscription *domain.Subscription) error {
+	target, err := url.Parse(subscription.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	if len(subscription.Events) == 0 {
+		return errors.New("at least one event type is required")
+	}
+
+	valid := make(map[string]bool)
+	for _, event := range domain.WebhookEvents {
+		valid[event] = true
+	}
+	for _, event := range subscription.Events {
+		if !valid[event] {
+			return fmt.Errorf("invalid event type %q: must be one of %s", event, strings.Join(domain.WebhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+func (s *WebhookServiceImpl) CreateSubscription(ctx context.Context, subscription *domain.Subscription, userUuid uuid.UUID) error {
+	if err := validateSubscription(subscription); err != nil {
+		return err
+	}
+
+	if subscription.SensorUuid != nil {
+		ownerUuid, err := s.Repo.GetSensorOwner(ctx, *subscription.SensorUuid)
+		if err != nil {
+			return err
+		}
+		if ownerUuid != userUuid {
+			return domain.ErrNotSensorOwner
+		}
+	}
+
+	if subscription.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return fmt.Errorf("failed to generate secret: %v", err)
+		}
+		subscription.Secret = secret
+	}
+
+	subscription.ID = uuid.NewV4()
+	subscription.OwnerUuid = userUuid
+	subscription.CreatedAt = time.Now().UTC()
+
+	return s.Repo.CreateSubscription(ctx, subscription)
+}
+
+func (s *WebhookServiceImpl) DeleteSubscription(ctx context.Context, subscriptionUuid uuid.UUID, userUuid uuid.UUID) error {
+	return s.Repo.DeleteSubscription(ctx, subscriptionUuid, userUuid)
+}
+
+func (s *WebhookServiceImpl) ListSubscriptions(ctx context.Context, userUuid uuid.UUID) ([]domain.Subscription, error) {
+	subscriptions, err := s.Repo.ListSubscriptions(ctx, userUuid)
+	if err != nil {
+		return nil, errors.New("failed to retrieve webhook subscriptions")
+	}
+
+	// The secret is only returned when the subscription is created
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	return subscriptions, nil
+}
+
+func (s *WebhookServiceImpl) ListDeadLetters(ctx context.Context, userUuid uuid.UUID) ([]domain.DeadLetter, error) {
+	deadLetters, err := s.Repo.ListDeadLetters(ctx, userUuid)
+	if err != nil {
+		return nil, errors.New("failed to retrieve webhook dead letters")
+	}
+	return deadLetters, nil
+}
+
+func (s *WebhookServiceImpl) Publish(ctx context.Context, eventType string, sensorUuid uuid.UUID, data interface{}) error {
+	subscriptions, err := s.Repo.ListSensorSubscriptions(ctx, sensorUuid)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	event := &domain.Event{
+		ID:         uuid.NewV4(),
+		Type:       eventType,
+		SensorUuid: sensorUuid,
+		OccurredAt: now,
+		Data:       data,
+	}
+
+	var payload []byte
+	var deliveries []domain.Delivery
+	for i := range subscriptions {
+		if !subscriptions[i].Receives(eventType) {
+			continue
+		}
+
+		// Every subscription receives the same body
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("failed to encode webhook event: %v", err)
+			}
+		}
+
+		deliveries = append(deliveries, domain.Delivery{
+			ID:               uuid.NewV4(),
+			SubscriptionUuid: subscriptions[i].ID,
+			Event:            eventType,
+			Payload:          payload,
+			NextAttemptAt:    now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.Repo.EnqueueDeliveries(ctx, deliveries)
+}
+
+// Publishes the event, logging the failures since the operation that raised it already succeeded
+func (s *WebhookServiceImpl) publish(ctx context.Context, eventType string, sensorUuid uuid.UUID, data interface{}) {
+	if err := s.Publish(ctx, eventType, sensorUuid, data); err != nil {
+		log.Printf("Failed to publish %s webhook event of sensor %s: %v", eventType, sensorUuid.String(), err)
+	}
+}
+
+// Reading sent in data.added and anomaly.detected events
+type webhookReading struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+	Anomaly   bool      `json:"anomaly,omitempty"`
+	Quality   string    `json:"quality,omitempty"`
+}
+
+func (s *WebhookServiceImpl) OnSensorData(ctx context.Context, sensorData []*sensor_data_domain.SensorData) {
+	// One event per sensor with all its readings
+	var order []uuid.UUID
+	bySensor := make(map[uuid.UUID][]webhookReading)
+	for _, data := range sensorData {
+		if _, ok := bySensor[data.SensorUuid]; !ok {
+			order = append(order, data.SensorUuid)
+		}
+		bySensor[data.SensorUuid] = append(bySensor[data.SensorUuid], webhookReading{
+			Timestamp: data.Timestamp,
+			Value:     data.Value,
+			Anomaly:   data.Anomaly,
+			Quality:   data.Quality,
+		})
+	}
+
+	for _, sensorUuid := range order {
+		s.publish(ctx, domain.WEBHOOK_EVENT_DATA_ADDED, sensorUuid, map[string]interface{}{
+			"readings": bySensor[sensorUuid],
+		})
+	}
+}
+
+func (s *WebhookServiceImpl) OnSensorCreated(ctx context.Context, sensor *sensor_domain.Sensor) {
+	s.publish(ctx, domain.WEBHOOK_EVENT_SENSOR_CREATED, sensor.ID, sensor)
+}
+
+func (s *WebhookServiceImpl) OnSensorUpdated(ctx context.Context, sensor *sensor_domain.Sensor) {
+	s.publish(ctx, domain.WEBHOOK_EVENT_SENSOR_UPDATED, sensor.ID, sensor)
+}
+
+func (s *WebhookServiceImpl) OnSensorStatusChanged(ctx context.Context, change *sensor_domain.SensorStatusChange) {
+	s.publish(ctx, domain.WEBHOOK_EVENT_SENSOR_STATUS_CHANGED, change.SensorUuid, change)
+}
+
+func (s *WebhookServiceImpl) OnAlertFired(ctx context.Context, rule *alert_domain.AlertRule, alert *alert_domain.Alert) {
+	s.publish(ctx, domain.WEBHOOK_EVENT_ALERT_FIRED, alert.SensorUuid, map[string]interface{}{
+		"rule":  rule,
+		"alert": alert,
+	})
+}
+
+func (s *WebhookServiceImpl) OnAnomalies(ctx context.Context, sensorUuid uuid.UUID, anomalies []*sensor_data_domain.SensorData) {
+	readings := make([]webhookReading, len(anomalies))
+	for i, data := range anomalies {
+		readings[i] = webhookReading{Timestamp: data.Timestamp, Value: data.Value, Anomaly: true, Quality: data.Quality}
+	}
+
+	s.publish(ctx, domain.WEBHOOK_EVENT_ANOMALY_DETECTED, sensorUuid, map[string]interface{}{
+		"readings": readings,
+	})
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"api/internal/webhooks/domain"
+	"strings"
+	"testing"
+)
+
+func TestValidateSubscription(t *testing.T) {
+	// Every event type can be subscribed to
+	for _, event := range domain.WebhookEvents {
+		subscription := &domain.Subscription{URL: "https://example.com/hook", Events: []string{event}}
+		if err := validateSubscription(subscription); err != nil {
+			t.Errorf("expected %s to be accepted, got %v", event, err)
+		}
+	}
+
+	// The error of an unknown event type lists the valid ones
+	err := validateSubscription(&domain.Subscription{URL: "https://example.com/hook", Events: []string{"sensor.deleted"}})
+	if err == nil {
+		t.Fatal("expected an unknown event type to be rejected")
+	}
+	for _, event := range domain.WebhookEvents {
+		if !strings.Contains(err.Error(), event) {
+			t.Errorf("expected the error to list %s, got %q", event, err)
+		}
+	}
+
+	if err = validateSubscription(&domain.Subscription{URL: "ftp://example.com", Events: domain.WebhookEvents}); err == nil {
+		t.Error("expected a non http URL to be rejected")
+	}
+	if err = validateSubscription(&domain.Subscription{URL: "https://example.com/hook"}); err == nil {
+		t.Error("expected a subscription without events to be rejected")
+	}
+}
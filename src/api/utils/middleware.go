@@ -1,9 +1,12 @@
 package utils
 
 import (
+	apikey_domain "api/internal/apikeys/domain"
 	auth_service "api/internal/auth/usecase"
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -125,3 +128,38 @@ func AuthMiddleware(authService auth_service.AuthService) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// Authenticates the API keys devices write sensor data with
+type APIKeyAuthenticator interface {
+	// Returns the sensor the key can write data for
+	AuthenticateKey(ctx context.Context, key string) (uuid.UUID, error)
+}
+
+// Accepts a device API key in the X-API-Key header, otherwise validates the JWT token like AuthMiddleware
+func DeviceOrUserAuthMiddleware(authService auth_service.AuthService, keys APIKeyAuthenticator) gin.HandlerFunc {
+	var userAuth = AuthMiddleware(authService)
+
+	return func(c *gin.Context) {
+		var key = c.GetHeader("X-API-Key")
+		if key == "" {
+			userAuth(c)
+			return
+		}
+
+		var sensorUuid, err = keys.AuthenticateKey(c.Request.Context(), key)
+		if errors.Is(err, apikey_domain.ErrInvalidKey) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or revoked api key"})
+			c.Abort()
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate api key"})
+			c.Abort()
+			return
+		}
+
+		// The request can only write the data of this sensor
+		c.Set("apiKeySensor", sensorUuid)
+		c.Next()
+	}
+}
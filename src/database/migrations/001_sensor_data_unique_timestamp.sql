@@ -0,0 +1,12 @@
+-- One reading per sensor and timestamp, so re-sent batches can be rejected, ignored or overwritten.
+
+-- Keep a single row for readings that were already duplicated
+WITH duplicated AS (
+    SELECT ROW_NUMBER() OVER (PARTITION BY sensorUuid, timestamp ORDER BY (SELECT NULL)) AS occurrence
+    FROM SensorData
+)
+DELETE FROM duplicated WHERE occurrence > 1;
+
+CREATE UNIQUE INDEX UX_SensorData_sensorUuid_timestamp
+    ON SensorData (sensorUuid, timestamp)
+    INCLUDE (value);
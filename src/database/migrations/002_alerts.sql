@@ -0,0 +1,35 @@
+-- Alert rules defined by sensor owners and the alerts they fire.
+
+CREATE TABLE alert_rules (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    sensorUuid UNIQUEIDENTIFIER NOT NULL,
+    ownerUuid UNIQUEIDENTIFIER NOT NULL,
+    name NVARCHAR(100) NOT NULL,
+    -- threshold or no_data
+    type VARCHAR(20) NOT NULL,
+    -- >, >=, < or <= (threshold rules only)
+    operator VARCHAR(2) NOT NULL DEFAULT '',
+    threshold FLOAT NOT NULL DEFAULT 0,
+    durationMinutes INT NOT NULL DEFAULT 0,
+    enabled BIT NOT NULL DEFAULT 1,
+    -- Since when the threshold condition holds, NULL when it doesn't
+    conditionSince DATETIME2 NULL
+);
+
+CREATE INDEX IX_alert_rules_sensorUuid ON alert_rules (sensorUuid);
+CREATE INDEX IX_alert_rules_ownerUuid ON alert_rules (ownerUuid);
+
+CREATE TABLE sensor_alerts (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    ruleUuid UNIQUEIDENTIFIER NOT NULL REFERENCES alert_rules (uuid),
+    sensorUuid UNIQUEIDENTIFIER NOT NULL,
+    -- firing, acknowledged or resolved
+    state VARCHAR(20) NOT NULL,
+    value FLOAT NULL,
+    message NVARCHAR(400) NOT NULL,
+    firedAt DATETIME2 NOT NULL,
+    acknowledgedAt DATETIME2 NULL,
+    resolvedAt DATETIME2 NULL
+);
+
+CREATE INDEX IX_sensor_alerts_ruleUuid_state ON sensor_alerts (ruleUuid, state);
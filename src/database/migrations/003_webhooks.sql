@@ -0,0 +1,39 @@
+-- Webhook subscriptions, their pending deliveries and the deliveries that failed every attempt.
+
+CREATE TABLE webhook_subscriptions (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    ownerUuid UNIQUEIDENTIFIER NOT NULL,
+    -- NULL receives the events of every sensor of the owner
+    sensorUuid UNIQUEIDENTIFIER NULL,
+    url NVARCHAR(2048) NOT NULL,
+    secret NVARCHAR(200) NOT NULL,
+    -- Comma separated event types
+    events VARCHAR(200) NOT NULL,
+    enabled BIT NOT NULL DEFAULT 1,
+    createdAt DATETIME2 NOT NULL
+);
+
+CREATE INDEX IX_webhook_subscriptions_sensorUuid ON webhook_subscriptions (sensorUuid);
+CREATE INDEX IX_webhook_subscriptions_ownerUuid ON webhook_subscriptions (ownerUuid);
+
+CREATE TABLE webhook_deliveries (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    subscriptionUuid UNIQUEIDENTIFIER NOT NULL REFERENCES webhook_subscriptions (uuid),
+    event VARCHAR(50) NOT NULL,
+    payload NVARCHAR(MAX) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    nextAttemptAt DATETIME2 NOT NULL,
+    lastError NVARCHAR(1000) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IX_webhook_deliveries_nextAttemptAt ON webhook_deliveries (nextAttemptAt);
+
+CREATE TABLE webhook_dead_letters (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    subscriptionUuid UNIQUEIDENTIFIER NOT NULL REFERENCES webhook_subscriptions (uuid),
+    event VARCHAR(50) NOT NULL,
+    payload NVARCHAR(MAX) NOT NULL,
+    attempts INT NOT NULL,
+    lastError NVARCHAR(1000) NOT NULL,
+    failedAt DATETIME2 NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Archived sensors are hidden from the list until they're restored.
+
+ALTER TABLE Sensors ADD archivedAt DATETIME2 NULL;
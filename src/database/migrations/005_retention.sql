@@ -0,0 +1,25 @@
+-- Retention policies and the hourly rollups raw readings are compacted into.
+
+CREATE TABLE retention_policies (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    -- Exactly one of sensorUuid and category is set
+    sensorUuid UNIQUEIDENTIFIER NULL,
+    category INT NULL,
+    rawDays INT NOT NULL,
+    -- 0 keeps the rollups forever
+    rollupDays INT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX UX_retention_policies_sensorUuid ON retention_policies (sensorUuid) WHERE sensorUuid IS NOT NULL;
+CREATE UNIQUE INDEX UX_retention_policies_category ON retention_policies (category) WHERE sensorUuid IS NULL;
+
+CREATE TABLE SensorDataRollup (
+    sensorUuid UNIQUEIDENTIFIER NOT NULL,
+    -- Start of the hour the readings were taken in
+    bucketStart DATETIME2 NOT NULL,
+    valueCount INT NOT NULL,
+    valueSum FLOAT NOT NULL,
+    minValue FLOAT NOT NULL,
+    maxValue FLOAT NOT NULL,
+    CONSTRAINT PK_SensorDataRollup PRIMARY KEY (sensorUuid, bucketStart)
+);
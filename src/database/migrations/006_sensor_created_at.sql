@@ -0,0 +1,3 @@
+-- Creation time of the sensors, used to sort the sensor list. Existing sensors get the migration time.
+
+ALTER TABLE Sensors ADD createdAt DATETIME2 NOT NULL CONSTRAINT DF_Sensors_createdAt DEFAULT SYSUTCDATETIME();
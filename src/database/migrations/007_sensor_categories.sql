@@ -0,0 +1,27 @@
+-- Sensor categories managed by admins, replacing the three built-in category constants.
+-- The built-in categories keep their ids so existing sensors and retention policies still match.
+
+CREATE TABLE sensor_categories (
+    id INT IDENTITY(0, 1) NOT NULL PRIMARY KEY,
+    name NVARCHAR(100) NOT NULL,
+    unit NVARCHAR(20) NOT NULL,
+    -- Physical range of the readings, NULL when unbounded
+    minValue FLOAT NULL,
+    maxValue FLOAT NULL,
+    -- Decimal places the values are displayed with
+    decimalPrecision INT NOT NULL DEFAULT 2,
+    CONSTRAINT CK_sensor_categories_range CHECK (minValue IS NULL OR maxValue IS NULL OR minValue <= maxValue)
+);
+
+CREATE UNIQUE INDEX UX_sensor_categories_name ON sensor_categories (name);
+
+SET IDENTITY_INSERT sensor_categories ON;
+INSERT INTO sensor_categories (id, name, unit, minValue, maxValue, decimalPrecision) VALUES
+    (0, N'Temperature', N'°C', -273.15, NULL, 1),
+    (1, N'Humidity', N'%', 0, 100, 1),
+    (2, N'Pressure', N'hPa', 0, NULL, 1);
+SET IDENTITY_INSERT sensor_categories OFF;
+
+DBCC CHECKIDENT ('sensor_categories', RESEED, 2);
+
+ALTER TABLE Sensors ADD CONSTRAINT FK_Sensors_category FOREIGN KEY (category) REFERENCES sensor_categories (id);
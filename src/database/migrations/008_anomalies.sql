@@ -0,0 +1,14 @@
+-- Anomaly detection settings per sensor and the flag of the readings detected as anomalies.
+
+ALTER TABLE SensorData ADD anomaly BIT NOT NULL CONSTRAINT DF_SensorData_anomaly DEFAULT 0;
+
+CREATE TABLE anomaly_settings (
+    sensorUuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    -- zscore or mad
+    method NVARCHAR(10) NOT NULL,
+    -- Number of previous readings the baseline is computed over
+    windowSize INT NOT NULL,
+    threshold FLOAT NOT NULL,
+    -- Whether the owner is emailed when anomalies are detected
+    notify BIT NOT NULL DEFAULT 1
+);
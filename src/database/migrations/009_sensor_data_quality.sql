@@ -0,0 +1,3 @@
+-- Quality flag of each reading: ok, estimated, suspect or missing (placeholder for a reading the device couldn't take).
+
+ALTER TABLE SensorData ADD quality NVARCHAR(10) NOT NULL CONSTRAINT DF_SensorData_quality DEFAULT 'ok';
@@ -0,0 +1,7 @@
+-- Expected reporting interval of the sensors and the status the monitor derives from their last reading.
+
+-- Seconds between two readings, 0 when the sensor isn't monitored
+ALTER TABLE Sensors ADD reportingInterval INT NOT NULL CONSTRAINT DF_Sensors_reportingInterval DEFAULT 0;
+-- online, stale or offline, NULL until the monitor first checks the sensor
+ALTER TABLE Sensors ADD status NVARCHAR(10) NULL;
+ALTER TABLE Sensors ADD statusChangedAt DATETIME2 NULL;
@@ -0,0 +1,20 @@
+-- API keys devices use to add the readings of one sensor without a user session.
+
+CREATE TABLE device_api_keys (
+    uuid UNIQUEIDENTIFIER NOT NULL PRIMARY KEY,
+    -- Sensor the key can write data for
+    sensorUuid UNIQUEIDENTIFIER NOT NULL,
+    name NVARCHAR(100) NOT NULL,
+    -- First characters of the key, to tell keys apart
+    prefix NVARCHAR(16) NOT NULL,
+    -- SHA-256 of the key, the key itself isn't stored
+    keyHash BINARY(32) NOT NULL,
+    createdAt DATETIME2 NOT NULL,
+    lastUsedAt DATETIME2 NULL,
+    -- NULL while the key is valid
+    revokedAt DATETIME2 NULL,
+    CONSTRAINT FK_device_api_keys_sensor FOREIGN KEY (sensorUuid) REFERENCES Sensors (uuid)
+);
+
+CREATE UNIQUE INDEX UX_device_api_keys_keyHash ON device_api_keys (keyHash);
+CREATE INDEX IX_device_api_keys_sensorUuid ON device_api_keys (sensorUuid);
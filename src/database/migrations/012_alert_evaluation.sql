@@ -0,0 +1,14 @@
+-- Timestamp of the last reading evaluated by each alert rule and a single open alert per rule.
+
+-- Readings up to this timestamp were evaluated, older ones (e.g. imported) are ignored
+ALTER TABLE alert_rules ADD evaluatedUntil DATETIME2 NULL;
+
+-- Resolves the duplicate open alerts fired concurrently, keeping the most recent one of each rule
+WITH ranked AS (
+    SELECT state, resolvedAt, ROW_NUMBER() OVER (PARTITION BY ruleUuid ORDER BY firedAt DESC) AS position
+    FROM sensor_alerts
+    WHERE resolvedAt IS NULL
+)
+UPDATE ranked SET state = 'resolved', resolvedAt = SYSUTCDATETIME() WHERE position > 1;
+
+CREATE UNIQUE INDEX UX_sensor_alerts_ruleUuid_open ON sensor_alerts (ruleUuid) WHERE resolvedAt IS NULL;